@@ -0,0 +1,61 @@
+// Package attack holds the options shared by the attacks of the challenges.
+// Every attack takes a context, which stops it, and an *Options, which may
+// be nil if the defaults will do.
+package attack
+
+import (
+	"io"
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/checkpoint"
+	"github.com/svkirillov/cryptopals-go/kangaroo"
+	"github.com/svkirillov/cryptopals-go/progress"
+)
+
+// Options are the optional parameters of an attack. The zero value asks
+// Bob with random elements from crypto/rand.Reader, saves nothing and
+// reports nothing.
+type Options struct {
+	// Store keeps the recovered residues, the candidates and the trap of
+	// the tame kangaroo as the attack goes, and the attack resumes from the
+	// state saved there. Nothing is saved if nil.
+	Store checkpoint.Store
+
+	// Kangaroo is how the kangaroos jump, the zero kangaroo.Config if nil.
+	Kangaroo *kangaroo.Config
+
+	// Rand is the source of the random elements sent to Bob,
+	// crypto/rand.Reader if nil.
+	Rand io.Reader
+
+	// Events receives the progress of the attack, which is discarded if
+	// nil.
+	Events progress.Reporter
+
+	// KeyBound is known to be greater than Bob's private key, e.g. because
+	// Bob uses short exponents, the order of the group if nil. Only
+	// challenge58.CatchingKangaroosAttack makes use of it.
+	KeyBound *big.Int
+
+	// Cofactor is the cofactor H Bob multiplies the points by before his
+	// private key d, e.g. with elliptic.CofactorMultiplication, so that his
+	// answers give H*d mod r rather than d mod r. The residues are
+	// multiplied by H^-1 mod r if it isn't nil. Only the invalid curve
+	// attacks of challenge59 make use of it.
+	Cofactor *big.Int
+}
+
+// OrDefault returns a copy of o with the nil Store, Kangaroo and Events
+// replaced by their defaults. o may be nil.
+func OrDefault(o *Options) *Options {
+	var res Options
+	if o != nil {
+		res = *o
+	}
+
+	res.Store = checkpoint.OrNone(res.Store)
+	res.Kangaroo = kangaroo.OrDefault(res.Kangaroo)
+	res.Events = progress.OrDiscard(res.Events)
+
+	return &res
+}
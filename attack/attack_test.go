@@ -0,0 +1,33 @@
+package attack
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/checkpoint"
+	"github.com/svkirillov/cryptopals-go/kangaroo"
+	"github.com/svkirillov/cryptopals-go/progress"
+)
+
+func TestOrDefault(t *testing.T) {
+	opts := OrDefault(nil)
+	if opts.Store != checkpoint.None || opts.Events != progress.Discard || opts.Kangaroo == nil {
+		t.Fatalf("%s: got %+v for nil, want the defaults", t.Name(), opts)
+	}
+	if opts.Rand != nil || opts.KeyBound != nil {
+		t.Errorf("%s: got %+v for nil, want nil Rand and KeyBound", t.Name(), opts)
+	}
+
+	cfg := &kangaroo.Config{Attempts: 5}
+	given := &Options{Kangaroo: cfg, KeyBound: big.NewInt(1 << 20)}
+
+	opts = OrDefault(given)
+	if opts.Kangaroo != cfg || opts.KeyBound != given.KeyBound {
+		t.Errorf("%s: got %+v, want the given Kangaroo and KeyBound", t.Name(), opts)
+	}
+
+	// the given options aren't changed
+	if given.Store != nil || given.Events != nil {
+		t.Errorf("%s: the given options are changed to %+v", t.Name(), given)
+	}
+}
@@ -1,45 +1,153 @@
 package challenge57
 
 import (
-	"crypto/hmac"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/congruence"
 	"github.com/svkirillov/cryptopals-go/dh"
 	"github.com/svkirillov/cryptopals-go/helpers"
 	"github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
+	"github.com/svkirillov/cryptopals-go/search"
 )
 
-func SmallSubgroupAttack(dhGroup dh.DHScheme,
-	oracleDH func(publicKey *big.Int) []byte,
+// maxAttempts is the number of elements sent to Bob for each factor before
+// giving up, if his answers are lost or corrupted.
+const maxAttempts = 8
+
+// SmallSubgroupAttack recovers Bob's private key, if the small factors of
+// (p-1)/q are enough to reassemble it. It stops once ctx is done, and the
+// residues recovered so far are returned in a *congruence.PartialError.
+// opts may be nil.
+func SmallSubgroupAttack(ctx context.Context,
+	dhGroup dh.DHScheme,
+	oracleDH oracle.DHOracle,
+	opts *attack.Options,
 ) (*big.Int, error) {
+	q := dhGroup.DHParams().Q
+
+	x, n, err := SmallSubgroupResidues(ctx, dhGroup, oracleDH, big.NewInt(1<<16), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// check if we have enough information to reassemble Bob's secret key
+	if n.Cmp(q) <= 0 {
+		return nil, errors.New("not enough information to reassemble Bob's secret key")
+	}
+
+	return x, nil
+}
+
+// SmallSubgroupResidues recovers Bob's private key x modulo n, where n is the
+// product of the prime factors of (p-1)/q less than factorBound. The
+// recovered residues are saved to opts.Store, and the attack resumes from
+// the residues saved there.
+func SmallSubgroupResidues(ctx context.Context,
+	dhGroup dh.DHScheme,
+	oracleDH oracle.DHOracle,
+	factorBound *big.Int,
+	opts *attack.Options,
+) (x, n *big.Int, err error) {
+	opts = attack.OrDefault(opts)
+	events, store, rng := opts.Events, opts.Store, opts.Rand
+
 	p := dhGroup.DHParams().P
 	q := dhGroup.DHParams().Q
 
 	j := new(big.Int).Div(new(big.Int).Sub(p, helpers.BigOne), q)
 
 	// Step #0
-	jFactors := helpers.Factorize(j, big.NewInt(1<<16))
+	jFactors := helpers.Factorize(j, factorBound)
 	if len(jFactors) == 0 {
-		return nil, errors.New("factors not found")
+		return nil, nil, errors.New("factors not found")
 	}
 
-	var modules, remainders []*big.Int
-	tmp := new(big.Int)
-
+	var factors []*big.Int
 	for _, r := range jFactors {
+		if r.Cmp(factorBound) < 0 {
+			factors = append(factors, r)
+		}
+	}
+
+	state, err := store.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.CheckModules(factors); err != nil {
+		return nil, nil, err
+	}
+	if state.Congruences.Len() > 0 {
+		events.Report(progress.Event{
+			Kind:    progress.Note,
+			Message: fmt.Sprintf("resumed with %d residues from the checkpoint", state.Congruences.Len()),
+		})
+	}
+
+	congruences := state.Congruences
+
+	for _, r := range factors {
+		if state.HasModulus(r) {
+			continue
+		}
+
+		events.Report(progress.Event{Kind: progress.SubgroupFound, Modulus: r})
+
+		remainder, err := residue(ctx, dhGroup, oracleDH, r, rng)
+		if ctx.Err() != nil {
+			return nil, nil, &congruence.PartialError{Congruences: congruences, Err: ctx.Err()}
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if remainder != nil {
+			congruences.Add(remainder, r)
+
+			state.Congruences = congruences
+			if err := store.Save(state); err != nil {
+				return nil, nil, err
+			}
+
+			n := congruences.Modulus()
+			events.Report(progress.Event{Kind: progress.ResidueRecovered, Residue: new(big.Int).Mod(remainder, r), Modulus: r})
+			events.Report(progress.Event{Kind: progress.ModulusGrown, Modulus: n, Bits: n.BitLen()})
+		}
+	}
+
+	// reassemble Bob's secret key modulo n = r1 * r2 * ... * rn using the Chinese Remainder Theorem
+	return congruences.Solve()
+}
+
+// residue returns Bob's private key modulo r, or nil if Bob rejects elements
+// of order r. The query is repeated with a new element if the answer is lost
+// or doesn't match any residue, i.e. it's corrupted. It stops with ctx.Err()
+// once ctx is done.
+func residue(ctx context.Context, dhGroup dh.DHScheme, oracleDH oracle.DHOracle, r *big.Int, rng io.Reader) (*big.Int, error) {
+	p := dhGroup.DHParams().P
+
+	power := new(big.Int).Div(new(big.Int).Sub(p, helpers.BigOne), r)
+	checker := oracle.CheckerOf(oracleDH)
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
 		// Step #1
-		power := tmp.Div(tmp.Sub(p, helpers.BigOne), r)
 		h := new(big.Int).Set(helpers.BigOne)
 		for h.Cmp(helpers.BigOne) == 0 {
-			rand, err := helpers.GenerateBigInt(p)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			rand, err := helpers.GenerateBigInt(rng, p)
 			if err != nil {
 				return nil, fmt.Errorf("couldn't generate random big.Int: %s", err.Error())
 			}
 
 			for rand.Cmp(helpers.BigZero) == 0 {
-				rand, err = helpers.GenerateBigInt(p)
+				rand, err = helpers.GenerateBigInt(rng, p)
 				if err != nil {
 					return nil, fmt.Errorf("couldn't generate random big.Int: %s", err.Error())
 				}
@@ -49,41 +157,28 @@ func SmallSubgroupAttack(dhGroup dh.DHScheme,
 		}
 
 		// Step #2,3
-		ss := oracleDH(h)
+		ss, err := oracleDH.DH(h)
+		if oracle.IsTransient(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("oracle: %w", err)
+		}
+		if ss == nil {
+			return nil, nil
+		}
 
 		// Step #4
-		for i := big.NewInt(1); i.Cmp(r) <= 0; i.Add(i, helpers.BigOne) {
-			k1 := dhGroup.DH(i, h)
-			ss1 := oracle.MAC(k1.Bytes())
-
-			if hmac.Equal(ss, ss1) {
-				modules = append(modules, r)
-				remainders = append(remainders, new(big.Int).Set(i))
-				continue
-			}
+		i, err := search.Smallest(ctx, helpers.BigOne, new(big.Int).Add(r, helpers.BigOne), 0, func(i *big.Int) bool {
+			return checker.CheckSecret(dhGroup.DH(i, h).Bytes(), ss)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if i != nil {
+			return i, nil
 		}
 	}
 
-	if len(modules) == 0 {
-		return nil, errors.New("empty sets of modules and remainders")
-	}
-
-	// n = r1 * r2 * ... * rn
-	n := new(big.Int).Set(helpers.BigOne)
-	for _, r := range modules {
-		n.Mul(n, r)
-	}
-
-	// check if we have enough information to reassemble Bob's secret key
-	if n.Cmp(q) <= 0 {
-		return nil, errors.New("not enough information to reassemble Bob's secret key")
-	}
-
-	// reassemble Bob's secret key using the Chinese Remainder Theorem
-	x, _, err := helpers.ChineseRemainderTheorem(remainders, modules)
-	if err != nil {
-		return nil, fmt.Errorf("chinese remainder theorem: %s", err.Error())
-	}
-
-	return x, nil
+	return nil, fmt.Errorf("no residue modulo %d after %d attempts", r, maxAttempts)
 }
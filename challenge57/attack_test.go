@@ -1,23 +1,410 @@
 package challenge57
 
 import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
 
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/checkpoint"
+	"github.com/svkirillov/cryptopals-go/congruence"
 	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/helpers"
 	oracle2 "github.com/svkirillov/cryptopals-go/oracle"
 )
 
 func TestSmallSubgroupAttack(t *testing.T) {
 	dhGroup := dh.MODP512V57()
 
-	oracle, isKeyCorrect, _ := oracle2.NewDHAttackOracle(dhGroup)
+	bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+	meter := oracle2.NewMeter(0)
 
-	privateKey, err := SmallSubgroupAttack(dhGroup, oracle)
+	privateKey, err := SmallSubgroupAttack(context.Background(), dhGroup, oracle2.NewMeteredDHOracle(bob, meter), nil)
 	if err != nil {
 		t.Fatalf("small subgroup attack failed: %s", err.Error())
 	}
+	t.Logf("%s: %s", t.Name(), meter.Summary())
 
-	if !isKeyCorrect(privateKey.Bytes()) {
+	if !bob.IsKeyCorrect(privateKey.Bytes()) {
 		t.Fatal("computed key isn't equal to Bob's private key")
 	}
+
+	// one query per small factor of (p-1)/q
+	if n, max := meter.Summary().Queries, len(smallFactors(dhGroup)); n > max {
+		t.Errorf("%s: %d queries, want at most %d", t.Name(), n, max)
+	}
+}
+
+func TestSmallSubgroupAttackWithBudget(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+
+	bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+	meter := oracle2.NewMeter(len(smallFactors(dhGroup)) - 1)
+
+	_, err := SmallSubgroupAttack(context.Background(), dhGroup, oracle2.NewMeteredDHOracle(bob, meter), nil)
+	if !errors.Is(err, oracle2.ErrBudgetExceeded) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, oracle2.ErrBudgetExceeded)
+	}
+	t.Logf("%s: %s", t.Name(), meter.Summary())
+}
+
+// smallFactors returns the factors of (p-1)/q the attack makes queries for.
+func smallFactors(dhGroup dh.DHScheme) []*big.Int {
+	params := dhGroup.DHParams()
+	j := new(big.Int).Sub(params.P, helpers.BigOne)
+	j.Div(j, params.Q)
+
+	var factors []*big.Int
+	for _, r := range helpers.Factorize(j, big.NewInt(1<<16)) {
+		if r.Cmp(big.NewInt(1<<16)) < 0 {
+			factors = append(factors, r)
+		}
+	}
+
+	return factors
+}
+
+func TestSmallSubgroupAttackWithFaults(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+	rng := helpers.NewSeededReader(38)
+
+	bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, rng)
+	injector := oracle2.NewInjector(oracle2.Faults{Drop: 0.2, Corrupt: 0.2}, rng)
+	faulty := oracle2.NewFaultyDHOracle(bob, injector)
+
+	oracles := []struct {
+		name   string
+		oracle oracle2.DHOracle
+	}{
+		{"retry", faulty},
+		{"vote", oracle2.NewVotingDHOracle(faulty, oracle2.NewVoter(3, 5))},
+	}
+
+	for _, o := range oracles {
+		privateKey, err := SmallSubgroupAttack(context.Background(), dhGroup, o.oracle, &attack.Options{Rand: rng})
+		if err != nil {
+			t.Fatalf("%s: %s: %s", t.Name(), o.name, err.Error())
+		}
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: %s: computed key isn't equal to Bob's private key", t.Name(), o.name)
+		}
+	}
+	t.Logf("%s: %+v", t.Name(), injector.Stats())
+}
+
+func TestSmallSubgroupAttackWithAEAD(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+	rng := helpers.NewSeededReader(40)
+
+	macBob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, rng)
+
+	for _, c := range []oracle2.Cipher{oracle2.AESGCM, oracle2.ChaCha20Poly1305} {
+		protocol := oracle2.NewAEADProtocol(c, rng)
+		bob := macBob.WithProtocol(protocol)
+
+		// the checker of the protocol has to be attached to a wrapped Bob
+		meter := oracle2.NewMeter(0)
+		checked := oracle2.NewCheckedDHOracle(oracle2.NewMeteredDHOracle(bob, meter), protocol)
+
+		privateKey, err := SmallSubgroupAttack(context.Background(), dhGroup, checked, &attack.Options{Rand: rng})
+		if err != nil {
+			t.Fatalf("%s: %s: %s", t.Name(), c, err.Error())
+		}
+		t.Logf("%s: %s: %s", t.Name(), c, meter.Summary())
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: %s: computed key isn't equal to Bob's private key", t.Name(), c)
+		}
+	}
+}
+
+func TestSmallSubgroupAttackOnWeakGroups(t *testing.T) {
+	weakGroupTests := []struct {
+		pBits, qBits int
+	}{
+		{128, 32},
+		{256, 64},
+		{512, 128},
+	}
+
+	for _, e := range weakGroupTests {
+		dhGroup, factors, err := dh.GenerateWeakGroup(nil, e.pBits, e.qBits, big.NewInt(1<<16))
+		if err != nil {
+			t.Fatalf("%s: %d-bit p, %d-bit q: %s", t.Name(), e.pBits, e.qBits, err.Error())
+		}
+		t.Logf("%s: %s: factors of (p-1)/q: %d", t.Name(), dhGroup.DHName(), factors)
+
+		bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+
+		privateKey, err := SmallSubgroupAttack(context.Background(), dhGroup, bob, nil)
+		if err != nil {
+			t.Fatalf("%s: %s: small subgroup attack failed: %s", t.Name(), dhGroup.DHName(), err.Error())
+		}
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: %s: computed key isn't equal to Bob's private key", t.Name(), dhGroup.DHName())
+		}
+	}
+}
+
+func TestSmallSubgroupAttackOnStandardGroups(t *testing.T) {
+	// (p-1)/q = 2 for safe primes, so the attack has to fail
+	for _, name := range []string{"MODP-1536", "MODP-2048", "FFDHE-2048", "FFDHE-3072"} {
+		dhGroup, err := dh.GroupByName(name)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+
+		bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+
+		if _, err := SmallSubgroupAttack(context.Background(), dhGroup, bob, nil); err == nil {
+			t.Errorf("%s: %s: small subgroup attack succeeded on a safe prime group", t.Name(), name)
+		}
+	}
+
+	// RFC 5114 groups leak Bob's private key modulo the small factors of (p-1)/q
+	for _, name := range []string{"RFC5114-1024-160", "RFC5114-2048-224", "RFC5114-2048-256"} {
+		dhGroup, err := dh.GroupByName(name)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+
+		dhKey, err := dhGroup.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", t.Name(), name, err.Error())
+		}
+
+		bob := oracle2.NewDHAttackOracleWithKey(dhGroup, dh.NoValidation, dhKey)
+
+		x, n, err := SmallSubgroupResidues(context.Background(), dhGroup, bob, big.NewInt(1<<16), nil)
+		if err != nil {
+			t.Fatalf("%s: %s: small subgroup attack failed: %s", t.Name(), name, err.Error())
+		}
+		t.Logf("%s: %s: recovered Bob's private key modulo %d", t.Name(), name, n)
+
+		if new(big.Int).Mod(dhKey.Private, n).Cmp(new(big.Int).Mod(x, n)) != 0 {
+			t.Fatalf("%s: %s: x mod %d isn't equal to Bob's private key mod %d", t.Name(), name, n, n)
+		}
+	}
+}
+
+func TestSmallSubgroupAttackWithValidation(t *testing.T) {
+	validationTests := []struct {
+		policy  dh.ValidationPolicy
+		success bool
+	}{
+		{dh.NoValidation, true},
+		// only rejects the element of order 2
+		{dh.RangeCheck, true},
+		{dh.SubgroupCheck, false},
+		{dh.CofactorClearing, false},
+	}
+
+	dhGroup := dh.MODP512V57()
+
+	for _, e := range validationTests {
+		bob := oracle2.NewDHAttackOracle(dhGroup, e.policy, nil)
+
+		privateKey, err := SmallSubgroupAttack(context.Background(), dhGroup, bob, nil)
+
+		if !e.success {
+			if err == nil {
+				t.Errorf("%s: %s: small subgroup attack succeeded", t.Name(), e.policy)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %s: small subgroup attack failed: %s", t.Name(), e.policy, err.Error())
+		}
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: %s: computed key isn't equal to Bob's private key", t.Name(), e.policy)
+		}
+	}
+}
+
+func TestSmallSubgroupAttackWithSeed(t *testing.T) {
+	seed, err := helpers.NewRandomSeed()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	t.Logf("%s: seed: %d", t.Name(), seed)
+
+	// the same seed has to give the same group, key and attack run
+	var groups []*dh.GroupParams
+	var keys []*big.Int
+
+	for i := 0; i < 2; i++ {
+		rng := helpers.NewSeededReader(seed)
+
+		dhGroup, _, err := dh.GenerateWeakGroup(rng, 128, 32, big.NewInt(1<<16))
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+
+		bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, rng)
+
+		privateKey, err := SmallSubgroupAttack(context.Background(), dhGroup, bob, &attack.Options{Rand: rng})
+		if err != nil {
+			t.Fatalf("%s: %s: small subgroup attack failed: %s", t.Name(), dhGroup.DHName(), err.Error())
+		}
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: %s: computed key isn't equal to Bob's private key", t.Name(), dhGroup.DHName())
+		}
+
+		groups = append(groups, dhGroup)
+		keys = append(keys, privateKey)
+	}
+
+	if groups[0].P.Cmp(groups[1].P) != 0 || groups[0].G.Cmp(groups[1].G) != 0 || keys[0].Cmp(keys[1]) != 0 {
+		t.Fatalf("%s: runs with the same seed differ", t.Name())
+	}
+}
+
+func TestSmallSubgroupAttackOverTCP(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+
+	bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+
+	srv, err := oracle2.StartServer("127.0.0.1:0", oracle2.ServerOracles{DH: bob, DHPublicKey: bob})
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	defer srv.Close()
+
+	client, err := oracle2.Dial(srv.Addr())
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	defer client.Close()
+
+	privateKey, err := SmallSubgroupAttack(context.Background(), dhGroup, client, nil)
+	if err != nil {
+		t.Fatalf("%s: small subgroup attack failed: %s", t.Name(), err.Error())
+	}
+
+	if !bob.IsKeyCorrect(privateKey.Bytes()) {
+		t.Fatalf("%s: computed key isn't equal to Bob's private key", t.Name())
+	}
+}
+
+func TestSmallSubgroupAttackOverHTTP(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+
+	bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+
+	srv := httptest.NewServer(oracle2.NewHTTPHandler(oracle2.ServerOracles{DH: bob, DHPublicKey: bob}))
+	defer srv.Close()
+
+	privateKey, err := SmallSubgroupAttack(context.Background(), dhGroup, oracle2.NewHTTPClient(srv.URL), nil)
+	if err != nil {
+		t.Fatalf("%s: small subgroup attack failed: %s", t.Name(), err.Error())
+	}
+
+	if !bob.IsKeyCorrect(privateKey.Bytes()) {
+		t.Fatalf("%s: computed key isn't equal to Bob's private key", t.Name())
+	}
+}
+
+func TestSmallSubgroupAttackContext(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+
+	dhKey, err := dhGroup.GenerateKey(helpers.NewSeededReader(43))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	bob := oracle2.NewDHAttackOracleWithKey(dhGroup, dh.NoValidation, dhKey)
+
+	// the attack is canceled while it looks for the third residue
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queries := 0
+	canceling := oracle2.DHOracleFunc(func(publicKey *big.Int) ([]byte, error) {
+		if queries++; queries == 3 {
+			cancel()
+		}
+		return bob.DH(publicKey)
+	})
+
+	_, err = SmallSubgroupAttack(ctx, dhGroup, canceling, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+
+	var partial *congruence.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("%s: got error %T, want *congruence.PartialError", t.Name(), err)
+	}
+	if partial.Congruences.Len() != 2 {
+		t.Fatalf("%s: got %d congruences, want 2", t.Name(), partial.Congruences.Len())
+	}
+
+	for i, m := range partial.Congruences.Modules {
+		r := partial.Congruences.Remainders[i]
+		if new(big.Int).Mod(dhKey.Private, m).Cmp(new(big.Int).Mod(r, m)) != 0 {
+			t.Errorf("%s: x = %d mod %d isn't Bob's private key", t.Name(), r, m)
+		}
+	}
+}
+
+func TestSmallSubgroupAttackWithCheckpoint(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+
+	dhKey, err := dhGroup.GenerateKey(helpers.NewSeededReader(44))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	bob := oracle2.NewDHAttackOracleWithKey(dhGroup, dh.NoValidation, dhKey)
+
+	store := checkpoint.File(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	// the first run is canceled while it looks for the third residue
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queries := 0
+	canceling := oracle2.DHOracleFunc(func(publicKey *big.Int) ([]byte, error) {
+		if queries++; queries == 3 {
+			cancel()
+		}
+		return bob.DH(publicKey)
+	})
+
+	if _, err := SmallSubgroupAttack(ctx, dhGroup, canceling, &attack.Options{Store: store}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if state.Congruences.Len() != 2 {
+		t.Fatalf("%s: %d residues saved, want 2", t.Name(), state.Congruences.Len())
+	}
+
+	// the second run only asks for the residues which aren't saved
+	full := oracle2.NewMeter(0)
+	if _, err := SmallSubgroupAttack(context.Background(), dhGroup, oracle2.NewMeteredDHOracle(bob, full), nil); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	resumed := oracle2.NewMeter(0)
+	privateKey, err := SmallSubgroupAttack(context.Background(), dhGroup, oracle2.NewMeteredDHOracle(bob, resumed), &attack.Options{Store: store})
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if privateKey.Cmp(dhKey.Private) != 0 {
+		t.Fatalf("%s: wrong private key was found in the resumed attack", t.Name())
+	}
+
+	if n, max := resumed.Summary().Queries, full.Summary().Queries-2; n > max {
+		t.Errorf("%s: %d queries after resuming, want at most %d", t.Name(), n, max)
+	}
 }
@@ -0,0 +1,156 @@
+package challenge57
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/congruence"
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
+	"github.com/svkirillov/cryptopals-go/search"
+)
+
+// primePower is r^e.
+type primePower struct {
+	r *big.Int
+	e int
+}
+
+func (pp primePower) value() *big.Int {
+	return new(big.Int).Exp(pp.r, big.NewInt(int64(pp.e)), nil)
+}
+
+// factorize returns the prime powers of n, if its prime factors are less
+// than factorBound.
+func factorize(n, factorBound *big.Int) ([]primePower, error) {
+	rest := new(big.Int).Set(n)
+	mod := new(big.Int)
+
+	var res []primePower
+	for _, r := range helpers.Factorize(n, factorBound) {
+		pp := primePower{r: r}
+		for mod.Mod(rest, r).Sign() == 0 {
+			rest.Div(rest, r)
+			pp.e++
+		}
+		res = append(res, pp)
+	}
+
+	if rest.Cmp(helpers.BigOne) != 0 {
+		return nil, fmt.Errorf("%d-bit factor of %d is above the bound", rest.BitLen(), n)
+	}
+
+	return res, nil
+}
+
+// PohligHellmanAttack recovers Bob's private key from his public key alone,
+// if the order q of the generator is a product of powers of primes less than
+// factorBound, e.g. in a group made by dh.GenerateSmoothGroup, where g
+// generates the whole group and q = p-1. Unlike SmallSubgroupAttack, Bob
+// isn't asked anything. It stops once ctx is done, and the residues
+// recovered so far are returned in a *congruence.PartialError. Only
+// opts.Events is used, and opts may be nil.
+func PohligHellmanAttack(ctx context.Context,
+	dhGroup dh.DHScheme,
+	publicKey oracle.PublicKeySource,
+	factorBound *big.Int,
+	opts *attack.Options,
+) (*big.Int, error) {
+	events := attack.OrDefault(opts).Events
+
+	params := dhGroup.DHParams()
+	y, err := publicKey.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+
+	powers, err := factorize(params.Q, factorBound)
+	if err != nil {
+		return nil, fmt.Errorf("the order of g can't be factored: %s", err.Error())
+	}
+
+	var congruences congruence.System
+
+	for _, pp := range powers {
+		m := pp.value()
+		events.Report(progress.Event{Kind: progress.SubgroupFound, Modulus: m})
+
+		remainder, err := primePowerResidue(ctx, params, y, pp)
+		if ctx.Err() != nil {
+			return nil, &congruence.PartialError{Congruences: congruences, Err: ctx.Err()}
+		}
+		if err != nil {
+			return nil, err
+		}
+		congruences.Add(remainder, m)
+
+		n := congruences.Modulus()
+		events.Report(progress.Event{Kind: progress.ResidueRecovered, Residue: remainder, Modulus: m})
+		events.Report(progress.Event{Kind: progress.ModulusGrown, Modulus: n, Bits: n.BitLen()})
+	}
+
+	x, _, err := congruences.Solve()
+	if err != nil {
+		return nil, err
+	}
+
+	if new(big.Int).Exp(params.G, x, params.P).Cmp(y) != 0 {
+		return nil, errors.New("the public key isn't a power of g")
+	}
+
+	return x, nil
+}
+
+// primePowerResidue returns the logarithm of y modulo r^e, one base r digit
+// at a time. In the subgroup of order r^e, y' = g'^x with x = x_0 + x_1*r +
+// ... + x_(e-1)*r^(e-1), and
+//
+//	(y' * g'^-(x_0 + ... + x_(k-1)*r^(k-1)))^(r^(e-1-k)) = (g'^(r^(e-1)))^x_k
+//
+// where g'^(r^(e-1)) is of order r, so each x_k is found by a brute force
+// search in [0, r).
+func primePowerResidue(ctx context.Context, params *dh.GroupParams, y *big.Int, pp primePower) (*big.Int, error) {
+	p := params.P
+	m := pp.value()
+
+	// g' = g^(q/r^e), y' = y^(q/r^e)
+	cofactor := new(big.Int).Div(params.Q, m)
+	g := new(big.Int).Exp(params.G, cofactor, p)
+	y = new(big.Int).Exp(y, cofactor, p)
+
+	// gamma = g'^(r^(e-1)) of order r
+	rPower := new(big.Int).Div(m, pp.r)
+	gamma := new(big.Int).Exp(g, rPower, p)
+	gInv := new(big.Int).ModInverse(g, p)
+
+	x := new(big.Int)
+	digitValue := big.NewInt(1)
+	h, tmp := new(big.Int), new(big.Int)
+
+	for k := 0; k < pp.e; k++ {
+		// h = (y' * g'^-x)^(r^(e-1-k))
+		h.Exp(gInv, x, p).Mul(h, y).Mod(h, p)
+		h.Exp(h, rPower, p)
+
+		d, err := search.Smallest(ctx, helpers.BigZero, pp.r, 0, func(d *big.Int) bool {
+			return new(big.Int).Exp(gamma, d, p).Cmp(h) == 0
+		})
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			return nil, fmt.Errorf("no digit of the logarithm modulo %d", m)
+		}
+
+		x.Add(x, tmp.Mul(d, digitValue))
+		digitValue.Mul(digitValue, pp.r)
+		rPower.Div(rPower, pp.r)
+	}
+
+	return x, nil
+}
@@ -0,0 +1,121 @@
+package challenge57
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/congruence"
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	oracle2 "github.com/svkirillov/cryptopals-go/oracle"
+)
+
+func TestGenerateSmoothGroup(t *testing.T) {
+	dhGroup, factors, err := dh.GenerateSmoothGroup(helpers.NewSeededReader(49), 256, big.NewInt(1<<8))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	params := dhGroup.DHParams()
+
+	if params.P.BitLen() != 256 || !params.P.ProbablyPrime(20) {
+		t.Fatalf("%s: P = %d isn't a 256-bit prime", t.Name(), params.P)
+	}
+
+	// P-1 is the product of the factors, and g generates the whole group
+	product := big.NewInt(1)
+	for _, r := range factors {
+		product.Mul(product, r)
+	}
+	if product.Add(product, helpers.BigOne).Cmp(params.P) != 0 {
+		t.Fatalf("%s: P-1 isn't the product of %d", t.Name(), factors)
+	}
+
+	repeated := 0
+	for i, r := range factors {
+		if i > 0 && factors[i-1].Cmp(r) == 0 {
+			repeated++
+			continue
+		}
+
+		e := new(big.Int).Div(params.Q, r)
+		if new(big.Int).Exp(params.G, e, params.P).Cmp(helpers.BigOne) == 0 {
+			t.Errorf("%s: the order of g divides (P-1)/%d", t.Name(), r)
+		}
+	}
+
+	// there are only 54 primes below 2^8
+	if repeated == 0 {
+		t.Errorf("%s: no prime powers in %d", t.Name(), factors)
+	}
+}
+
+func TestPohligHellmanAttack(t *testing.T) {
+	smoothGroupTests := []struct {
+		pBits       int
+		smoothBound int64
+	}{
+		{128, 1 << 8},
+		{512, 1 << 12},
+		{1024, 1 << 12},
+	}
+
+	for _, e := range smoothGroupTests {
+		rng := helpers.NewSeededReader(int64(e.pBits))
+
+		dhGroup, factors, err := dh.GenerateSmoothGroup(rng, e.pBits, big.NewInt(e.smoothBound))
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+		t.Logf("%s: %s: factors of p-1: %d", t.Name(), dhGroup.DHName(), factors)
+
+		bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, rng)
+
+		privateKey, err := PohligHellmanAttack(context.Background(), dhGroup, bob, big.NewInt(e.smoothBound), nil)
+		if err != nil {
+			t.Fatalf("%s: %s: Pohlig-Hellman attack failed: %s", t.Name(), dhGroup.DHName(), err.Error())
+		}
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: %s: computed key isn't equal to Bob's private key", t.Name(), dhGroup.DHName())
+		}
+
+		// (p-1)/q = 1, so there are no small subgroups apart from the group
+		// of Bob's key
+		if _, err := SmallSubgroupAttack(context.Background(), dhGroup, bob, nil); err == nil {
+			t.Errorf("%s: %s: small subgroup attack didn't fail", t.Name(), dhGroup.DHName())
+		}
+	}
+}
+
+func TestPohligHellmanAttackOnPrimeOrder(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+	bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+
+	// q is a 128-bit prime
+	if _, err := PohligHellmanAttack(context.Background(), dhGroup, bob, big.NewInt(1<<16), nil); err == nil {
+		t.Fatalf("%s: Pohlig-Hellman attack didn't fail", t.Name())
+	}
+}
+
+func TestPohligHellmanAttackContext(t *testing.T) {
+	dhGroup, _, err := dh.GenerateSmoothGroup(helpers.NewSeededReader(49), 256, big.NewInt(1<<8))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, helpers.NewSeededReader(50))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = PohligHellmanAttack(ctx, dhGroup, bob, big.NewInt(1<<8), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+
+	var partial *congruence.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("%s: got error %T, want *congruence.PartialError", t.Name(), err)
+	}
+}
@@ -1,52 +1,32 @@
 package challenge58
 
 import (
-	"crypto/hmac"
-	"errors"
+	"context"
 	"fmt"
-	"math"
 	"math/big"
 
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/challenge57"
+	"github.com/svkirillov/cryptopals-go/checkpoint"
+	"github.com/svkirillov/cryptopals-go/congruence"
 	"github.com/svkirillov/cryptopals-go/dh"
 	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/kangaroo"
 	"github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
 )
 
-// f maps group elements to scalars.
-// See tasks/challenge58.txt:24 and tasks/challenge58.txt:94 for details.
-func f(y, k, p *big.Int) *big.Int {
-	// f = 2^(y mod k) mod p
-	return new(big.Int).Exp(helpers.BigTwo, new(big.Int).Mod(y, k), p)
-}
-
-// calcK calculates k based on a formula in this paper: https://arxiv.org/pdf/0812.0789.pdf
-func calcK(a, b *big.Int) *big.Int {
-	// k = log2(sqrt(b-a)) + log2(log2(sqrt(b-a))) - 2
-	sqrtba := math.Sqrt(float64(new(big.Int).Sub(b, a).Uint64()))
-	logSqrt := math.Log2(sqrtba)
-	logLogSqrt := math.Log2(logSqrt)
-	return new(big.Int).SetUint64(uint64(logSqrt + logLogSqrt - 2))
-}
+// maxIntervalBits is the size of the largest interval in which the wild
+// kangaroo can be caught in a reasonable time.
+const maxIntervalBits = 64
 
-// calcN calculates amount of leaps for tame kangaroo.
-func calcN(p, k *big.Int) *big.Int {
-	N := new(big.Int).Set(helpers.BigZero)
-
-	for i := new(big.Int).Set(helpers.BigZero); i.Cmp(k) < 0; i.Add(i, helpers.BigOne) {
-		N.Add(N, f(i, k, p))
-	}
-
-	N.Div(N, k)
-
-	// see for details: tasks/challenge58.txt:99
-	return N.Mul(big.NewInt(4), N)
-}
+// jumpsPerEvent is the number of jumps between the progress reports of
+// a kangaroo.
+const jumpsPerEvent = 1 << 16
 
 // tameKangaroo returns distance traveled by tame kangaroo and where he
-// ended up.
-func tameKangaroo(g, b, p, k *big.Int) (xT, yT *big.Int) {
-	N := calcN(p, k)
-
+// ended up, or nils if ctx is done before he stops.
+func tameKangaroo(ctx context.Context, g, b, p *big.Int, params *kangaroo.Params, events progress.Reporter) (xT, yT *big.Int) {
 	// xT := 0
 	// yT := g^b
 	xT = new(big.Int).Set(helpers.BigZero)
@@ -54,23 +34,74 @@ func tameKangaroo(g, b, p, k *big.Int) (xT, yT *big.Int) {
 
 	tmp := new(big.Int)
 
+	var jumps uint64
+
 	// for i in 1..N:
-	for i := new(big.Int).Set(helpers.BigZero); i.Cmp(N) < 0; i.Add(i, helpers.BigOne) {
+	for i := new(big.Int).Set(helpers.BigZero); i.Cmp(params.N) < 0; i.Add(i, helpers.BigOne) {
+		fVal := params.Jumps.Jump(yT)
+
 		// xT := xT + f(yT)
-		xT.Add(xT, f(yT, k, p))
+		xT.Add(xT, fVal)
 
 		// yT := yT * g^f(yT)
-		yT.Mod(yT.Mul(yT, tmp.Exp(g, f(yT, k, p), p)), p)
+		yT.Mod(yT.Mul(yT, tmp.Exp(g, fVal, p)), p)
+
+		if jumps++; jumps%jumpsPerEvent == 0 {
+			reportJumps(events, "tame", jumps, xT)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		default:
+			// pass
+		}
 	}
+	reportJumps(events, "tame", jumps, xT)
 
 	return
 }
 
-// CatchingWildKangaroo implements Pollard's method for catching kangaroos
-func CatchingWildKangaroo(g, y, p *big.Int, a, b *big.Int) *big.Int {
-	k := calcK(a, b)
-	xT, yT := tameKangaroo(g, b, p, k)
+func reportJumps(events progress.Reporter, kangaroo string, jumps uint64, distance *big.Int) {
+	events.Report(progress.Event{
+		Kind:     progress.KangarooJumps,
+		Kangaroo: kangaroo,
+		Jumps:    jumps,
+		Distance: new(big.Int).Set(distance),
+	})
+}
+
+// CatchingWildKangaroo implements Pollard's method for catching kangaroos.
+// The kangaroos jump according to opts.Kangaroo and are run again with new
+// jumps if the wild one escapes, up to opts.Kangaroo.MaxAttempts() times.
+// The jumps are reported to opts.Events, and opts may be nil. It gives up
+// with nil once ctx is done.
+func CatchingWildKangaroo(ctx context.Context, g, y, p *big.Int, a, b *big.Int, opts *attack.Options) *big.Int {
+	opts = attack.OrDefault(opts)
+	events, cfg := opts.Events, opts.Kangaroo
+
+	for attempt := 0; attempt < cfg.MaxAttempts(); attempt++ {
+		params, err := cfg.Params(a, b, attempt)
+		if err != nil {
+			return nil
+		}
+
+		xT, yT := tameKangaroo(ctx, g, b, p, params, events)
+		if xT == nil {
+			return nil
+		}
+
+		if x := wildKangaroo(ctx, g, y, p, a, b, params.Jumps, xT, yT, events); x != nil || ctx.Err() != nil {
+			return x
+		}
+	}
 
+	return nil
+}
+
+// wildKangaroo returns the logarithm of y in [a, b], if the wild kangaroo
+// falls into the trap (xT, yT) of the tame one, or nil.
+func wildKangaroo(ctx context.Context, g, y, p, a, b *big.Int, jumps kangaroo.Jumps, xT, yT *big.Int, events progress.Reporter) *big.Int {
 	// xW := 0
 	// yW := y
 	xW := new(big.Int).Set(helpers.BigZero)
@@ -81,9 +112,11 @@ func CatchingWildKangaroo(g, y, p *big.Int, a, b *big.Int) *big.Int {
 	tmp.Sub(b, a).Add(tmp, xT)
 	xWUpperBound := new(big.Int).Set(tmp) // xWUpperBound := b - a + xT
 
+	var n uint64
+
 	// while xW < b - a + xT:
 	for xW.Cmp(xWUpperBound) < 0 {
-		fVal := f(yW, k, p)
+		fVal := jumps.Jump(yW)
 
 		// xW := xW + f(yW)
 		xW.Add(xW, fVal)
@@ -93,81 +126,65 @@ func CatchingWildKangaroo(g, y, p *big.Int, a, b *big.Int) *big.Int {
 
 		// if yW = yT:
 		if yW.Cmp(yT) == 0 {
+			reportJumps(events, "wild", n+1, xW)
+
 			// b + xT - xW
 			return tmp.Add(b, tmp.Sub(xT, xW))
 		}
+
+		if n++; n%jumpsPerEvent == 0 {
+			reportJumps(events, "wild", n, xW)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			// pass
+		}
 	}
+	reportJumps(events, "wild", n, xW)
 
 	return nil
 }
 
+// CatchingKangaroosAttack recovers Bob's private key in [0, opts.KeyBound),
+// or in [0, q) if opts.KeyBound is nil, combining the small subgroup
+// confinement attack with Pollard's kangaroos. The recovered residues and
+// the trap of the tame kangaroo are saved to opts.Store, and the attack
+// resumes from the state saved there. It stops once ctx is done, and the
+// residues recovered so far are returned in a *congruence.PartialError.
+// opts may be nil.
 func CatchingKangaroosAttack(
+	ctx context.Context,
 	dhGroup dh.DHScheme,
-	oracleDH func(publicKey *big.Int) []byte,
-	getPublicKey func() *big.Int,
+	oracleDH oracle.DHOracle,
+	publicKey oracle.PublicKeySource,
+	opts *attack.Options,
 ) (*big.Int, error) {
-	p := dhGroup.DHParams().P
-	g := dhGroup.DHParams().G
-	q := dhGroup.DHParams().Q
+	opts = attack.OrDefault(opts)
+	events, cfg, store := opts.Events, opts.Kangaroo, opts.Store
 
-	j := new(big.Int).Div(new(big.Int).Sub(p, helpers.BigOne), q)
-
-	// Step #0
-	jFactors := helpers.Factorize(j, big.NewInt(1<<16))
-	if len(jFactors) == 0 {
-		return nil, errors.New("factors not found")
+	keyBound := opts.KeyBound
+	if keyBound == nil {
+		keyBound = dhGroup.DHParams().Q
 	}
 
-	var modules, remainders []*big.Int
-	tmp := new(big.Int)
-
-	for _, r := range jFactors {
-		// Step #1
-		power := tmp.Div(tmp.Sub(p, helpers.BigOne), r)
-		h := new(big.Int).Set(helpers.BigOne)
-		for h.Cmp(helpers.BigOne) == 0 {
-			rand, err := helpers.GenerateBigInt(p)
-			if err != nil {
-				return nil, fmt.Errorf("couldn'ss generate random big.Int: %s", err.Error())
-			}
-
-			for rand.Cmp(helpers.BigZero) == 0 {
-				rand, err = helpers.GenerateBigInt(p)
-				if err != nil {
-					return nil, fmt.Errorf("couldn'ss generate random big.Int: %s", err.Error())
-				}
-			}
-
-			h.Exp(rand, power, p)
-		}
-
-		// Step #2,3
-		ss := oracleDH(h)
-
-		// Step #4
-		for i := big.NewInt(1); i.Cmp(r) <= 0; i.Add(i, helpers.BigOne) {
-			k1 := dhGroup.DH(i, h)
-			ss1 := oracle.MAC(k1.Bytes())
-
-			if hmac.Equal(ss, ss1) {
-				modules = append(modules, r)
-				remainders = append(remainders, new(big.Int).Set(i))
-				continue
-			}
-		}
-	}
+	p := dhGroup.DHParams().P
+	g := dhGroup.DHParams().G
 
-	if len(modules) == 0 {
-		return nil, errors.New("empty sets of modules and remainders")
-	}
+	tmp := new(big.Int)
 
 	// x = n mod r
-	n, r, err := helpers.ChineseRemainderTheorem(remainders, modules)
+	n, r, err := challenge57.SmallSubgroupResidues(ctx, dhGroup, oracleDH, big.NewInt(1<<16), opts)
 	if err != nil {
-		return nil, fmt.Errorf("chinese remainder theorem: %s", err.Error())
+		return nil, err
 	}
 
-	y := getPublicKey()
+	y, err := publicKey.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
 
 	// y' = y * g^-n
 	newY := new(big.Int).Mod(tmp.Mul(y, tmp.Exp(g, tmp.Neg(n), p)), p)
@@ -175,18 +192,70 @@ func CatchingKangaroosAttack(
 	// g' = g^r
 	newG := new(big.Int).Exp(g, r, p)
 
-	// [a, b] = [0, (q-1)/r]
+	// [a, b] = [0, (keyBound-1)/r]
 	a := new(big.Int).Set(helpers.BigZero)
-	b := new(big.Int).Div(tmp.Sub(q, helpers.BigOne), r)
+	b := new(big.Int).Div(tmp.Sub(keyBound, helpers.BigOne), r)
 
 	// if q too small
 	if b.Cmp(helpers.BigZero) == 0 {
 		b = new(big.Int).SetUint64(1 << 20)
 	}
 
-	m := CatchingWildKangaroo(newG, newY, p, a, b)
+	if b.BitLen() > maxIntervalBits {
+		return nil, fmt.Errorf("interval [0, %d] is too large for catching kangaroos", b)
+	}
+
+	partial := &congruence.PartialError{}
+	partial.Congruences.Add(n, r)
+
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var m *big.Int
+
+	for attempt := 0; m == nil && attempt < cfg.MaxAttempts(); attempt++ {
+		params, err := cfg.Params(a, b, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		if attempt > 0 {
+			events.Report(progress.Event{
+				Kind:    progress.Note,
+				Message: fmt.Sprintf("the wild kangaroo escaped, the kangaroos jump %s now", params.Jumps),
+			})
+		}
+
+		// the trap is only reused if it's made with the same base, interval
+		// and jumps
+		var xT, yT *big.Int
+		if trap := state.Trap; trap != nil && trap.Y == nil && trap.Jumps == params.Jumps.String() &&
+			trap.X.Cmp(new(big.Int).Exp(newG, tmp.Add(b, trap.Distance), p)) == 0 {
+			xT, yT = trap.Distance, trap.X
+			events.Report(progress.Event{Kind: progress.Note, Message: "resumed with the tame kangaroo's trap from the checkpoint"})
+		} else {
+			xT, yT = tameKangaroo(ctx, newG, b, p, params, events)
+			if xT == nil {
+				partial.Err = ctx.Err()
+				return nil, partial
+			}
+
+			state.Trap = &checkpoint.Trap{Distance: xT, X: yT, Jumps: params.Jumps.String()}
+			if err := store.Save(state); err != nil {
+				return nil, err
+			}
+		}
+
+		m = wildKangaroo(ctx, newG, newY, p, a, b, params.Jumps, xT, yT, events)
+		if ctx.Err() != nil {
+			partial.Err = ctx.Err()
+			return nil, partial
+		}
+	}
 	if m == nil {
-		return nil, errors.New("got wrong value from CatchingWildKangaroo")
+		return nil, fmt.Errorf("the wild kangaroo escaped %d times", cfg.MaxAttempts())
 	}
 
 	// x = n + m*r
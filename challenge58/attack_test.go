@@ -1,12 +1,19 @@
 package challenge58
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"math/big"
 	"testing"
 
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/congruence"
 	"github.com/svkirillov/cryptopals-go/dh"
 	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/kangaroo"
 	oracle2 "github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
 )
 
 func TestCatchWildKangaroo(t *testing.T) {
@@ -16,23 +23,247 @@ func TestCatchWildKangaroo(t *testing.T) {
 	a := new(big.Int).Set(helpers.BigZero)
 	b := new(big.Int).SetUint64(1 << 20)
 
-	x := CatchingWildKangaroo(g, y, p, a, b)
+	x := CatchingWildKangaroo(context.Background(), g, y, p, a, b, nil)
 	if x == nil || new(big.Int).Exp(g, x, p).Cmp(y) != 0 {
 		t.Error("Pollard's method for catching kangaroos fails")
 	}
 }
 
+// constantJumps never lets the wild kangaroo catch up with the tame one,
+// unless he starts at b. The mean is made up to keep the tame kangaroo
+// short.
+type constantJumps struct {
+	length *big.Int
+}
+
+func (j constantJumps) Jump(*big.Int) *big.Int { return j.length }
+func (j constantJumps) Mean() *big.Int         { return helpers.BigOne }
+func (j constantJumps) String() string         { return "constant " + j.length.String() }
+
+func TestCatchWildKangarooWithConfig(t *testing.T) {
+	p := helpers.SetBigIntFromDec("11470374874925275658116663507232161402086650258453896274534991676898999262641581519101074740642369848233294239851519212341844337347119899874391456329785623")
+	g := helpers.SetBigIntFromDec("622952335333961296978159266084741085889881358738459939978290179936063635566740258555167783009058567397963466103140082647486611657350811560630587013183357")
+	a := new(big.Int).Set(helpers.BigZero)
+	b := new(big.Int).SetUint64(1 << 20)
+
+	x := big.NewInt(123456)
+	y := new(big.Int).Exp(g, x, p)
+
+	// the wild kangaroo escapes in the first attempt
+	jumps := func(width *big.Int, attempt int) (kangaroo.Jumps, error) {
+		if attempt == 0 {
+			return constantJumps{length: new(big.Int).Add(width, helpers.BigOne)}, nil
+		}
+		return kangaroo.PowersOfTwo(width, attempt)
+	}
+
+	opts := &attack.Options{Kangaroo: &kangaroo.Config{Jumps: jumps, Attempts: 1}}
+	if got := CatchingWildKangaroo(context.Background(), g, y, p, a, b, opts); got != nil {
+		t.Fatalf("%s: got %d with constant jumps, want nil", t.Name(), got)
+	}
+
+	opts.Kangaroo.Attempts = 2
+	got := CatchingWildKangaroo(context.Background(), g, y, p, a, b, opts)
+	if got == nil || got.Cmp(x) != 0 {
+		t.Errorf("%s: got %d in the second attempt, want %d", t.Name(), got, x)
+	}
+}
+
 func TestCatchingKangaroosAttack(t *testing.T) {
 	dhGroup := dh.MODP512V58()
 
-	oracle, isKeyCorrect, getPublicKey := oracle2.NewDHAttackOracle(dhGroup)
+	bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+	meter := oracle2.NewMeter(0)
 
-	privateKey, err := CatchingKangaroosAttack(dhGroup, oracle, getPublicKey)
+	privateKey, err := CatchingKangaroosAttack(context.Background(), dhGroup, oracle2.NewMeteredDHOracle(bob, meter), bob, nil)
 	if err != nil {
 		t.Fatalf("CatchingKangaroosAttack fails: %s", err.Error())
 	}
+	t.Logf("%s: %s", t.Name(), meter.Summary())
 
-	if !isKeyCorrect(privateKey.Bytes()) {
+	if !bob.IsKeyCorrect(privateKey.Bytes()) {
 		t.Fatal("computed key isn't equal to Bob's private key")
 	}
+
+	// one query per small factor of (p-1)/q, the kangaroos don't need Bob
+	j := new(big.Int).Sub(dhGroup.DHParams().P, helpers.BigOne)
+	j.Div(j, dhGroup.DHParams().Q)
+	if n, max := meter.Summary().Queries, len(helpers.Factorize(j, big.NewInt(1<<16))); n > max {
+		t.Errorf("%s: %d queries, want at most %d", t.Name(), n, max)
+	}
+}
+
+func TestCatchingKangaroosAttackOnWeakGroups(t *testing.T) {
+	weakGroupTests := []struct {
+		pBits, qBits int
+	}{
+		// the kangaroo has to catch keys in an interval of about 2^(2*qBits-pBits)
+		{160, 96},
+		{256, 140},
+		{512, 272},
+	}
+
+	for _, e := range weakGroupTests {
+		dhGroup, factors, err := dh.GenerateWeakGroup(nil, e.pBits, e.qBits, big.NewInt(1<<16))
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+		t.Logf("%s: %s: factors of (p-1)/q: %d", t.Name(), dhGroup.DHName(), factors)
+
+		bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+
+		privateKey, err := CatchingKangaroosAttack(context.Background(), dhGroup, bob, bob, nil)
+		if err != nil {
+			t.Fatalf("%s: %s: CatchingKangaroosAttack fails: %s", t.Name(), dhGroup.DHName(), err.Error())
+		}
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: %s: computed key isn't equal to Bob's private key", t.Name(), dhGroup.DHName())
+		}
+	}
+}
+
+func TestCatchingKangaroosAttackOnStandardGroups(t *testing.T) {
+	// (p-1)/q = 2 for safe primes, so the interval is far too large
+	for _, name := range []string{"MODP-1536", "MODP-2048", "FFDHE-2048", "FFDHE-3072"} {
+		dhGroup, err := dh.GroupByName(name)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+
+		bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+
+		if _, err := CatchingKangaroosAttack(context.Background(), dhGroup, bob, bob, nil); err == nil {
+			t.Errorf("%s: %s: CatchingKangaroosAttack succeeded on a safe prime group", t.Name(), name)
+		}
+	}
+
+	// Bob uses short exponents with RFC 5114 groups
+	keyBound := new(big.Int).Lsh(helpers.BigOne, 40)
+
+	for _, name := range []string{"RFC5114-1024-160", "RFC5114-2048-224", "RFC5114-2048-256"} {
+		dhGroup, err := dh.GroupByName(name)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+
+		privateKey, err := rand.Int(rand.Reader, keyBound)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", t.Name(), name, err.Error())
+		}
+		publicKey := new(big.Int).Exp(dhGroup.DHParams().G, privateKey, dhGroup.DHParams().P)
+
+		bob := oracle2.NewDHAttackOracleWithKey(dhGroup, dh.NoValidation, &dh.DHKey{
+			Private: privateKey,
+			Public:  publicKey,
+		})
+
+		x, err := CatchingKangaroosAttack(context.Background(), dhGroup, bob, bob, &attack.Options{KeyBound: keyBound})
+		if err != nil {
+			t.Fatalf("%s: %s: CatchingKangaroosAttack fails: %s", t.Name(), name, err.Error())
+		}
+
+		if x.Cmp(privateKey) != 0 {
+			t.Fatalf("%s: %s: computed key isn't equal to Bob's private key", t.Name(), name)
+		}
+	}
+}
+
+func TestCatchingKangaroosAttackWithValidation(t *testing.T) {
+	validationTests := []struct {
+		policy  dh.ValidationPolicy
+		success bool
+	}{
+		// only rejects the element of order 2
+		{dh.RangeCheck, true},
+		{dh.SubgroupCheck, false},
+		{dh.CofactorClearing, false},
+	}
+
+	dhGroup := dh.MODP512V58()
+
+	for _, e := range validationTests {
+		bob := oracle2.NewDHAttackOracle(dhGroup, e.policy, nil)
+
+		privateKey, err := CatchingKangaroosAttack(context.Background(), dhGroup, bob, bob, nil)
+
+		if !e.success {
+			if err == nil {
+				t.Errorf("%s: %s: CatchingKangaroosAttack succeeded", t.Name(), e.policy)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %s: CatchingKangaroosAttack fails: %s", t.Name(), e.policy, err.Error())
+		}
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: %s: computed key isn't equal to Bob's private key", t.Name(), e.policy)
+		}
+	}
+}
+
+func TestCatchingKangaroosAttackWithoutPublicKey(t *testing.T) {
+	dhGroup, _, err := dh.GenerateWeakGroup(helpers.NewSeededReader(35), 160, 96, big.NewInt(1<<16))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	bob := oracle2.NewDHAttackOracle(dhGroup, dh.NoValidation, nil)
+
+	// Bob's public key isn't served, so it can't be fetched
+	srv, err := oracle2.StartServer("127.0.0.1:0", oracle2.ServerOracles{DH: bob})
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	defer srv.Close()
+
+	client, err := oracle2.Dial(srv.Addr())
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	defer client.Close()
+
+	if _, err := CatchingKangaroosAttack(context.Background(), dhGroup, client, client.DHPublicKey(), nil); !errors.Is(err, oracle2.ErrRemote) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, oracle2.ErrRemote)
+	}
+}
+
+func TestCatchingKangaroosAttackContext(t *testing.T) {
+	dhGroup := dh.MODP512V58()
+
+	dhKey, err := dhGroup.GenerateKey(helpers.NewSeededReader(43))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	bob := oracle2.NewDHAttackOracleWithKey(dhGroup, dh.NoValidation, dhKey)
+
+	// the attack is canceled once the kangaroos start to jump
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := progress.ReporterFunc(func(e progress.Event) {
+		if e.Kind == progress.KangarooJumps {
+			cancel()
+		}
+	})
+
+	_, err = CatchingKangaroosAttack(ctx, dhGroup, bob, bob, &attack.Options{Events: events})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+
+	var partial *congruence.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("%s: got error %T, want *congruence.PartialError", t.Name(), err)
+	}
+
+	x, n, err := partial.Congruences.Solve()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if new(big.Int).Mod(dhKey.Private, n).Cmp(x) != 0 {
+		t.Errorf("%s: x = %d mod %d isn't Bob's private key", t.Name(), x, n)
+	}
 }
@@ -1,22 +1,36 @@
 package challenge59
 
 import (
-	"crypto/hmac"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/congruence"
 	"github.com/svkirillov/cryptopals-go/elliptic"
 	"github.com/svkirillov/cryptopals-go/helpers"
 	"github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
+	"github.com/svkirillov/cryptopals-go/search"
 )
 
-// pickRandomPoint picks a random point on given curve
-func pickRandomPoint(curve elliptic.Curve, order *big.Int) (x *big.Int, y *big.Int) {
+// maxAttempts is the number of points sent to Bob for each factor before
+// giving up, if his answers are lost or corrupted.
+const maxAttempts = 8
+
+// pickRandomPoint picks a random point of given order on given curve, or
+// returns nils once ctx is done.
+func pickRandomPoint(ctx context.Context, curve elliptic.Curve, order *big.Int, rng io.Reader) (x *big.Int, y *big.Int) {
 	k := new(big.Int).Div(curve.Params().N, order).Bytes()
 
 	for {
-		x, y = elliptic.GeneratePoint(curve)
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+
+		x, y = elliptic.GeneratePoint(curve, rng)
 		x, y = curve.ScalarMult(x, y, k)
 
 		if x.Cmp(helpers.BigZero) == 0 && y.Cmp(helpers.BigZero) == 0 {
@@ -27,10 +41,10 @@ func pickRandomPoint(curve elliptic.Curve, order *big.Int) (x *big.Int, y *big.I
 	}
 }
 
-// ecdh performs DH with given curve, public and private keys
+// ecdh returns the shared secret with given curve, public and private keys
 func ecdh(curve elliptic.Curve, x *big.Int, y *big.Int, privateKey []byte) []byte {
 	ssx, ssy := curve.ScalarMult(x, y, privateKey)
-	return oracle.MAC(elliptic.Marshal(curve, ssx, ssy))
+	return elliptic.Marshal(curve, ssx, ssy)
 }
 
 // checkDuplicate returns true if no duplicates were found
@@ -51,12 +65,12 @@ func checkDuplicate(reminders []*big.Int, modules []*big.Int, r *big.Int, m *big
 	return ok
 }
 
-func InvalidCurveAttack(oracleECDH func(x, y *big.Int) []byte) (*big.Int, error) {
-	invalidCurves := []elliptic.Curve{elliptic.P128V1(), elliptic.P128V2(), elliptic.P128V3()}
-
-	var modules, remainders []*big.Int
+// challengeCurves returns the malicious curves from the challenge with the
+// odd factors of their orders below 2^16.
+func challengeCurves() ([]*elliptic.InvalidCurve, error) {
+	var invalidCurves []*elliptic.InvalidCurve
 
-	for _, curve := range invalidCurves {
+	for _, curve := range []elliptic.Curve{elliptic.P128V1(), elliptic.P128V2(), elliptic.P128V3()} {
 		factors := helpers.Factorize(curve.Params().N, big.NewInt(1<<16))
 		if len(factors) == 0 {
 			return nil, errors.New("factors not found")
@@ -65,27 +79,167 @@ func InvalidCurveAttack(oracleECDH func(x, y *big.Int) []byte) (*big.Int, error)
 			factors = factors[1:]
 		}
 
-		for _, factor := range factors {
-			x, y := pickRandomPoint(curve, factor)
+		invalidCurves = append(invalidCurves, &elliptic.InvalidCurve{
+			CurveParams: curve.Params(),
+			Factors:     factors,
+		})
+	}
+
+	return invalidCurves, nil
+}
+
+// InvalidCurveAttack recovers the private key using points of small order
+// on the given invalid curves, e.g. the ones found by
+// elliptic.GenerateInvalidCurves. If invalidCurves is nil, the malicious
+// curves from the challenge are used, which are only good for
+// elliptic.P128(). Bob's key is still recovered if he multiplies the points
+// by the cofactor, once opts.Cofactor is set. The recovered residues are
+// saved to opts.Store, and the attack resumes from the residues saved there.
+// It stops once ctx is done,
+// and the residues recovered so far are returned in
+// a *congruence.PartialError. opts may be nil.
+func InvalidCurveAttack(
+	ctx context.Context,
+	oracleECDH oracle.ECDHOracle,
+	invalidCurves []*elliptic.InvalidCurve,
+	opts *attack.Options,
+) (*big.Int, error) {
+	if invalidCurves == nil {
+		var err error
+		if invalidCurves, err = challengeCurves(); err != nil {
+			return nil, err
+		}
+	}
+
+	congruences, err := invalidCurveResidues(ctx, oracleECDH, invalidCurves, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	x, _, err := congruences.Solve()
+	if err != nil {
+		return nil, err
+	}
+
+	return x, nil
+}
+
+// invalidCurveResidues recovers the private key modulo the factors of the
+// invalid curves which aren't saved to opts.Store yet, and returns the
+// congruences together with the saved ones.
+func invalidCurveResidues(
+	ctx context.Context,
+	oracleECDH oracle.ECDHOracle,
+	invalidCurves []*elliptic.InvalidCurve,
+	opts *attack.Options,
+) (congruence.System, error) {
+	opts = attack.OrDefault(opts)
+	events, store, rng := opts.Events, opts.Store, opts.Rand
+
+	var factors []*big.Int
+	for _, curve := range invalidCurves {
+		factors = append(factors, curve.Factors...)
+	}
+
+	state, err := store.Load()
+	if err != nil {
+		return congruence.System{}, err
+	}
+	if err := state.CheckModules(factors); err != nil {
+		return congruence.System{}, err
+	}
+	if state.Congruences.Len() > 0 {
+		events.Report(progress.Event{
+			Kind:    progress.Note,
+			Message: fmt.Sprintf("resumed with %d residues from the checkpoint", state.Congruences.Len()),
+		})
+	}
+
+	congruences := state.Congruences
 
-			ss := oracleECDH(x, y)
+	for _, curve := range invalidCurves {
+		for _, factor := range curve.Factors {
+			if state.HasModulus(factor) {
+				continue
+			}
 
-			for k := big.NewInt(1); k.Cmp(factor) <= 0; k.Add(k, helpers.BigOne) {
-				ss1 := ecdh(curve, x, y, k.Bytes())
+			events.Report(progress.Event{Kind: progress.SubgroupFound, Modulus: factor})
 
-				if hmac.Equal(ss, ss1) && checkDuplicate(remainders, modules, k, factor) {
-					remainders = append(remainders, new(big.Int).Set(k))
-					modules = append(modules, factor)
-					break
+			k, err := residue(ctx, oracleECDH, curve, factor, rng)
+			if ctx.Err() != nil {
+				return congruence.System{}, &congruence.PartialError{Congruences: congruences, Err: ctx.Err()}
+			}
+			if err != nil {
+				return congruence.System{}, err
+			}
+
+			// H*d = k mod factor gives d = k * H^-1 mod factor
+			if k != nil && opts.Cofactor != nil {
+				hInv := new(big.Int).ModInverse(opts.Cofactor, factor)
+				if hInv == nil {
+					return congruence.System{}, fmt.Errorf("the cofactor %d isn't invertible modulo %d", opts.Cofactor, factor)
+				}
+				k.Mul(k, hInv).Mod(k, factor)
+			}
+
+			if k != nil && checkDuplicate(congruences.Remainders, congruences.Modules, k, factor) {
+				congruences.Add(k, factor)
+
+				state.Congruences = congruences
+				if err := store.Save(state); err != nil {
+					return congruence.System{}, err
 				}
+
+				n := congruences.Modulus()
+				events.Report(progress.Event{Kind: progress.ResidueRecovered, Residue: new(big.Int).Mod(k, factor), Modulus: factor})
+				events.Report(progress.Event{Kind: progress.ModulusGrown, Modulus: n, Bits: n.BitLen()})
 			}
 		}
 	}
 
-	x, _, err := helpers.ChineseRemainderTheorem(remainders, modules)
-	if err != nil {
-		return nil, fmt.Errorf("chinese remainder theorem: %s", err.Error())
+	return congruences, nil
+}
+
+// residue returns the private key modulo factor, or nil if Bob rejects
+// points of order factor on the curve. The query is repeated with a new
+// point if the answer is lost or doesn't match any residue. It stops with
+// ctx.Err() once ctx is done.
+func residue(
+	ctx context.Context,
+	oracleECDH oracle.ECDHOracle,
+	curve elliptic.Curve,
+	factor *big.Int,
+	rng io.Reader,
+) (*big.Int, error) {
+	checker := oracle.CheckerOf(oracleECDH)
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		x, y := pickRandomPoint(ctx, curve, factor, rng)
+		if x == nil {
+			return nil, ctx.Err()
+		}
+
+		ss, err := oracleECDH.ECDH(x, y)
+		if oracle.IsTransient(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("oracle: %w", err)
+		}
+		if ss == nil {
+			return nil, nil
+		}
+
+		k, err := search.Smallest(ctx, helpers.BigOne, new(big.Int).Add(factor, helpers.BigOne), 0, func(k *big.Int) bool {
+			return checker.CheckSecret(ecdh(curve, x, y, k.Bytes()), ss)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if k != nil {
+			return k, nil
+		}
 	}
 
-	return x, nil
+	return nil, fmt.Errorf("no residue modulo %d after %d attempts", factor, maxAttempts)
 }
@@ -1,24 +1,147 @@
 package challenge59
 
 import (
+	"context"
+	"errors"
+	"math/big"
 	"testing"
 
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/congruence"
 	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/helpers"
 	oracle2 "github.com/svkirillov/cryptopals-go/oracle"
 )
 
 func TestECDHInvalidCurveAttack(t *testing.T) {
 	p128 := elliptic.P128()
 
-	oracle, isKeyCorrect, _ := oracle2.NewECDHAttackOracle(p128)
+	bob := oracle2.NewECDHAttackOracle(p128, elliptic.NoValidation, nil)
+	meter := oracle2.NewMeter(0)
 
-	privateKey, err := InvalidCurveAttack(oracle)
+	privateKey, err := InvalidCurveAttack(context.Background(), oracle2.NewMeteredECDHOracle(bob, meter), nil, nil)
 	if err != nil {
 		t.Fatalf("%s: %s\n", t.Name(), err.Error())
 	}
 	t.Logf("%s: Private key: %d\n", t.Name(), privateKey)
+	t.Logf("%s: %s\n", t.Name(), meter.Summary())
 
-	if !isKeyCorrect(privateKey.Bytes()) {
+	if !bob.IsKeyCorrect(privateKey.Bytes()) {
 		t.Fatalf("%s: wrong private key was found in the invalid curve attack\n", t.Name())
 	}
+
+	// one query per small odd factor of the orders of the invalid curves
+	max := 0
+	for _, curve := range []elliptic.Curve{elliptic.P128V1(), elliptic.P128V2(), elliptic.P128V3()} {
+		max += len(helpers.Factorize(curve.Params().N, big.NewInt(1<<16))) - 1
+	}
+	if n := meter.Summary().Queries; n > max {
+		t.Errorf("%s: %d queries, want at most %d\n", t.Name(), n, max)
+	}
+}
+
+func TestECDHInvalidCurveAttackOnGeneratedCurves(t *testing.T) {
+	p48 := elliptic.P48()
+
+	invalidCurves, err := elliptic.GenerateInvalidCurves(p48, big.NewInt(1<<16), 100)
+	if err != nil {
+		t.Fatalf("%s: %s\n", t.Name(), err.Error())
+	}
+	for _, curve := range invalidCurves {
+		t.Logf("%s: %s: factors %d\n", t.Name(), curve.Name, curve.Factors)
+	}
+
+	bob := oracle2.NewECDHAttackOracle(p48, elliptic.NoValidation, nil)
+
+	privateKey, err := InvalidCurveAttack(context.Background(), bob, invalidCurves, nil)
+	if err != nil {
+		t.Fatalf("%s: %s\n", t.Name(), err.Error())
+	}
+	t.Logf("%s: Private key: %d\n", t.Name(), privateKey)
+
+	if !bob.IsKeyCorrect(privateKey.Bytes()) {
+		t.Fatalf("%s: wrong private key was found in the invalid curve attack\n", t.Name())
+	}
+}
+
+func TestECDHInvalidCurveAttackWithValidation(t *testing.T) {
+	p128 := elliptic.P128()
+
+	for _, policy := range []elliptic.ValidationPolicy{
+		elliptic.OnCurveCheck,
+		elliptic.OrderCheck,
+	} {
+		bob := oracle2.NewECDHAttackOracle(p128, policy, nil)
+
+		privateKey, err := InvalidCurveAttack(context.Background(), bob, nil, nil)
+		if err == nil && bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Errorf("%s: %s: invalid curve attack succeeded", t.Name(), policy)
+		}
+	}
+}
+
+func TestECDHInvalidCurveAttackWithCofactor(t *testing.T) {
+	p128 := elliptic.P128()
+	bob := oracle2.NewECDHAttackOracle(p128, elliptic.CofactorMultiplication, helpers.NewSeededReader(7))
+
+	h, err := elliptic.Cofactor(p128)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	// Bob leaks 8*d mod r instead of d mod r, which is multiplied by 8^-1
+	// mod r
+	privateKey, err := InvalidCurveAttack(context.Background(), bob, nil, &attack.Options{Cofactor: h})
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	if !bob.IsKeyCorrect(privateKey.Bytes()) {
+		t.Fatalf("%s: wrong private key was found in the invalid curve attack", t.Name())
+	}
+}
+
+func TestECDHInvalidCurveAttackContext(t *testing.T) {
+	p128 := elliptic.P128()
+	bob := oracle2.NewECDHAttackOracle(p128, elliptic.NoValidation, helpers.NewSeededReader(43))
+
+	// the attack is canceled while it looks for the third residue
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queries := 0
+	canceling := oracle2.ECDHOracleFunc(func(x, y *big.Int) ([]byte, error) {
+		if queries++; queries == 3 {
+			cancel()
+		}
+		return bob.ECDH(x, y)
+	})
+
+	_, err := InvalidCurveAttack(ctx, canceling, nil, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+
+	var partial *congruence.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("%s: got error %T, want *congruence.PartialError", t.Name(), err)
+	}
+	if partial.Congruences.Len() != 2 {
+		t.Fatalf("%s: got %d congruences, want 2", t.Name(), partial.Congruences.Len())
+	}
+
+	// Bob's key is only known through the residues, so the partial key is
+	// checked against the full attack with the same seed
+	privateKey, err := InvalidCurveAttack(context.Background(), oracle2.NewECDHAttackOracle(p128, elliptic.NoValidation, helpers.NewSeededReader(43)), nil, nil)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	x, n, err := partial.Congruences.Solve()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if new(big.Int).Mod(privateKey, n).Cmp(x) != 0 {
+		t.Errorf("%s: x = %d mod %d isn't Bob's private key", t.Name(), x, n)
+	}
 }
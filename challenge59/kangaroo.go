@@ -0,0 +1,176 @@
+package challenge59
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/congruence"
+	"github.com/svkirillov/cryptopals-go/dlog"
+	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
+)
+
+// maxIntervalBits is the size of the largest interval left to the
+// kangaroos. A wider one takes more than 2^32 jumps.
+const maxIntervalBits = 64
+
+// belowBound returns the invalid curves with only the factors less than
+// factorBound, dropping the curves left without factors.
+func belowBound(invalidCurves []*elliptic.InvalidCurve, factorBound *big.Int) []*elliptic.InvalidCurve {
+	var res []*elliptic.InvalidCurve
+
+	for _, curve := range invalidCurves {
+		var factors []*big.Int
+		for _, factor := range curve.Factors {
+			if factor.Cmp(factorBound) < 0 {
+				factors = append(factors, factor)
+			}
+		}
+
+		if len(factors) > 0 {
+			res = append(res, &elliptic.InvalidCurve{CurveParams: curve.CurveParams, Factors: factors})
+		}
+	}
+
+	return res
+}
+
+// modulusOf returns the product of the distinct factors of the invalid
+// curves.
+func modulusOf(invalidCurves []*elliptic.InvalidCurve) *big.Int {
+	r := big.NewInt(1)
+	seen := make(map[string]bool)
+
+	for _, curve := range invalidCurves {
+		for _, factor := range curve.Factors {
+			if key := factor.String(); !seen[key] {
+				seen[key] = true
+				r.Mul(r, factor)
+			}
+		}
+	}
+
+	return r
+}
+
+// interval returns the upper end b of [0, b] with every m such that n + m*r
+// is less than order, or an error if it's wider than maxIntervalBits.
+func interval(order, n, r *big.Int) (*big.Int, error) {
+	b := new(big.Int).Sub(order, helpers.BigOne)
+	b.Sub(b, n)
+	if b.Sign() < 0 {
+		return b.SetInt64(0), nil
+	}
+	b.Div(b, r)
+
+	if b.BitLen() > maxIntervalBits {
+		return nil, fmt.Errorf("the private key is known modulo %d-bit %d, which leaves %d bits to the kangaroos, "+
+			"more than %d: raise the factor bound", r.BitLen(), r, b.BitLen(), maxIntervalBits)
+	}
+
+	return b, nil
+}
+
+// InvalidCurveKangarooAttack recovers the private key x from the residues
+// modulo the factors of the invalid curves less than factorBound, which give
+// x = n mod r, and the public key: x = n + m*r, where m in [0, (N-1-n)/r] is
+// caught by the kangaroos on the target curve of order N. A lower bound
+// takes fewer queries to Bob, but a wider interval for the kangaroos. If
+// invalidCurves is nil, the malicious curves from the challenge are used,
+// which are only good for elliptic.P128(). The kangaroos jump according to
+// opts.Kangaroo. The recovered residues are saved to opts.Store, and the
+// attack resumes from the residues saved there. It stops once ctx is done,
+// and the residues recovered so far are returned in
+// a *congruence.PartialError. opts may be nil.
+func InvalidCurveKangarooAttack(
+	ctx context.Context,
+	oracleECDH oracle.ECDHOracle,
+	publicKey oracle.ECPublicKeySource,
+	curve elliptic.Curve,
+	invalidCurves []*elliptic.InvalidCurve,
+	factorBound *big.Int,
+	opts *attack.Options,
+) (*big.Int, error) {
+	opts = attack.OrDefault(opts)
+	events := opts.Events
+
+	if invalidCurves == nil {
+		var err error
+		if invalidCurves, err = challengeCurves(); err != nil {
+			return nil, err
+		}
+	}
+
+	invalidCurves = belowBound(invalidCurves, factorBound)
+	order := curve.Params().N
+
+	// Bob isn't asked anything if the interval is too wide even with every
+	// residue
+	if _, err := interval(order, helpers.BigZero, modulusOf(invalidCurves)); err != nil {
+		return nil, err
+	}
+
+	congruences, err := invalidCurveResidues(ctx, oracleECDH, invalidCurves, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// x = n mod r, or nothing is known if there are no residues
+	n, r := new(big.Int), big.NewInt(1)
+	if congruences.Len() > 0 {
+		if n, r, err = congruences.Solve(); err != nil {
+			return nil, err
+		}
+	}
+
+	// [a, b] = [0, (N-1-n)/r]
+	a := helpers.BigZero
+	b, err := interval(order, n, r)
+	if err != nil {
+		return nil, err
+	}
+
+	events.Report(progress.Event{
+		Kind:    progress.Note,
+		Message: fmt.Sprintf("Bob's private key is %d modulo %d, the kangaroos jump in [0, %d]", n, r, b),
+	})
+
+	pkx, pky, err := publicKey.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+
+	// g' = r*G, y' = y - n*G = m*g'
+	gx, gy := curve.ScalarBaseMult(r.Bytes())
+	yx, yy := pkx, pky
+	if n.Sign() != 0 {
+		nx, ny := curve.ScalarBaseMult(n.Bytes())
+		nx, ny = elliptic.Inverse(curve, nx, ny)
+		yx, yy = curve.Add(pkx, pky, nx, ny)
+	}
+
+	problem := &dlog.Problem{Curve: curve, Gx: gx, Gy: gy, X: yx, Y: yy, A: a, B: b}
+
+	m, err := problem.Kangaroo(ctx, opts.Kangaroo, events)
+	if ctx.Err() != nil {
+		return nil, &congruence.PartialError{Congruences: congruences, Err: ctx.Err()}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the kangaroos in [0, %d]: %w", b, err)
+	}
+
+	// x = n + m*r
+	x := m.Mul(m, r).Add(m, n)
+	x.Mod(x, order)
+
+	if xx, xy := curve.ScalarBaseMult(x.Bytes()); xx.Cmp(pkx) != 0 || xy.Cmp(pky) != 0 {
+		return nil, errors.New("the public key isn't a multiple of the base point")
+	}
+
+	return x, nil
+}
@@ -0,0 +1,107 @@
+package challenge59
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/congruence"
+	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	oracle2 "github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
+)
+
+func TestInvalidCurveKangarooAttack(t *testing.T) {
+	p128 := elliptic.P128()
+
+	// the residues modulo the factors below 2^16 give the whole key, below
+	// 2^14 all but 9 bits of it and below 13500 all but 23 bits
+	prev := -1
+	for _, bound := range []int64{1 << 16, 1 << 14, 13500} {
+		bob := oracle2.NewECDHAttackOracle(p128, elliptic.NoValidation, helpers.NewSeededReader(bound))
+		meter := oracle2.NewMeter(0)
+
+		privateKey, err := InvalidCurveKangarooAttack(context.Background(), oracle2.NewMeteredECDHOracle(bob, meter), bob, p128, nil,
+			big.NewInt(bound), &attack.Options{Rand: helpers.NewSeededReader(50)})
+		if err != nil {
+			t.Fatalf("%s: bound %d: %s", t.Name(), bound, err.Error())
+		}
+		t.Logf("%s: bound %d: %s", t.Name(), bound, meter.Summary())
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: bound %d: computed key isn't equal to Bob's private key", t.Name(), bound)
+		}
+
+		n := meter.Summary().Queries
+		if prev >= 0 && n >= prev {
+			t.Errorf("%s: bound %d: %d queries, want fewer than %d", t.Name(), bound, n, prev)
+		}
+		prev = n
+	}
+}
+
+func TestInvalidCurveKangarooAttackOnGeneratedCurves(t *testing.T) {
+	p48 := elliptic.P48()
+
+	invalidCurves, err := elliptic.GenerateInvalidCurves(p48, big.NewInt(1<<16), 100)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	bob := oracle2.NewECDHAttackOracle(p48, elliptic.NoValidation, helpers.NewSeededReader(48))
+
+	// the kangaroos find the last 8 and 17 bits of the key
+	for _, bound := range []int64{1 << 10, 1 << 8} {
+		privateKey, err := InvalidCurveKangarooAttack(context.Background(), bob, bob, p48, invalidCurves, big.NewInt(bound), nil)
+		if err != nil {
+			t.Fatalf("%s: bound %d: %s", t.Name(), bound, err.Error())
+		}
+
+		if !bob.IsKeyCorrect(privateKey.Bytes()) {
+			t.Fatalf("%s: bound %d: computed key isn't equal to Bob's private key", t.Name(), bound)
+		}
+	}
+}
+
+func TestInvalidCurveKangarooAttackErrors(t *testing.T) {
+	p128 := elliptic.P128()
+	bob := oracle2.NewECDHAttackOracle(p128, elliptic.NoValidation, nil)
+	meter := oracle2.NewMeter(0)
+
+	// the factors below 2^8 leave more than 64 bits to the kangaroos, so Bob
+	// isn't asked anything
+	_, err := InvalidCurveKangarooAttack(context.Background(), oracle2.NewMeteredECDHOracle(bob, meter), bob, p128, nil, big.NewInt(1<<8), nil)
+	if err == nil {
+		t.Fatalf("%s: no error for the factor bound 2^8", t.Name())
+	}
+	if n := meter.Summary().Queries; n != 0 {
+		t.Errorf("%s: %d queries, want 0", t.Name(), n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the attack is canceled once every residue is recovered, while the
+	// kangaroos jump
+	events := progress.ReporterFunc(func(e progress.Event) {
+		if e.Kind == progress.KangarooJumps {
+			cancel()
+		}
+	})
+
+	_, err = InvalidCurveKangarooAttack(ctx, bob, bob, p128, nil, big.NewInt(13000), &attack.Options{Events: events})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+
+	var partial *congruence.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("%s: got error %T, want *congruence.PartialError", t.Name(), err)
+	}
+	if partial.Congruences.Len() == 0 {
+		t.Fatalf("%s: no congruences", t.Name())
+	}
+}
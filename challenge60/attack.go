@@ -2,20 +2,27 @@ package challenge60
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/rand"
 	"fmt"
-	"math"
+	"io"
 	"math/big"
-	"runtime"
 	"sync"
 
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/checkpoint"
+	"github.com/svkirillov/cryptopals-go/congruence"
+	"github.com/svkirillov/cryptopals-go/dlog"
 	"github.com/svkirillov/cryptopals-go/elliptic"
 	"github.com/svkirillov/cryptopals-go/helpers"
 	"github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
+	"github.com/svkirillov/cryptopals-go/search"
 	"github.com/svkirillov/cryptopals-go/x128"
 )
 
+// maxAttempts is the number of times a point is sent to Bob before giving
+// up, if his answers are lost or corrupted.
+const maxAttempts = 8
+
 type twistPoint struct {
 	order *big.Int
 	point *big.Int
@@ -65,14 +72,19 @@ func convertToWeierstrass(u *big.Int) (x, y *big.Int, err error) {
 	return nil, nil, fmt.Errorf("(%d, %d) is not a point on p128 curve", x, y)
 }
 
-// findTwistPoint returns a point on the twist with given order.
-func findTwistPoint(twistOrder, order *big.Int) *big.Int {
+// findTwistPoint returns a point on the twist with given order, or nil once
+// ctx is done.
+func findTwistPoint(ctx context.Context, twistOrder, order *big.Int, rng io.Reader) *big.Int {
 	k := new(big.Int).Div(twistOrder, order).Bytes()
 
 	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
 		// a. Choose a random u mod p and verify that u^3 + A*u^2 + u is a
 		//    nonsquare in GF(p).
-		u, err := rand.Int(rand.Reader, x128.P)
+		u, err := helpers.GenerateBigInt(rng, x128.P)
 		if err != nil {
 			panic(err)
 		}
@@ -89,8 +101,9 @@ func findTwistPoint(twistOrder, order *big.Int) *big.Int {
 	}
 }
 
-// findAllTwistPoints finds all points on twist curves.
-func findAllTwistPoints() (twistOrder *big.Int, points []twistPoint) {
+// findAllTwistPoints finds all points on twist curves. It stops with the
+// points found so far once ctx is done.
+func findAllTwistPoints(ctx context.Context, rng io.Reader) (twistOrder *big.Int, points []twistPoint) {
 	// 1. Calculate the order of the twist and find its small factors. This
 	//    one should have a bunch under 2^24.
 	// It is known, that both curves contain 2*p+2 points: |E| + |T| = 2*p + 2
@@ -106,7 +119,10 @@ func findAllTwistPoints() (twistOrder *big.Int, points []twistPoint) {
 
 	// 2. Find points with those orders.
 	for _, order := range factors {
-		u := findTwistPoint(twistOrder, order)
+		u := findTwistPoint(ctx, twistOrder, order, rng)
+		if u == nil {
+			return
+		}
 		points = append(points, twistPoint{
 			order: order,
 			point: u,
@@ -116,116 +132,10 @@ func findAllTwistPoints() (twistOrder *big.Int, points []twistPoint) {
 	return
 }
 
-// f maps group elements to scalars.
-// See tasks/challenge58.txt:24 and tasks/challenge58.txt:94 for details.
-func f(y, k, p *big.Int) *big.Int {
-	// f = 2^(y mod k) mod p
-	return new(big.Int).Exp(helpers.BigTwo, new(big.Int).Mod(y, k), p)
-}
-
-// calcK calculates k based on a formula in this paper: https://arxiv.org/pdf/0812.0789.pdf
-func calcK(a, b *big.Int) *big.Int {
-	// k = log2(sqrt(b-a)) + log2(log2(sqrt(b-a))) - 2
-	sqrtba := math.Sqrt(float64(new(big.Int).Sub(b, a).Uint64()))
-	logSqrt := math.Log2(sqrtba)
-	logLogSqrt := math.Log2(logSqrt)
-	return new(big.Int).SetUint64(uint64(logSqrt + logLogSqrt - 2))
-}
-
-// calcN calculates amount of leaps for tame kangaroo.
-func calcN(p, k *big.Int) *big.Int {
-	N := new(big.Int).Set(helpers.BigZero)
-
-	for i := new(big.Int).Set(helpers.BigZero); i.Cmp(k) < 0; i.Add(i, helpers.BigOne) {
-		N.Add(N, f(i, k, p))
-	}
-
-	// N = N/k * 4
-	// see for details: tasks/challenge58.txt:99
-	tmp := new(big.Int).Rsh(k, 2)
-	if tmp.Cmp(helpers.BigZero) == 0 {
-		N.Div(N, k).Mul(N, tmp.SetUint64(4))
-	} else {
-		N.Div(N, tmp)
-	}
-
-	return N
-}
-
-// tameKangaroo returns distance traveled by tame kangaroo and where he
-// ended up.
-func tameKangaroo(curve elliptic.Curve, bx, by, b, k, N *big.Int) (xT *big.Int, xyT *big.Int, yyT *big.Int) {
-	curveN := curve.Params().N
-
-	// xT := 0
-	// xyT, yyT := b * base
-	xT = new(big.Int).Set(helpers.BigZero)
-	xyT, yyT = curve.ScalarMult(bx, by, b.Bytes())
-
-	// for i in 1..N:
-	for i := new(big.Int).Set(helpers.BigZero); i.Cmp(N) < 0; i.Add(i, helpers.BigOne) {
-		fVal := f(xyT, k, curveN)
-
-		// xT := xT + f(xyT)
-		xT.Add(xT, fVal)
-
-		// xyT, yyT := (xyT, yyT) + (base * f(xyT))
-		tmpX, tmpY := curve.ScalarMult(bx, by, fVal.Bytes())
-		xyT, yyT = curve.Add(xyT, yyT, tmpX, tmpY)
-	}
-
-	return
-}
-
-// catchingWildKangaroo implements Pollard's method for catching kangaroos.
-func catchingWildKangaroo(ctx context.Context, curve elliptic.Curve, bx, by, x, y, xT, xyT, yyT, k, a, b *big.Int) *big.Int {
-	curveN := curve.Params().N
-
-	// xW := 0
-	// xyW, yyW := x, y
-	xW := new(big.Int).Set(helpers.BigZero)
-	xyW := new(big.Int).Set(x)
-	yyW := new(big.Int).Set(y)
-
-	tmp := new(big.Int)
-
-	tmp.Sub(b, a).Add(tmp, xT)
-	xWUpperBound := new(big.Int).Set(tmp) // xWUpperBound := b - a + xT
-
-	// while xW < b - a + xT:
-	for xW.Cmp(xWUpperBound) < 0 {
-		fVal := f(xyW, k, curveN)
-
-		// xW := xW + f(xyW)
-		xW.Add(xW, fVal)
-
-		// xyW, yyW := (xyW, yyW) + (base * f(xyW))
-		tmpX, tmpY := curve.ScalarMult(bx, by, fVal.Bytes())
-		xyW, yyW = curve.Add(xyW, yyW, tmpX, tmpY)
-
-		// if yW = yT:
-		if xyW.Cmp(xyT) == 0 && yyW.Cmp(yyT) == 0 {
-			// b + xT - xW
-			tmp.Add(b, xT).Sub(tmp, xW)
-			fmt.Printf("Wild Kangaroo: xW = %d, xyW = %d, yyW = %d, (b + xT - xW) = %d\n", xW, xyW, yyW, tmp)
-			return tmp
-		}
-
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-			// pass
-		}
-	}
-
-	return nil
-}
-
-// ecdh performs DH on x128 curve with given public and private keys
+// ecdh returns the shared secret on x128 curve with given public and private keys
 func ecdh(publicKey *big.Int, privateKey []byte) []byte {
 	ss := x128.ScalarMult(publicKey, privateKey)
-	return oracle.MAC(ss.Bytes())
+	return ss.Bytes()
 }
 
 // checkDuplicate returns true if no duplicates were found
@@ -247,118 +157,157 @@ func checkDuplicate(reminders []*big.Int, modules []*big.Int, r *big.Int, m *big
 }
 
 // getRemaindersOfPrivateKey returns a set of equations of the form b = k mod p where
-// b is privateKey, k is remainder of private key by modulo p
+// b is privateKey, k is remainder of private key by modulo p. It stops with
+// the equations found so far once ctx is done.
 func getRemaindersOfPrivateKey(
-	oracleECDH func(x *big.Int) []byte,
+	ctx context.Context,
+	oracleECDH oracle.X128Oracle,
 	points []twistPoint,
-) (remainders []*big.Int, modules []*big.Int) {
-	bruteFunc := func(
-		ctx context.Context,
-		ss []byte,
-		point, order *big.Int,
-		bruteFrom, bruteTo *big.Int,
-		answer chan equation,
-		wg *sync.WaitGroup,
-	) {
-		defer wg.Done()
-		for k := bruteFrom; k.Cmp(bruteTo) < 0; k.Add(k, helpers.BigOne) {
-			ss1 := ecdh(point, k.Bytes())
-			if hmac.Equal(ss, ss1) {
-				answer <- equation{
-					reminder: k,
-					module:   order,
-				}
-				return
-			}
+	events progress.Reporter,
+) (remainders []*big.Int, modules []*big.Int, err error) {
+	state := &checkpoint.State{}
+	err = resumeRemaindersOfPrivateKey(ctx, oracleECDH, points, state, checkpoint.None, events)
+	return state.Congruences.Remainders, state.Congruences.Modules, err
+}
 
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				// pass
-			}
+// resumeRemaindersOfPrivateKey adds the remainders modulo the orders of the
+// points to the congruences of state, skipping the orders which are already
+// there, and saves state to store after each one.
+func resumeRemaindersOfPrivateKey(
+	ctx context.Context,
+	oracleECDH oracle.X128Oracle,
+	points []twistPoint,
+	state *checkpoint.State,
+	store checkpoint.Store,
+	events progress.Reporter,
+) error {
+	for _, point := range points {
+		if state.HasModulus(point.order) {
+			continue
 		}
-	}
 
-	var wg sync.WaitGroup
+		events.Report(progress.Event{Kind: progress.SubgroupFound, Modulus: point.order})
 
-	nWorkers := runtime.NumCPU()
-	fmt.Println("Number of used threads =", nWorkers)
-
-	step := new(big.Int)
-	tailFrom := new(big.Int)
-	border := new(big.Int)
+		e, err := getRemainder(ctx, oracleECDH, point)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
 
-	for _, point := range points {
-		answer := make(chan equation, nWorkers)
+		if e != nil && checkDuplicate(state.Congruences.Remainders, state.Congruences.Modules, e.reminder, e.module) {
+			state.Congruences.Add(e.reminder, e.module)
 
-		// step := point.order / (nWorkers - 1)
-		step.SetUint64(uint64(nWorkers-1)).Div(point.order, step)
+			// the candidates are modulo the old product
+			state.Candidates = nil
+			if err := store.Save(state); err != nil {
+				return err
+			}
 
-		// tailFrom := step * (nWorkers - 1)
-		tailFrom.SetUint64(uint64(nWorkers-1)).Mul(tailFrom, step)
+			// the remainder is only known up to the sign, see matchCandidates
+			product := state.Congruences.Modulus()
+			events.Report(progress.Event{Kind: progress.ResidueRecovered, Residue: e.reminder, Modulus: e.module})
+			events.Report(progress.Event{Kind: progress.ModulusGrown, Modulus: product, Bits: product.BitLen()})
+		}
+	}
 
-		ss := oracleECDH(point.point)
+	return nil
+}
 
-		ctx, cancel := context.WithCancel(context.Background())
+// getRemainder returns the remainder of the private key modulo the order of
+// the point, or nil if Bob rejects the point. The query is repeated if the
+// answer is lost or doesn't match any remainder. It stops with ctx.Err()
+// once ctx is done.
+func getRemainder(ctx context.Context, oracleECDH oracle.X128Oracle, point twistPoint) (*equation, error) {
+	checker := oracle.CheckerOf(oracleECDH)
 
-		border.Set(helpers.BigZero)
-		for ; border.Cmp(tailFrom) < 0; border.Add(border, step) {
-			wg.Add(1)
-			go bruteFunc(
-				ctx, ss,
-				point.point, point.order,
-				new(big.Int).Set(border),
-				new(big.Int).Add(border, step),
-				answer,
-				&wg,
-			)
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
-		wg.Add(1)
-		go bruteFunc(
-			ctx, ss,
-			point.point, point.order,
-			new(big.Int).Set(tailFrom),
-			new(big.Int).Add(point.order, helpers.BigOne),
-			answer,
-			&wg,
-		)
-
-		select {
-		case e := <-answer:
-			if checkDuplicate(remainders, modules, e.reminder, e.module) {
-				remainders = append(remainders, e.reminder)
-				modules = append(modules, e.module)
 
-				cancel()
-				break
-			}
+		ss, err := oracleECDH.X128DH(point.point)
+		if oracle.IsTransient(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("oracle: %w", err)
+		}
+		if ss == nil {
+			return nil, nil
 		}
 
-		cancel()
-
-		wg.Wait()
-		close(answer)
+		// k and order-k give the same u-coordinate, the smaller one is taken
+		k, err := search.Smallest(ctx, helpers.BigZero, new(big.Int).Add(point.order, helpers.BigOne), 0, func(k *big.Int) bool {
+			return checker.CheckSecret(ecdh(point.point, k.Bytes()), ss)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if k != nil {
+			return &equation{reminder: k, module: point.order}, nil
+		}
 	}
 
-	return
+	return nil, fmt.Errorf("no remainder modulo %d after %d attempts", point.order, maxAttempts)
 }
 
 // getCandidatesForPrivateKey returns a set of possible private keys by modulo r
 func getCandidatesForPrivateKey(
-	oracleECDH func(x *big.Int) []byte,
+	ctx context.Context,
+	oracleECDH oracle.X128Oracle,
 	twistOrder *big.Int,
 	remainders []*big.Int,
 	modules []*big.Int,
+	rng io.Reader,
+	events progress.Reporter,
 ) (candidates []*big.Int, r *big.Int, err error) {
 	r = new(big.Int).Set(helpers.BigOne)
 	for _, module := range modules {
 		r.Mul(r, module)
 	}
 
-	g := findTwistPoint(twistOrder, r)
-	ss := oracleECDH(g)
+	g := findTwistPoint(ctx, twistOrder, r, rng)
+	if g == nil {
+		return nil, nil, ctx.Err()
+	}
 
+	// a lost or corrupted answer gives no candidates, so the query is repeated
+	for attempt := 0; attempt < maxAttempts && len(candidates) == 0; attempt++ {
+		ss, err := oracleECDH.X128DH(g)
+		if oracle.IsTransient(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("oracle: %w", err)
+		}
+
+		candidates, err = matchCandidates(oracle.CheckerOf(oracleECDH), ss, g, remainders, modules)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("no candidates for the private key after %d attempts", maxAttempts)
+	}
+
+	for _, candidate := range candidates {
+		events.Report(progress.Event{Kind: progress.CandidateFound, Candidate: candidate, Modulus: r})
+	}
+
+	return candidates, r, nil
+}
+
+// matchCandidates returns the private keys modulo the product of the
+// modules, for which the MAC of g is ss.
+func matchCandidates(
+	checker oracle.SecretChecker,
+	ss []byte,
+	g *big.Int,
+	remainders, modules []*big.Int,
+) (candidates []*big.Int, err error) {
 	tmpReminders := make([]*big.Int, len(remainders))
 	l := len(modules)
 	pow := 1 << l
@@ -375,11 +324,10 @@ next:
 
 		possibleKey, _, err := helpers.ChineseRemainderTheorem(tmpReminders, modules)
 		if err != nil {
-			return nil, nil, fmt.Errorf("chinese remainder theorem: %s", err.Error())
+			return nil, fmt.Errorf("chinese remainder theorem: %s", err.Error())
 		}
 
-		ss1 := ecdh(g, possibleKey.Bytes())
-		if hmac.Equal(ss, ss1) {
+		if checker.CheckSecret(ecdh(g, possibleKey.Bytes()), ss) {
 			for _, candidate := range candidates {
 				if possibleKey.Cmp(candidate) == 0 {
 					continue next
@@ -390,33 +338,102 @@ next:
 		}
 	}
 
-	return candidates, r, nil
+	return candidates, nil
 }
 
+// InsecureTwistsAttack recovers the private key using points of small order
+// on the twist of x128. The recovered remainders, the candidates and the
+// trap of the tame kangaroo are saved to opts.Store, and the attack resumes
+// from the state saved there. The kangaroos jump according to
+// opts.Kangaroo. It stops once ctx is done, and what is known so far is
+// returned in a *congruence.PartialError: the remainders of the private
+// key, which are only known up to the sign, and the candidates for the
+// private key modulo their product, once they are found. opts may be nil.
 func InsecureTwistsAttack(
-	oracleECDH func(x *big.Int) []byte,
-	getPublicKey func() *big.Int,
-	privateKeyOracle func(*big.Int) *big.Int,
+	ctx context.Context,
+	oracleECDH oracle.X128Oracle,
+	publicKey oracle.PublicKeySource,
+	privateKeyOracle oracle.LeakOracle,
+	opts *attack.Options,
 ) (privateKey *big.Int, err error) {
-	twistOrder, points := findAllTwistPoints()
-	remainders, modules := getRemaindersOfPrivateKey(oracleECDH, points)
-	candidates, r, err := getCandidatesForPrivateKey(oracleECDH, twistOrder, remainders, modules)
+	opts = attack.OrDefault(opts)
+	events, cfg, store, rng := opts.Events, opts.Kangaroo, opts.Store, opts.Rand
+
+	partial := &congruence.PartialError{}
+	stopped := func() error {
+		partial.Err = ctx.Err()
+		return partial
+	}
+
+	twistOrder, points := findAllTwistPoints(ctx, rng)
+	if ctx.Err() != nil {
+		return nil, stopped()
+	}
+
+	state, err := store.Load()
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Println("remainders:", remainders)
-	fmt.Println("modules:", modules)
-	fmt.Println("Candidates for private key:", candidates)
-	fmt.Println("r =", r)
+	var orders []*big.Int
+	for _, point := range points {
+		orders = append(orders, point.order)
+	}
+	if err := state.CheckModules(orders); err != nil {
+		return nil, err
+	}
+	if state.Congruences.Len() > 0 {
+		events.Report(progress.Event{
+			Kind:    progress.Note,
+			Message: fmt.Sprintf("resumed with %d remainders from the checkpoint", state.Congruences.Len()),
+		})
+	}
+
+	err = resumeRemaindersOfPrivateKey(ctx, oracleECDH, points, state, store, events)
+	partial.Congruences = state.Congruences
+	if ctx.Err() != nil {
+		return nil, stopped()
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	realPrivateKey := privateKeyOracle(r)
-	fmt.Printf("Real private key x = n mod r: x %% %d = %d\n", r, realPrivateKey)
+	candidates, r := state.Candidates, state.Congruences.Modulus()
+	if len(candidates) == 0 {
+		candidates, r, err = getCandidatesForPrivateKey(ctx, oracleECDH, twistOrder,
+			state.Congruences.Remainders, state.Congruences.Modules, rng, events)
+		if ctx.Err() != nil {
+			return nil, stopped()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		state.Candidates = candidates
+		if err := store.Save(state); err != nil {
+			return nil, err
+		}
+	} else {
+		events.Report(progress.Event{
+			Kind:    progress.Note,
+			Message: fmt.Sprintf("resumed with %d candidates from the checkpoint", len(candidates)),
+		})
+	}
+	partial.Candidates = candidates
+
+	realPrivateKey := privateKeyOracle.PrivateKeyMod(r)
+	events.Report(progress.Event{
+		Kind:    progress.Note,
+		Message: fmt.Sprintf("Bob's private key is %d modulo %d", realPrivateKey, r),
+	})
 
 	p128 := elliptic.P128()
 
 	// convert public key from montgomery form to weierstrass
-	x128PublicKey := getPublicKey()
+	x128PublicKey, err := publicKey.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
 	pkP128x, pkP128y, err := convertToWeierstrass(x128PublicKey)
 	if err != nil {
 		return nil, fmt.Errorf("convert montgomery public key point to weierstrass form: %s", err.Error())
@@ -430,59 +447,118 @@ func InsecureTwistsAttack(
 	b := new(big.Int).Sub(p128.Params().N, helpers.BigOne)
 	b.Div(b, r)
 
-	// calculate k and n parameters for catching wild kangaroo attack
-	k := calcK(a, b)
-	N := calcN(p128.Params().N, k)
+	problem := &dlog.Problem{Curve: p128, Gx: newBaseX, Gy: newBaseY, A: a, B: b}
+
+	for attempt := 0; attempt < cfg.MaxAttempts(); attempt++ {
+		params, err := cfg.Params(a, b, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		events.Report(progress.Event{
+			Kind:    progress.Note,
+			Message: fmt.Sprintf("the kangaroos jump %s in [0, %d], N = %d", params.Jumps, b, params.N),
+		})
+
+		// the trap is only reused if it's made with the same base, interval
+		// and jumps
+		var trap *dlog.Trap
+		if saved := state.Trap; saved != nil && saved.Y != nil && saved.Jumps == params.Jumps.String() &&
+			isTrap(p128, newBaseX, newBaseY, b, saved) {
+			trap = &dlog.Trap{Distance: saved.Distance, X: saved.X, Y: saved.Y, Jumps: params.Jumps}
+			events.Report(progress.Event{Kind: progress.Note, Message: "resumed with the tame kangaroo's trap from the checkpoint"})
+		} else {
+			// run tame kangaroo
+			if trap = problem.Tame(ctx, params, events); trap == nil {
+				return nil, stopped()
+			}
+
+			state.Trap = &checkpoint.Trap{Distance: trap.Distance, X: trap.X, Y: trap.Y, Jumps: params.Jumps.String()}
+			if err := store.Save(state); err != nil {
+				return nil, err
+			}
+		}
+
+		m, n := catchWildKangaroos(ctx, problem, pkP128x, pkP128y, trap, candidates, events)
+		if ctx.Err() != nil {
+			return nil, stopped()
+		}
+		if m != nil {
+			// x = n + m*r
+			return m.Mul(m, r).Add(m, n), nil
+		}
+	}
+
+	// a wild kangaroo may escape even for the right candidate, but not
+	// every time
+	for _, candidate := range candidates {
+		events.Report(progress.Event{Kind: progress.CandidateEliminated, Candidate: candidate, Modulus: r})
+	}
 
-	fmt.Println("k =", k)
-	fmt.Println("N =", N)
+	return nil, fmt.Errorf("the wild kangaroos of %d candidates escaped %d times", len(candidates), cfg.MaxAttempts())
+}
 
-	// run tame kangaroo
-	xT, xyT, yyT := tameKangaroo(p128, newBaseX, newBaseY, b, k, N)
-	fmt.Printf("Tame Kangaroo: xT = %d, xyT = %d, yyT = %d\n", xT, xyT, yyT)
+// catchWildKangaroos runs a wild kangaroo for every candidate n at once and
+// returns the logarithm m of the public key (pkx, pky) minus n*G in the base
+// of the problem with its candidate, or nils if every wild kangaroo escapes.
+func catchWildKangaroos(
+	ctx context.Context,
+	problem *dlog.Problem,
+	pkx, pky *big.Int,
+	trap *dlog.Trap,
+	candidates []*big.Int,
+	events progress.Reporter,
+) (m, n *big.Int) {
+	type result struct {
+		m, n *big.Int
+	}
 
-	ch := make(chan *big.Int, len(candidates))
+	ch := make(chan result, len(candidates))
 
 	var wg sync.WaitGroup
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// check candidates
+	curve := problem.Curve
+
 	for _, candidate := range candidates {
 		wg.Add(1)
 		go func(n *big.Int) {
 			defer wg.Done()
 
 			// y' = y * g^-n
-			newX, newY := p128.ScalarBaseMult(n.Bytes())
-			newX, newY = elliptic.Inverse(p128, newX, newY)
-			newX, newY = p128.Add(newX, newY, pkP128x, pkP128y)
-
-			m := catchingWildKangaroo(ctx, p128, newBaseX, newBaseY, newX, newY, xT, xyT, yyT, k, a, b)
-			if m == nil {
-				ch <- nil
-				return
+			newX, newY := curve.ScalarBaseMult(n.Bytes())
+			newX, newY = elliptic.Inverse(curve, newX, newY)
+			newX, newY = curve.Add(newX, newY, pkx, pky)
+
+			wild := *problem
+			wild.X, wild.Y = newX, newY
+
+			// the jumps are reported with the candidate of the kangaroo
+			m := wild.Wild(ctx, trap, progress.ReporterFunc(func(e progress.Event) {
+				e.Candidate = n
+				events.Report(e)
+			}))
+			if m != nil {
+				cancel()
+				ch <- result{m: m, n: n}
 			}
-
-			cancel()
-
-			x := new(big.Int).Mul(m, r)
-			x.Add(x, n)
-
-			ch <- x
 		}(candidate)
 	}
 
 	wg.Wait()
+	close(ch)
 
-	for len(ch) > 0 {
-		if privateKey = <-ch; privateKey != nil {
-			close(ch)
-			return privateKey, nil
-		}
+	if res, ok := <-ch; ok {
+		return res.m, res.n
 	}
 
-	close(ch)
-
 	return nil, nil
 }
+
+// isTrap reports whether the tame kangaroo started at b*(bx, by) ends up in
+// trap.
+func isTrap(curve elliptic.Curve, bx, by, b *big.Int, trap *checkpoint.Trap) bool {
+	x, y := curve.ScalarMult(bx, by, new(big.Int).Add(b, trap.Distance).Bytes())
+	return x.Cmp(trap.X) == 0 && y.Cmp(trap.Y) == 0
+}
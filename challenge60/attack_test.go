@@ -1,16 +1,33 @@
 package challenge60
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"flag"
 	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/checkpoint"
+	"github.com/svkirillov/cryptopals-go/congruence"
+	"github.com/svkirillov/cryptopals-go/dlog"
 	"github.com/svkirillov/cryptopals-go/elliptic"
 	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/kangaroo"
 	oracle2 "github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
 	"github.com/svkirillov/cryptopals-go/x128"
 )
 
+var (
+	seed       = flag.Int64("seed", 0, "seed of the insecure twist attack, random if 0")
+	transcript = flag.String("transcript", "", "record the queries of the insecure twist attack to the file")
+	replay     = flag.String("replay", "", "answer the queries of the insecure twist attack from the transcript")
+)
+
 func TestECKangarooAlgorithm(t *testing.T) {
 	ecKangarooTests := []struct {
 		k, b string
@@ -30,11 +47,9 @@ func TestECKangarooAlgorithm(t *testing.T) {
 		b, _ := new(big.Int).SetString(e.b, 10)
 
 		x, y := curve.ScalarBaseMult(k.Bytes())
-		K := calcK(a, b)
-		N := calcN(curve.Params().N, K)
-		xT, xyT, yyT := tameKangaroo(curve, bx, by, b, K, N)
-		kk := catchingWildKangaroo(context.Background(), curve, bx, by, x, y, xT, xyT, yyT, K, a, b)
-		if kk == nil || kk.Cmp(k) != 0 {
+		problem := &dlog.Problem{Curve: curve, Gx: bx, Gy: by, X: x, Y: y, A: a, B: b}
+		kk, err := problem.Kangaroo(context.Background(), &kangaroo.Config{Attempts: 1}, nil)
+		if err != nil || kk.Cmp(k) != 0 {
 			t.Fatal("Pollard's method for catching kangaroos on elliptic curves fails")
 		}
 	}
@@ -48,16 +63,238 @@ func TestInsecureTwistAttack(t *testing.T) {
 		t.Fatalf("%s: the point is not on the x128 curve", t.Name())
 	}
 
-	oracle, isKeyCorrect, getPublic, privateKeyOracle := oracle2.NewX128TwistAttackOracle()
+	s := *seed
+	if s == 0 {
+		var err error
+		if s, err = helpers.NewRandomSeed(); err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+	}
+	t.Logf("%s: seed: %d", t.Name(), s)
+
+	// a failed run can be replayed with -seed and -replay, if it was
+	// recorded with -transcript
+	bob := oracle2.NewX128TwistAttackOracle(helpers.NewSeededReader(s))
+
+	var xOracle oracle2.X128Oracle = bob
+	var publicKey oracle2.PublicKeySource = bob
+	var leakOracle oracle2.LeakOracle = bob
+
+	switch {
+	case *replay != "":
+		f, err := os.Open(*replay)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+		defer f.Close()
+
+		r, err := oracle2.NewReplayOracle(f)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+		xOracle, publicKey, leakOracle = r, r, r
 
-	privateKey, err := InsecureTwistsAttack(oracle, getPublic, privateKeyOracle)
+	case *transcript != "":
+		f, err := os.Create(*transcript)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+		defer f.Close()
+
+		recorder := oracle2.NewRecorder(f)
+		defer func() {
+			if err := recorder.Err(); err != nil {
+				t.Errorf("%s: transcript: %s", t.Name(), err.Error())
+			}
+		}()
+
+		xOracle = oracle2.NewRecordingX128Oracle(xOracle, recorder)
+		publicKey = oracle2.NewRecordingPublicKeySource(publicKey, recorder)
+		leakOracle = oracle2.NewRecordingLeakOracle(leakOracle, recorder)
+	}
+
+	meter := oracle2.NewMeter(0)
+
+	privateKey, err := InsecureTwistsAttack(
+		context.Background(),
+		oracle2.NewMeteredX128Oracle(xOracle, meter),
+		publicKey,
+		leakOracle,
+		&attack.Options{
+			Rand: helpers.NewSeededReader(s + 1),
+			Events: progress.ReporterFunc(func(e progress.Event) {
+				t.Logf("%s: %s", t.Name(), e)
+			}),
+		},
+	)
 	if err != nil {
 		t.Fatalf("%s: %s\n", t.Name(), err.Error())
 	}
+	t.Logf("%s: %s", t.Name(), meter.Summary())
+
+	// one query per small odd factor of the twist order and one more to
+	// tell the candidates apart
+	twistOrder, points := findAllTwistPoints(context.Background(), nil)
+	if n, max := meter.Summary().Queries, len(points)+1; n > max {
+		t.Errorf("%s: %d queries, want at most %d (twist order %d)", t.Name(), n, max, twistOrder)
+	}
 
-	if privateKey != nil && isKeyCorrect(privateKey.Bytes()) {
+	if privateKey != nil && bob.IsKeyCorrect(privateKey.Bytes()) {
 		t.Logf("%s: private key was found: %d", t.Name(), privateKey)
 	} else {
-		t.Fatalf("%s: wrong private key was found in the insecure twist attack", t.Name())
+		t.Fatalf("%s: wrong private key was found in the insecure twist attack (seed %d)", t.Name(), s)
+	}
+}
+
+func TestRemaindersReplay(t *testing.T) {
+	bob := oracle2.NewX128TwistAttackOracle(helpers.NewSeededReader(39))
+
+	smallPoints := func() []twistPoint {
+		_, allPoints := findAllTwistPoints(context.Background(), helpers.NewSeededReader(40))
+
+		var points []twistPoint
+		for _, p := range allPoints {
+			if p.order.Cmp(big.NewInt(1<<16)) < 0 {
+				points = append(points, p)
+			}
+		}
+		return points
+	}
+
+	var buf bytes.Buffer
+	recorder := oracle2.NewRecorder(&buf)
+
+	remainders, modules, err := getRemaindersOfPrivateKey(context.Background(), oracle2.NewRecordingX128Oracle(bob, recorder), smallPoints(), progress.Discard)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	// the same seed gives the same points, which are answered from the
+	// transcript without Bob
+	replay, err := oracle2.NewReplayOracle(&buf)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	replayedRemainders, replayedModules, err := getRemaindersOfPrivateKey(context.Background(), replay, smallPoints(), progress.Discard)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	if len(replayedModules) != len(modules) {
+		t.Fatalf("%s: %d remainders replayed, want %d", t.Name(), len(replayedModules), len(modules))
+	}
+	for i := range modules {
+		if replayedRemainders[i].Cmp(remainders[i]) != 0 || replayedModules[i].Cmp(modules[i]) != 0 {
+			t.Errorf("%s: got %d mod %d, want %d mod %d", t.Name(),
+				replayedRemainders[i], replayedModules[i], remainders[i], modules[i])
+		}
+	}
+}
+
+func TestInsecureTwistAttackContext(t *testing.T) {
+	bob := oracle2.NewX128TwistAttackOracle(helpers.NewSeededReader(43))
+
+	// the attack is canceled while it looks for the third remainder
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queries := 0
+	canceling := oracle2.X128OracleFunc(func(u *big.Int) ([]byte, error) {
+		if queries++; queries == 3 {
+			cancel()
+		}
+		return bob.X128DH(u)
+	})
+
+	_, err := InsecureTwistsAttack(ctx, canceling, bob, bob, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+
+	var partial *congruence.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("%s: got error %T, want *congruence.PartialError", t.Name(), err)
+	}
+	if partial.Congruences.Len() != 2 || len(partial.Candidates) != 0 {
+		t.Fatalf("%s: got %d congruences and %d candidates, want 2 and none",
+			t.Name(), partial.Congruences.Len(), len(partial.Candidates))
+	}
+
+	// the remainders are only known up to the sign
+	for i, m := range partial.Congruences.Modules {
+		r := partial.Congruences.Remainders[i]
+		x := bob.PrivateKeyMod(m)
+		if x.Cmp(r) != 0 && x.Cmp(new(big.Int).Sub(m, r)) != 0 {
+			t.Errorf("%s: wrong remainder %d modulo %d", t.Name(), r, m)
+		}
+	}
+}
+
+func TestRemaindersWithCheckpoint(t *testing.T) {
+	rng := helpers.NewSeededReader(41)
+
+	bob := oracle2.NewX128TwistAttackOracle(rng)
+
+	_, allPoints := findAllTwistPoints(context.Background(), rng)
+	var points []twistPoint
+	for _, p := range allPoints {
+		if p.order.Cmp(big.NewInt(1<<16)) < 0 {
+			points = append(points, p)
+		}
+	}
+
+	store := checkpoint.File(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	// the first run is canceled after the second remainder
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queries := 0
+	canceling := oracle2.X128OracleFunc(func(u *big.Int) ([]byte, error) {
+		if queries++; queries == 2 {
+			cancel()
+		}
+		return bob.X128DH(u)
+	})
+
+	state := &checkpoint.State{}
+	if err := resumeRemaindersOfPrivateKey(ctx, canceling, points, state, store, progress.Discard); !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+
+	// the second run only asks for the remainders which aren't saved
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	saved := state.Congruences.Len()
+
+	queries = 0
+	counting := oracle2.X128OracleFunc(func(u *big.Int) ([]byte, error) {
+		queries++
+		return bob.X128DH(u)
+	})
+
+	if err := resumeRemaindersOfPrivateKey(context.Background(), counting, points, state, store, progress.Discard); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if queries != len(points)-saved {
+		t.Errorf("%s: %d queries after resuming with %d of %d remainders", t.Name(), queries, saved, len(points))
+	}
+
+	state, err = store.Load()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if state.Congruences.Len() != len(points) {
+		t.Fatalf("%s: %d remainders saved, want %d", t.Name(), state.Congruences.Len(), len(points))
+	}
+	for i, m := range state.Congruences.Modules {
+		r := state.Congruences.Remainders[i]
+		x := bob.PrivateKeyMod(m)
+		if x.Cmp(r) != 0 && x.Cmp(new(big.Int).Sub(m, r)) != 0 {
+			t.Errorf("%s: wrong remainder %d modulo %d", t.Name(), r, m)
+		}
 	}
 }
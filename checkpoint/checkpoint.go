@@ -0,0 +1,338 @@
+// Package checkpoint saves the progress of an attack, so that an interrupted
+// attack can be resumed. A checkpoint is only meaningful for the attack and
+// the Bob it was made with.
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/svkirillov/cryptopals-go/congruence"
+	"github.com/svkirillov/cryptopals-go/oracle"
+)
+
+// Trap is where the tame kangaroo ended up after jumping Distance. X is the
+// group element for finite field DH, and (X, Y) is the point for elliptic
+// curves. Jumps identifies the jumps of the kangaroos, see kangaroo.Jumps.
+type Trap struct {
+	Distance *big.Int
+	X, Y     *big.Int
+	Jumps    string
+}
+
+// Origin is the run which saved a state: the attack, Bob's group or curve,
+// the seed of Bob's keys and the attack, and Bob's public key. The residues
+// of a key are no good against another key of the same group, which
+// CheckModules can't see.
+type Origin struct {
+	Attack string
+	Group  string
+
+	// Seed is 0 if it's unknown.
+	Seed int64
+
+	// PublicKey is Bob's public key, or the coordinates of his public point,
+	// nil if it's unknown.
+	PublicKey []*big.Int
+}
+
+// Check returns an error if the state saved by the run o can't be resumed
+// by the run other. The unknown seeds and public keys match anything.
+func (o *Origin) Check(other *Origin) error {
+	if o.Attack != other.Attack || o.Group != other.Group {
+		return fmt.Errorf("checkpoint: saved by %s on %s, not by %s on %s", o.Attack, o.Group, other.Attack, other.Group)
+	}
+	if o.Seed != 0 && other.Seed != 0 && o.Seed != other.Seed {
+		return fmt.Errorf("checkpoint: saved with seed %d, not %d", o.Seed, other.Seed)
+	}
+	if o.PublicKey != nil && other.PublicKey != nil && !equal(o.PublicKey, other.PublicKey) {
+		return fmt.Errorf("checkpoint: saved for another public key of Bob, %d, not %d", o.PublicKey, other.PublicKey)
+	}
+	return nil
+}
+
+func equal(a, b []*big.Int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Cmp(b[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// State is the progress of an attack.
+type State struct {
+	// Origin is the run which saved the state, nil if it's unknown.
+	Origin *Origin
+
+	// Congruences are the residues of the private key recovered so far.
+	Congruences congruence.System
+
+	// Candidates are the possible values of the private key modulo
+	// Congruences.Modulus(), if the residues don't fix it.
+	Candidates []*big.Int
+
+	// Trap is the last position of the tame kangaroo, nil if he hasn't
+	// finished yet.
+	Trap *Trap
+}
+
+// CheckModules returns an error if the state has congruences modulo numbers
+// which aren't among the given modules, i.e. it comes from another attack.
+func (s *State) CheckModules(modules []*big.Int) error {
+	allowed := make(map[string]bool, len(modules))
+	for _, m := range modules {
+		allowed[m.String()] = true
+	}
+
+	for _, m := range s.Congruences.Modules {
+		if !allowed[m.String()] {
+			return fmt.Errorf("checkpoint: unexpected modulus %d, the checkpoint is from another attack", m)
+		}
+	}
+
+	return nil
+}
+
+// empty reports whether nothing is learned yet.
+func (s *State) empty() bool {
+	return s.Congruences.Len() == 0 && len(s.Candidates) == 0 && s.Trap == nil
+}
+
+// HasModulus reports whether the private key modulo m is already known.
+func (s *State) HasModulus(m *big.Int) bool {
+	for _, module := range s.Congruences.Modules {
+		if module.Cmp(m) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type jsonTrap struct {
+	Distance oracle.BigInt  `json:"distance"`
+	X        oracle.BigInt  `json:"x"`
+	Y        *oracle.BigInt `json:"y,omitempty"`
+	Jumps    string         `json:"jumps,omitempty"`
+}
+
+type jsonOrigin struct {
+	Attack    string          `json:"attack"`
+	Group     string          `json:"group"`
+	Seed      int64           `json:"seed,omitempty"`
+	PublicKey []oracle.BigInt `json:"public_key,omitempty"`
+}
+
+type jsonState struct {
+	Origin     *jsonOrigin     `json:"origin,omitempty"`
+	Remainders []oracle.BigInt `json:"remainders"`
+	Modules    []oracle.BigInt `json:"modules"`
+	Candidates []oracle.BigInt `json:"candidates,omitempty"`
+	Trap       *jsonTrap       `json:"trap,omitempty"`
+}
+
+func toBigInts(ns []*big.Int) []oracle.BigInt {
+	res := make([]oracle.BigInt, len(ns))
+	for i, n := range ns {
+		res[i] = oracle.BigInt{Int: n}
+	}
+	return res
+}
+
+func fromBigInts(ns []oracle.BigInt) ([]*big.Int, error) {
+	var res []*big.Int
+	for _, n := range ns {
+		if n.Int == nil {
+			return nil, fmt.Errorf("checkpoint: null number")
+		}
+		res = append(res, n.Int)
+	}
+	return res, nil
+}
+
+func (s *State) MarshalJSON() ([]byte, error) {
+	js := jsonState{
+		Remainders: toBigInts(s.Congruences.Remainders),
+		Modules:    toBigInts(s.Congruences.Modules),
+		Candidates: toBigInts(s.Candidates),
+	}
+	if o := s.Origin; o != nil {
+		js.Origin = &jsonOrigin{Attack: o.Attack, Group: o.Group, Seed: o.Seed}
+		if o.PublicKey != nil {
+			js.Origin.PublicKey = toBigInts(o.PublicKey)
+		}
+	}
+	if s.Trap != nil {
+		js.Trap = &jsonTrap{
+			Distance: oracle.BigInt{Int: s.Trap.Distance},
+			X:        oracle.BigInt{Int: s.Trap.X},
+			Jumps:    s.Trap.Jumps,
+		}
+		if s.Trap.Y != nil {
+			js.Trap.Y = &oracle.BigInt{Int: s.Trap.Y}
+		}
+	}
+
+	return json.Marshal(js)
+}
+
+func (s *State) UnmarshalJSON(data []byte) error {
+	var js jsonState
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	if len(js.Remainders) != len(js.Modules) {
+		return fmt.Errorf("checkpoint: %d remainders for %d modules", len(js.Remainders), len(js.Modules))
+	}
+
+	var state State
+	var err error
+
+	if state.Congruences.Remainders, err = fromBigInts(js.Remainders); err != nil {
+		return err
+	}
+	if state.Congruences.Modules, err = fromBigInts(js.Modules); err != nil {
+		return err
+	}
+	if state.Candidates, err = fromBigInts(js.Candidates); err != nil {
+		return err
+	}
+	if o := js.Origin; o != nil {
+		state.Origin = &Origin{Attack: o.Attack, Group: o.Group, Seed: o.Seed}
+		if state.Origin.PublicKey, err = fromBigInts(o.PublicKey); err != nil {
+			return err
+		}
+	}
+	if js.Trap != nil {
+		if js.Trap.Distance.Int == nil || js.Trap.X.Int == nil {
+			return fmt.Errorf("checkpoint: incomplete trap")
+		}
+		state.Trap = &Trap{Distance: js.Trap.Distance.Int, X: js.Trap.X.Int, Jumps: js.Trap.Jumps}
+		if js.Trap.Y != nil {
+			state.Trap.Y = js.Trap.Y.Int
+		}
+	}
+
+	*s = state
+	return nil
+}
+
+// Store keeps the state of an attack.
+type Store interface {
+	// Load returns the saved state, or an empty state if nothing is saved.
+	Load() (*State, error)
+
+	// Save replaces the saved state.
+	Save(s *State) error
+}
+
+type none struct{}
+
+func (none) Load() (*State, error) { return &State{}, nil }
+func (none) Save(*State) error     { return nil }
+
+// None is a Store which saves nothing.
+var None Store = none{}
+
+// OrNone returns store, or None if store is nil.
+func OrNone(store Store) Store {
+	if store == nil {
+		return None
+	}
+	return store
+}
+
+type withOrigin struct {
+	store  Store
+	origin *Origin
+}
+
+// WithOrigin returns a Store which saves the state to store together with
+// the origin, and refuses to load a state saved by another run or by a run
+// it can't tell, unless nothing was learned yet. If origin is nil, store is
+// returned as is.
+func WithOrigin(store Store, origin *Origin) Store {
+	if origin == nil {
+		return store
+	}
+	return &withOrigin{store: OrNone(store), origin: origin}
+}
+
+func (w *withOrigin) Load() (*State, error) {
+	s, err := w.store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case s.Origin != nil:
+		if err := s.Origin.Check(w.origin); err != nil {
+			return nil, err
+		}
+	case !s.empty():
+		return nil, errors.New("checkpoint: the state isn't known to be saved by this attack on this Bob")
+	}
+
+	return s, nil
+}
+
+func (w *withOrigin) Save(s *State) error {
+	saved := *s
+	saved.Origin = w.origin
+	return w.store.Save(&saved)
+}
+
+// File is a Store which keeps the state as JSON in the named file. The file
+// is replaced atomically, so it's never left half written.
+type File string
+
+func (f File) Load() (*State, error) {
+	data, err := ioutil.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("checkpoint: %s: %w", f, err)
+	}
+
+	return &s, nil
+}
+
+func (f File) Save(s *State) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(string(f)), filepath.Base(string(f))+".*")
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), string(f)); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package checkpoint
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	store := File(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	// nothing is saved yet
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if state.Congruences.Len() != 0 || state.Candidates != nil || state.Trap != nil {
+		t.Fatalf("%s: got %+v from a missing file, want an empty state", t.Name(), state)
+	}
+
+	state.Congruences.Add(big.NewInt(2), big.NewInt(3))
+	state.Congruences.Add(big.NewInt(3), big.NewInt(5))
+	state.Candidates = []*big.Int{big.NewInt(8), big.NewInt(7)}
+	state.Trap = &Trap{Distance: big.NewInt(1000), X: big.NewInt(12345), Y: big.NewInt(67890), Jumps: "2^(y mod 20)"}
+
+	if err := store.Save(state); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	if loaded.Congruences.Len() != 2 || !loaded.HasModulus(big.NewInt(3)) || !loaded.HasModulus(big.NewInt(5)) {
+		t.Fatalf("%s: got congruences %+v, want %+v", t.Name(), loaded.Congruences, state.Congruences)
+	}
+	for i, r := range state.Congruences.Remainders {
+		if loaded.Congruences.Remainders[i].Cmp(r) != 0 {
+			t.Errorf("%s: got remainder %d, want %d", t.Name(), loaded.Congruences.Remainders[i], r)
+		}
+	}
+	if len(loaded.Candidates) != 2 || loaded.Candidates[0].Cmp(big.NewInt(8)) != 0 || loaded.Candidates[1].Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("%s: got candidates %d, want [8 7]", t.Name(), loaded.Candidates)
+	}
+	if trap := loaded.Trap; trap == nil || trap.Distance.Cmp(state.Trap.Distance) != 0 ||
+		trap.X.Cmp(state.Trap.X) != 0 || trap.Y == nil || trap.Y.Cmp(state.Trap.Y) != 0 || trap.Jumps != state.Trap.Jumps {
+		t.Errorf("%s: got trap %+v, want %+v", t.Name(), loaded.Trap, state.Trap)
+	}
+
+	if err := loaded.CheckModules([]*big.Int{big.NewInt(3), big.NewInt(5), big.NewInt(7)}); err != nil {
+		t.Errorf("%s: %s", t.Name(), err.Error())
+	}
+	if err := loaded.CheckModules([]*big.Int{big.NewInt(3), big.NewInt(7)}); err == nil {
+		t.Errorf("%s: modulus 5 of another attack is accepted", t.Name())
+	}
+}
+
+func TestWithOrigin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	origin := &Origin{Attack: "small-subgroup", Group: "MODP-512-V57", Seed: 1, PublicKey: []*big.Int{big.NewInt(12345)}}
+
+	state := &State{}
+	state.Congruences.Add(big.NewInt(2), big.NewInt(3))
+
+	if err := WithOrigin(File(path), origin).Save(state); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	loaded, err := WithOrigin(File(path), origin).Load()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if o := loaded.Origin; o == nil || o.Attack != origin.Attack || o.Group != origin.Group || o.Seed != origin.Seed ||
+		len(o.PublicKey) != 1 || o.PublicKey[0].Cmp(origin.PublicKey[0]) != 0 {
+		t.Fatalf("%s: got origin %+v, want %+v", t.Name(), loaded.Origin, origin)
+	}
+
+	// the unknown seed and public key of a remote Bob match
+	if _, err := WithOrigin(File(path), &Origin{Attack: origin.Attack, Group: origin.Group}).Load(); err != nil {
+		t.Errorf("%s: %s", t.Name(), err.Error())
+	}
+
+	for _, other := range []*Origin{
+		{Attack: "kangaroo", Group: origin.Group, Seed: origin.Seed},
+		{Attack: origin.Attack, Group: "MODP-512-V58", Seed: origin.Seed},
+		{Attack: origin.Attack, Group: origin.Group, Seed: 2},
+		{Attack: origin.Attack, Group: origin.Group, PublicKey: []*big.Int{big.NewInt(54321)}},
+	} {
+		if _, err := WithOrigin(File(path), other).Load(); err == nil {
+			t.Errorf("%s: the state saved by %+v is resumed by %+v", t.Name(), origin, other)
+		}
+	}
+
+	// a state saved without the origin may be anyone's
+	if err := File(path).Save(state); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if _, err := WithOrigin(File(path), origin).Load(); err == nil {
+		t.Errorf("%s: the state saved without the origin is resumed", t.Name())
+	}
+}
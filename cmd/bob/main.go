@@ -0,0 +1,85 @@
+// Command bob serves Bob's side of the key agreements from challenges 57-60
+// over HTTP, see oracle.NewHTTPHandler for the API, and optionally over the
+// TCP line protocol of oracle.StartServer.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/oracle"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "address to listen on")
+	tcpAddr := flag.String("tcp", "", "also serve the line protocol of oracle.StartServer on this address")
+	groupName := flag.String("group", "MODP-512-V57", "DH group served at /dh")
+	dhPolicyName := flag.String("dh-policy", "none", "DH public key validation: none, range, subgroup or cofactor")
+	ecPolicyName := flag.String("ec-policy", "none", "ECDH point validation on P-128: none, on-curve, order or cofactor")
+	cipherName := flag.String("cipher", "", "answer with HKDF-SHA256 and aes-gcm or chacha20-poly1305 instead of the MAC")
+	seed := flag.Int64("seed", 0, "seed for Bob's keys, random keys if 0")
+	flag.Parse()
+
+	group, err := dh.GroupByName(*groupName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dhPolicy, err := dh.ParseValidationPolicy(*dhPolicyName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ecPolicy, err := elliptic.ParseValidationPolicy(*ecPolicyName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var rng io.Reader
+	if *seed != 0 {
+		rng = helpers.NewSeededReader(*seed)
+	}
+
+	dhBob := oracle.NewDHAttackOracle(group, dhPolicy, rng)
+	ecBob := oracle.NewECDHAttackOracle(elliptic.P128(), ecPolicy, rng)
+	x128Bob := oracle.NewX128TwistAttackOracle(rng)
+
+	if *cipherName != "" {
+		c, err := oracle.ParseCipher(*cipherName)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		protocol := oracle.NewAEADProtocol(c, nil)
+		dhBob = dhBob.WithProtocol(protocol)
+		ecBob = ecBob.WithProtocol(protocol)
+		x128Bob = x128Bob.WithProtocol(protocol)
+	}
+
+	oracles := oracle.ServerOracles{
+		DH:            dhBob,
+		ECDH:          ecBob,
+		X128:          x128Bob,
+		DHPublicKey:   dhBob,
+		ECDHPublicKey: ecBob,
+		X128PublicKey: x128Bob,
+	}
+
+	if *tcpAddr != "" {
+		server, err := oracle.StartServer(*tcpAddr, oracles)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer server.Close()
+
+		log.Printf("serving Bob for %s on tcp://%s", group.DHName(), server.Addr())
+	}
+
+	handler := oracle.NewHTTPHandler(oracles)
+
+	log.Printf("serving Bob for %s on http://%s", group.DHName(), *addr)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/svkirillov/cryptopals-go/attack"
+	"github.com/svkirillov/cryptopals-go/checkpoint"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/kangaroo"
+	"github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
+)
+
+// options are the flags shared by all commands.
+type options struct {
+	backend    string
+	addr       string
+	replay     string
+	record     string
+	cipher     string
+	seed       int64
+	timeout    time.Duration
+	reqTimeout time.Duration
+	budget     int
+	log        string
+	checkpoint string
+
+	// origin is the run saved with the checkpoint, set by run
+	origin *checkpoint.Origin
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.backend, "backend", "inproc", "where Bob runs: inproc, tcp, http or replay")
+	fs.StringVar(&o.addr, "addr", "127.0.0.1:8080", "address of Bob's server for -backend tcp or http")
+	fs.StringVar(&o.replay, "replay", "", "transcript to answer from for -backend replay")
+	fs.StringVar(&o.record, "record", "", "write a transcript of the queries to this file")
+	fs.StringVar(&o.cipher, "cipher", "", "Bob answers with HKDF-SHA256 and aes-gcm or chacha20-poly1305 instead of the MAC")
+	fs.Int64Var(&o.seed, "seed", 0, "seed for Bob's keys and the attack, random if 0")
+	fs.DurationVar(&o.timeout, "timeout", 0, "give up after this time, no limit if 0")
+	fs.DurationVar(&o.reqTimeout, "request-timeout", 0, "give up on a request to a remote Bob after this time, no limit if 0")
+	fs.IntVar(&o.budget, "budget", 0, "maximum number of queries to Bob, unlimited if 0")
+	fs.StringVar(&o.log, "log", "text", "progress of the attack on stderr: text, json or none")
+	fs.StringVar(&o.checkpoint, "checkpoint", "", "save the progress of the attack to this file and resume from it")
+}
+
+// bobRNG returns the source of Bob's keys. The attack draws from another
+// stream of the same seed, so that a run can be replayed.
+func (o *options) bobRNG() io.Reader {
+	return helpers.NewSeededReader(o.seed)
+}
+
+func (o *options) attackRNG() io.Reader {
+	return helpers.NewSeededReader(o.seed + 1)
+}
+
+// jumpsRNG returns the source of the random tables of jumps, which is
+// separate from the points and the elements drawn by the attack.
+func (o *options) jumpsRNG() io.Reader {
+	return helpers.NewSeededReader(o.seed + 2)
+}
+
+// protocol returns the protocol Bob answers with, nil for the MAC.
+func (o *options) protocol() (oracle.Protocol, error) {
+	if o.cipher == "" {
+		return nil, nil
+	}
+
+	c, err := oracle.ParseCipher(o.cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	return oracle.NewAEADProtocol(c, nil), nil
+}
+
+// store returns where the progress of the attack is saved, nil if it isn't.
+// A checkpoint saved by another run isn't resumed.
+func (o *options) store() checkpoint.Store {
+	if o.checkpoint == "" {
+		return nil
+	}
+	return checkpoint.WithOrigin(checkpoint.File(o.checkpoint), o.origin)
+}
+
+// attackOptions returns the options of the attack against t, with the
+// kangaroos jumping according to cfg.
+func (o *options) attackOptions(t *target, cfg *kangaroo.Config) *attack.Options {
+	return &attack.Options{Store: o.store(), Kangaroo: cfg, Rand: o.attackRNG(), Events: t.events}
+}
+
+// reporter returns where the progress of the attack goes.
+func (o *options) reporter() (progress.Reporter, error) {
+	switch o.log {
+	case "text":
+		return progress.NewTextLogger(os.Stderr), nil
+	case "json":
+		return progress.NewJSONLogger(os.Stderr), nil
+	case "none":
+		return progress.Discard, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", o.log)
+	}
+}
+
+// bob is the in-process Bob of a command.
+type bob struct {
+	oracle.ServerOracles
+	leak    oracle.LeakOracle
+	checker oracle.KeyChecker
+
+	// publicKey is Bob's public key or the coordinates of his public point
+	publicKey []*big.Int
+}
+
+// target is Bob as seen by an attack, wrapped according to the options.
+type target struct {
+	dh            oracle.DHOracle
+	ecdh          oracle.ECDHOracle
+	x128          oracle.X128Oracle
+	dhPublicKey   oracle.PublicKeySource
+	x128PublicKey oracle.PublicKeySource
+	leak          oracle.LeakOracle
+
+	// checker is nil unless Bob runs in process
+	checker oracle.KeyChecker
+
+	events   progress.Reporter
+	meter    *oracle.Meter
+	recorder *oracle.Recorder
+	closers  []io.Closer
+}
+
+// target connects to Bob on the chosen backend. local is only used with
+// the inproc backend.
+func (o *options) target(local bob) (*target, error) {
+	events, err := o.reporter()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &target{meter: oracle.NewMeter(o.budget), events: events}
+
+	switch o.backend {
+	case "inproc":
+		t.dh, t.ecdh, t.x128 = local.DH, local.ECDH, local.X128
+		t.dhPublicKey, t.x128PublicKey = local.DHPublicKey, local.X128PublicKey
+		t.leak, t.checker = local.leak, local.checker
+	case "tcp":
+		client, err := oracle.Dial(o.addr)
+		if err != nil {
+			return nil, err
+		}
+		client.Timeout = o.reqTimeout
+		t.closers = append(t.closers, client)
+		t.dh, t.ecdh, t.x128 = client, client, client
+		t.dhPublicKey, t.x128PublicKey = client.DHPublicKey(), client.X128PublicKey()
+	case "http":
+		client := oracle.NewHTTPClient("http://" + o.addr)
+		client.Client = &http.Client{Timeout: o.reqTimeout}
+		t.dh, t.ecdh, t.x128 = client, client, client
+		t.dhPublicKey, t.x128PublicKey = client.DHPublicKey(), client.X128PublicKey()
+	case "replay":
+		if o.replay == "" {
+			return nil, errors.New("-backend replay needs a -replay transcript")
+		}
+		f, err := os.Open(o.replay)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		replay, err := oracle.NewReplayOracle(f)
+		if err != nil {
+			return nil, err
+		}
+		t.dh, t.ecdh, t.x128 = replay, replay, replay
+		t.dhPublicKey, t.x128PublicKey, t.leak = replay, replay, replay
+	default:
+		return nil, fmt.Errorf("unknown backend %q", o.backend)
+	}
+
+	if o.record != "" {
+		f, err := os.Create(o.record)
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		t.closers = append(t.closers, f)
+
+		t.recorder = oracle.NewRecorder(f)
+		t.dh = oracle.NewRecordingDHOracle(t.dh, t.recorder)
+		t.ecdh = oracle.NewRecordingECDHOracle(t.ecdh, t.recorder)
+		t.x128 = oracle.NewRecordingX128Oracle(t.x128, t.recorder)
+		if t.dhPublicKey != nil {
+			t.dhPublicKey = oracle.NewRecordingPublicKeySource(t.dhPublicKey, t.recorder)
+		}
+		if t.x128PublicKey != nil {
+			t.x128PublicKey = oracle.NewRecordingPublicKeySource(t.x128PublicKey, t.recorder)
+		}
+		if t.leak != nil {
+			t.leak = oracle.NewRecordingLeakOracle(t.leak, t.recorder)
+		}
+	}
+
+	t.dh = oracle.NewMeteredDHOracle(t.dh, t.meter)
+	t.ecdh = oracle.NewMeteredECDHOracle(t.ecdh, t.meter)
+	t.x128 = oracle.NewMeteredX128Oracle(t.x128, t.meter)
+
+	protocol, err := o.protocol()
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	if protocol != nil {
+		t.dh = oracle.NewCheckedDHOracle(t.dh, protocol)
+		t.ecdh = oracle.NewCheckedECDHOracle(t.ecdh, protocol)
+		t.x128 = oracle.NewCheckedX128Oracle(t.x128, protocol)
+	}
+
+	return t, nil
+}
+
+// Close closes the connection to Bob and the transcript.
+func (t *target) Close() error {
+	var err error
+	for _, c := range t.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if t.recorder != nil && err == nil {
+		err = t.recorder.Err()
+	}
+	return err
+}
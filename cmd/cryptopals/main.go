@@ -0,0 +1,419 @@
+// Command cryptopals runs the attacks from challenges 57-60 against Bob in
+// process, against a Bob served by cmd/bob or against a recorded transcript.
+//
+// Usage:
+//
+//	cryptopals <command> [flags]
+//
+// The commands are small-subgroup, kangaroo, invalid-curve and
+// insecure-twist, see "cryptopals <command> -h" for the flags.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/svkirillov/cryptopals-go/challenge57"
+	"github.com/svkirillov/cryptopals-go/challenge58"
+	"github.com/svkirillov/cryptopals-go/challenge59"
+	"github.com/svkirillov/cryptopals-go/challenge60"
+	"github.com/svkirillov/cryptopals-go/checkpoint"
+	"github.com/svkirillov/cryptopals-go/congruence"
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/kangaroo"
+	"github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
+)
+
+// command is an attack run by a subcommand.
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"small-subgroup": {"small subgroup confinement on a DH group (challenge 57)", smallSubgroup},
+	"kangaroo":       {"small subgroups and Pollard's kangaroos on a DH group (challenge 58)", catchingKangaroos},
+	"invalid-curve":  {"invalid curve attack on ECDH (challenge 59)", invalidCurve},
+	"insecure-twist": {"insecure twist attack on x128 (challenge 60)", insecureTwist},
+}
+
+func main() {
+	log.SetFlags(log.Ltime)
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "-h" && os.Args[1] != "help" {
+			fmt.Fprintf(os.Stderr, "cryptopals: unknown command %q\n", os.Args[1])
+		}
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: cryptopals <command> [flags]\n\ncommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].usage)
+	}
+}
+
+// newFlagSet returns the flags of a command with the shared options.
+func newFlagSet(name string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	opts.register(fs)
+	return fs
+}
+
+// parse parses the flags and picks a seed if none is given.
+func parse(fs *flag.FlagSet, opts *options, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	// a checkpoint is only good for the Bob of its seed
+	if opts.seed == 0 && opts.checkpoint != "" {
+		state, err := checkpoint.File(opts.checkpoint).Load()
+		if err != nil {
+			return err
+		}
+		if state.Origin != nil && state.Origin.Seed != 0 {
+			opts.seed = state.Origin.Seed
+			log.Printf("resuming with seed %d from %s", opts.seed, opts.checkpoint)
+		}
+	}
+
+	if opts.seed == 0 {
+		n, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+		if err != nil {
+			return err
+		}
+		opts.seed = n.Int64() + 1
+	}
+
+	return nil
+}
+
+func smallSubgroup(args []string) error {
+	var opts options
+	fs := newFlagSet("small-subgroup", &opts)
+	groupName := fs.String("group", "MODP-512-V57", "DH group: "+strings.Join(dh.GroupNames(), ", "))
+	policyName := fs.String("dh-policy", "none", "Bob's public key validation: none, range, subgroup or cofactor")
+	if err := parse(fs, &opts, args); err != nil {
+		return err
+	}
+
+	group, local, err := dhBob(&opts, *groupName, *policyName)
+	if err != nil {
+		return err
+	}
+
+	return run(&opts, "small-subgroup", group.DHName(), local, func(ctx context.Context, t *target) (*big.Int, error) {
+		return challenge57.SmallSubgroupAttack(ctx, group, t.dh, opts.attackOptions(t, nil))
+	})
+}
+
+func catchingKangaroos(args []string) error {
+	var opts options
+	var jumps jumpOptions
+	fs := newFlagSet("kangaroo", &opts)
+	jumps.register(fs)
+	groupName := fs.String("group", "MODP-512-V58", "DH group: "+strings.Join(dh.GroupNames(), ", "))
+	policyName := fs.String("dh-policy", "none", "Bob's public key validation: none, range, subgroup or cofactor")
+	if err := parse(fs, &opts, args); err != nil {
+		return err
+	}
+
+	group, local, err := dhBob(&opts, *groupName, *policyName)
+	if err != nil {
+		return err
+	}
+	cfg, err := jumps.config(&opts)
+	if err != nil {
+		return err
+	}
+
+	return run(&opts, "kangaroo", group.DHName(), local, func(ctx context.Context, t *target) (*big.Int, error) {
+		return challenge58.CatchingKangaroosAttack(ctx, group, t.dh, t.dhPublicKey, opts.attackOptions(t, cfg))
+	})
+}
+
+func invalidCurve(args []string) error {
+	var opts options
+	fs := newFlagSet("invalid-curve", &opts)
+	curveName := fs.String("curve", "P-128", "Bob's curve: "+strings.Join(elliptic.CurveNames(), ", "))
+	policyName := fs.String("ec-policy", "none", "Bob's point validation: none, on-curve, order or cofactor")
+	if err := parse(fs, &opts, args); err != nil {
+		return err
+	}
+
+	curve, err := elliptic.CurveByName(*curveName)
+	if err != nil {
+		return err
+	}
+	policy, err := elliptic.ParseValidationPolicy(*policyName)
+	if err != nil {
+		return err
+	}
+	protocol, err := opts.protocol()
+	if err != nil {
+		return err
+	}
+
+	ecBob := oracle.NewECDHAttackOracle(curve, policy, opts.bobRNG())
+	if protocol != nil {
+		ecBob = ecBob.WithProtocol(protocol)
+	}
+	x, y, err := ecBob.PublicKey()
+	if err != nil {
+		return err
+	}
+	local := bob{
+		ServerOracles: oracle.ServerOracles{ECDH: ecBob, ECDHPublicKey: ecBob},
+		checker:       ecBob,
+		publicKey:     []*big.Int{x, y},
+	}
+
+	return run(&opts, "invalid-curve", curve.Params().Name, local, func(ctx context.Context, t *target) (*big.Int, error) {
+		// the malicious curves of the challenge only fit P-128
+		if curve.Params().Name == "P-128" {
+			return challenge59.InvalidCurveAttack(ctx, t.ecdh, nil, opts.attackOptions(t, nil))
+		}
+
+		invalidCurves, err := elliptic.GenerateInvalidCurves(curve, big.NewInt(1<<16), 100)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range invalidCurves {
+			t.events.Report(progress.Event{
+				Kind:    progress.Note,
+				Message: fmt.Sprintf("invalid curve %s with factors %d", c.Name, c.Factors),
+			})
+		}
+
+		return challenge59.InvalidCurveAttack(ctx, t.ecdh, invalidCurves, opts.attackOptions(t, nil))
+	})
+}
+
+func insecureTwist(args []string) error {
+	var opts options
+	var jumps jumpOptions
+	fs := newFlagSet("insecure-twist", &opts)
+	jumps.register(fs)
+	if err := parse(fs, &opts, args); err != nil {
+		return err
+	}
+
+	if opts.backend == "tcp" || opts.backend == "http" {
+		return fmt.Errorf("insecure-twist needs Bob's leak oracle, which -backend %s doesn't serve", opts.backend)
+	}
+
+	protocol, err := opts.protocol()
+	if err != nil {
+		return err
+	}
+	cfg, err := jumps.config(&opts)
+	if err != nil {
+		return err
+	}
+
+	x128Bob := oracle.NewX128TwistAttackOracle(opts.bobRNG())
+	if protocol != nil {
+		x128Bob = x128Bob.WithProtocol(protocol)
+	}
+	publicKey, err := x128Bob.PublicKey()
+	if err != nil {
+		return err
+	}
+	local := bob{
+		ServerOracles: oracle.ServerOracles{X128: x128Bob, X128PublicKey: x128Bob},
+		leak:          x128Bob,
+		checker:       x128Bob,
+		publicKey:     []*big.Int{publicKey},
+	}
+
+	return run(&opts, "insecure-twist", "x128", local, func(ctx context.Context, t *target) (*big.Int, error) {
+		return challenge60.InsecureTwistsAttack(ctx, t.x128, t.x128PublicKey, t.leak, opts.attackOptions(t, cfg))
+	})
+}
+
+// jumpOptions are the flags of the commands with kangaroos.
+type jumpOptions struct {
+	jumps    string
+	attempts int
+}
+
+func (o *jumpOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.jumps, "jumps", "powers", "jumps of the kangaroos: powers, i.e. 2^(y mod k), or random, i.e. a random table")
+	fs.IntVar(&o.attempts, "attempts", 3, "runs of the kangaroos with new jumps before giving up")
+}
+
+// config returns the configuration of the kangaroos. The random tables are
+// drawn from the seed of the attack.
+func (o *jumpOptions) config(opts *options) (*kangaroo.Config, error) {
+	cfg := &kangaroo.Config{Attempts: o.attempts}
+
+	switch o.jumps {
+	case "powers":
+		cfg.Jumps = kangaroo.PowersOfTwo
+	case "random":
+		cfg.Jumps = kangaroo.RandomTable(opts.jumpsRNG())
+	default:
+		return nil, fmt.Errorf("unknown jumps %q", o.jumps)
+	}
+
+	return cfg, nil
+}
+
+// dhBob returns the group and the in-process Bob of the DH commands.
+func dhBob(opts *options, groupName, policyName string) (dh.DHScheme, bob, error) {
+	group, err := dh.GroupByName(groupName)
+	if err != nil {
+		return nil, bob{}, err
+	}
+	policy, err := dh.ParseValidationPolicy(policyName)
+	if err != nil {
+		return nil, bob{}, err
+	}
+	protocol, err := opts.protocol()
+	if err != nil {
+		return nil, bob{}, err
+	}
+
+	dhBob := oracle.NewDHAttackOracle(group, policy, opts.bobRNG())
+	if protocol != nil {
+		dhBob = dhBob.WithProtocol(protocol)
+	}
+
+	publicKey, err := dhBob.PublicKey()
+	if err != nil {
+		return nil, bob{}, err
+	}
+
+	return group, bob{
+		ServerOracles: oracle.ServerOracles{DH: dhBob, DHPublicKey: dhBob},
+		checker:       dhBob,
+		publicKey:     []*big.Int{publicKey},
+	}, nil
+}
+
+// run runs the attack f against the target and reports the recovered key.
+// If the attack is interrupted or times out, it reports what it learned so
+// far.
+func run(opts *options, name, params string, local bob, f func(ctx context.Context, t *target) (*big.Int, error)) error {
+	t, err := opts.target(local)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%s on %s, %s backend, seed %d", name, params, opts.backend, opts.seed)
+
+	// Bob's key is only known in process, a remote Bob may change it
+	opts.origin = &checkpoint.Origin{Attack: name, Group: params, Seed: opts.seed}
+	if opts.backend == "inproc" {
+		opts.origin.PublicKey = local.publicKey
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if opts.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, opts.timeout)
+		defer cancelTimeout()
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	type result struct {
+		key *big.Int
+		err error
+	}
+
+	done := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		key, err := f(ctx, t)
+		done <- result{key, err}
+	}()
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	var res result
+loop:
+	for {
+		select {
+		case res = <-done:
+			break loop
+		case <-ticker.C:
+			log.Printf("%s elapsed, %s", time.Since(start).Round(time.Second), t.meter.Summary())
+		case <-interrupt:
+			log.Printf("interrupted, waiting for the attack to stop")
+			cancel()
+		}
+	}
+
+	elapsed := time.Since(start)
+
+	if err := t.Close(); err != nil {
+		return err
+	}
+
+	var partial *congruence.PartialError
+	if errors.As(res.err, &partial) {
+		if x, n, err := partial.Congruences.Solve(); err == nil {
+			fmt.Printf("private key: %d mod %d\n", x, n)
+		}
+		for _, candidate := range partial.Candidates {
+			fmt.Printf("candidate: %d\n", candidate)
+		}
+	}
+	if res.err != nil {
+		return fmt.Errorf("%s: %w (%s)", name, res.err, t.meter.Summary())
+	}
+
+	fmt.Printf("private key: %d\n", res.key)
+	log.Printf("recovered in %s, %s", elapsed.Round(time.Millisecond), t.meter.Summary())
+
+	if t.checker == nil {
+		log.Printf("the key can't be checked with the %s backend", opts.backend)
+		return nil
+	}
+	if !t.checker.IsKeyCorrect(res.key.Bytes()) {
+		return errors.New("the key isn't Bob's private key")
+	}
+	log.Printf("the key is Bob's private key")
+
+	return nil
+}
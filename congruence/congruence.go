@@ -0,0 +1,76 @@
+// Package congruence collects the congruences x = r mod m on Bob's private
+// key x which the attacks recover one small modulus at a time.
+package congruence
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+// System is a system of congruences x = Remainders[i] mod Modules[i] with
+// pairwise coprime modules. The zero value is an empty system.
+type System struct {
+	Remainders []*big.Int
+	Modules    []*big.Int
+}
+
+// Add adds the congruence x = r mod m.
+func (s *System) Add(r, m *big.Int) {
+	s.Remainders = append(s.Remainders, r)
+	s.Modules = append(s.Modules, m)
+}
+
+// Len returns the number of congruences.
+func (s *System) Len() int {
+	return len(s.Modules)
+}
+
+// Modulus returns the product of the modules, 1 for an empty system.
+func (s *System) Modulus() *big.Int {
+	n := big.NewInt(1)
+	for _, m := range s.Modules {
+		n.Mul(n, m)
+	}
+	return n
+}
+
+// Solve returns x modulo n, the product of the modules, using the Chinese
+// Remainder Theorem.
+func (s *System) Solve() (x, n *big.Int, err error) {
+	if s.Len() == 0 {
+		return nil, nil, errors.New("empty sets of modules and remainders")
+	}
+
+	x, n, err = helpers.ChineseRemainderTheorem(s.Remainders, s.Modules)
+	if err != nil {
+		return nil, nil, fmt.Errorf("chinese remainder theorem: %s", err.Error())
+	}
+
+	return x, n, nil
+}
+
+// PartialError is returned by an attack stopped before it recovered the
+// private key, e.g. because its context was canceled. It holds what the
+// attack learned so far.
+type PartialError struct {
+	// Congruences are the congruences on the private key found so far.
+	Congruences System
+
+	// Candidates, if not empty, are the possible values of the private key
+	// modulo Congruences.Modulus().
+	Candidates []*big.Int
+
+	// Err is the reason the attack stopped.
+	Err error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%s, the private key is known modulo %d", e.Err, e.Congruences.Modulus())
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
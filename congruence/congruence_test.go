@@ -0,0 +1,37 @@
+package congruence
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestSystem(t *testing.T) {
+	var s System
+
+	if _, _, err := s.Solve(); err == nil {
+		t.Errorf("%s: an empty system is solved", t.Name())
+	}
+	if s.Modulus().Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("%s: the modulus of an empty system is %d, want 1", t.Name(), s.Modulus())
+	}
+
+	// x = 23
+	s.Add(big.NewInt(2), big.NewInt(3))
+	s.Add(big.NewInt(3), big.NewInt(5))
+	s.Add(big.NewInt(2), big.NewInt(7))
+
+	x, n, err := s.Solve()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	if x.Cmp(big.NewInt(23)) != 0 || n.Cmp(big.NewInt(105)) != 0 || s.Modulus().Cmp(n) != 0 {
+		t.Errorf("%s: got x = %d mod %d, want 23 mod 105", t.Name(), x, n)
+	}
+
+	var err2 error = &PartialError{Congruences: s, Err: context.DeadlineExceeded}
+	if !errors.Is(err2, context.DeadlineExceeded) {
+		t.Errorf("%s: %v doesn't unwrap to the reason", t.Name(), err2)
+	}
+}
@@ -20,6 +20,10 @@ type DHScheme interface {
 	// public keys and returns the result.
 	DH(private, public *big.Int) *big.Int
 
+	// ValidatedDH checks the public key according to the policy and performs
+	// a Diffie-Hellman calculation if the key is accepted.
+	ValidatedDH(private, public *big.Int, policy ValidationPolicy) (*big.Int, error)
+
 	// DHLen is the number of bites returned by DH.
 	DHLen() int
 
@@ -49,7 +53,7 @@ func (g GroupParams) GenerateKey(rng io.Reader) (*DHKey, error) {
 		rng = rand.Reader
 	}
 
-	privateKey, err := rand.Int(rand.Reader, g.Q)
+	privateKey, err := rand.Int(rng, g.Q)
 	if err != nil {
 		return nil, err
 	}
@@ -16,6 +16,7 @@ var initonce sync.Once
 func initAll() {
 	initModp512v57()
 	initModp512v58()
+	initStandardGroups()
 }
 
 func initModp512v57() {
@@ -0,0 +1,319 @@
+package dh
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+// maxSmoothTries is the number of attempts to find the last smooth factor
+// before starting over with a new set of factors.
+const maxSmoothTries = 1 << 10
+
+// maxFactorSets is the number of sets of factors tried before giving up.
+const maxFactorSets = 1 << 10
+
+// GenerateWeakGroup generates a group with a pBits-bit prime P, a subgroup of
+// prime order Q of qBits bits and a cofactor J = (P-1)/Q which is a product
+// of 2 and distinct primes less than smoothBound. It returns the group and
+// the factors of J in ascending order.
+//
+// Such groups are vulnerable to small subgroup confinement attacks, see
+// challenge 57 and challenge 58.
+func GenerateWeakGroup(rng io.Reader, pBits, qBits int, smoothBound *big.Int) (*GroupParams, []*big.Int, error) {
+	if rng == nil {
+		rng = rand.Reader
+	}
+
+	if smoothBound.Cmp(big.NewInt(5)) < 0 {
+		return nil, nil, errors.New("dh: smoothness bound is too small")
+	}
+	if pBits-qBits <= smoothBound.BitLen() {
+		return nil, nil, fmt.Errorf("dh: %d-bit cofactor is too small for the smoothness bound", pBits-qBits)
+	}
+
+	// J = 2 * distinct odd primes has to reach 2^(pBits-qBits-1)
+	low := new(big.Int).Lsh(helpers.BigOne, uint(pBits-qBits-2))
+	if !primesReach(helpers.BigThree, smoothBound, low) {
+		return nil, nil, fmt.Errorf("dh: too few primes less than %d for a %d-bit cofactor", smoothBound, pBits-qBits)
+	}
+
+	q, err := helpers.GeneratePrime(rng, qBits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 2^(pBits-1) <= P-1 < 2^pBits
+	lowP := new(big.Int).Lsh(helpers.BigOne, uint(pBits-1))
+	highP := new(big.Int).Lsh(helpers.BigOne, uint(pBits))
+	highP.Sub(highP, helpers.BigOne)
+
+	for set := 0; set < maxFactorSets; set++ {
+		factors := []*big.Int{big.NewInt(2)}
+		used := map[string]bool{"2": true}
+		qj := new(big.Int).Lsh(q, 1)
+
+		// the last factor has to be in [lo, hi] for P to have pBits bits
+		lo, hi := new(big.Int), new(big.Int)
+		bounds := func() {
+			lo.Add(lowP, qj).Sub(lo, helpers.BigOne).Div(lo, qj)
+			hi.Div(highP, qj)
+		}
+
+		// the primes drawn again and again may be all that's left, so the
+		// set is given up after a number of draws in a row
+		draws := 0
+		for bounds(); lo.Cmp(smoothBound) >= 0 && draws < maxSmoothTries; bounds() {
+			r, err := randomPrime(rng, helpers.BigThree, smoothBound)
+			if err != nil {
+				return nil, nil, err
+			}
+			if r == nil || used[r.String()] {
+				draws++
+				continue
+			}
+
+			used[r.String()] = true
+			factors = append(factors, r)
+			qj.Mul(qj, r)
+			draws = 0
+		}
+		if lo.Cmp(smoothBound) >= 0 {
+			continue
+		}
+
+		if lo.Cmp(helpers.BigThree) < 0 {
+			lo.Set(helpers.BigThree)
+		}
+		if hi.Cmp(smoothBound) >= 0 {
+			hi.Sub(smoothBound, helpers.BigOne)
+		}
+		if lo.Cmp(hi) > 0 {
+			continue
+		}
+
+		for i := 0; i < maxSmoothTries; i++ {
+			r, err := randomPrime(rng, lo, new(big.Int).Add(hi, helpers.BigOne))
+			if err != nil {
+				return nil, nil, err
+			}
+			if r == nil {
+				break
+			}
+			if used[r.String()] {
+				continue
+			}
+
+			// P = Q*J + 1
+			p := new(big.Int).Mul(qj, r)
+			p.Add(p, helpers.BigOne)
+			if !p.ProbablyPrime(20) {
+				continue
+			}
+
+			factors = append(factors, r)
+			sort.Slice(factors, func(i, j int) bool { return factors[i].Cmp(factors[j]) < 0 })
+
+			j := new(big.Int).Sub(p, helpers.BigOne)
+			g, err := subgroupGenerator(rng, p, j.Div(j, q))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return &GroupParams{
+				P:       p,
+				G:       g,
+				Q:       q,
+				Name:    fmt.Sprintf("WEAK-%d-%d", pBits, qBits),
+				BitSize: pBits,
+			}, factors, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("dh: no %d-bit weak group found after %d sets of factors", pBits, maxFactorSets)
+}
+
+// GenerateSmoothGroup generates a group with a pBits-bit prime P such that
+// P-1 is a product of 2 and primes less than smoothBound, which may repeat,
+// and G which generates the whole multiplicative group, so Q = P-1. It
+// returns the group and the prime factors of P-1 in ascending order, with
+// the repeated ones repeated.
+//
+// The logarithm of any element in such a group is found by Pohlig-Hellman
+// one prime power of P-1 at a time, so Bob's public key gives his private
+// key away.
+func GenerateSmoothGroup(rng io.Reader, pBits int, smoothBound *big.Int) (*GroupParams, []*big.Int, error) {
+	if rng == nil {
+		rng = rand.Reader
+	}
+
+	if smoothBound.Cmp(big.NewInt(5)) < 0 {
+		return nil, nil, errors.New("dh: smoothness bound is too small")
+	}
+	if pBits <= smoothBound.BitLen()+1 {
+		return nil, nil, fmt.Errorf("dh: %d-bit P is too small for the smoothness bound", pBits)
+	}
+
+	// 2^(pBits-1) <= P-1 < 2^pBits
+	lowP := new(big.Int).Lsh(helpers.BigOne, uint(pBits-1))
+	highP := new(big.Int).Lsh(helpers.BigOne, uint(pBits))
+	highP.Sub(highP, helpers.BigOne)
+
+	for set := 0; set < maxFactorSets; set++ {
+		factors := []*big.Int{big.NewInt(2)}
+		j := big.NewInt(2)
+
+		// the last factor has to be in [lo, hi] for P to have pBits bits
+		lo, hi := new(big.Int), new(big.Int)
+		bounds := func() {
+			lo.Add(lowP, j).Sub(lo, helpers.BigOne).Div(lo, j)
+			hi.Div(highP, j)
+		}
+
+		for bounds(); lo.Cmp(smoothBound) >= 0; bounds() {
+			r, err := randomPrime(rng, helpers.BigTwo, smoothBound)
+			if err != nil {
+				return nil, nil, err
+			}
+			if r == nil {
+				continue
+			}
+
+			factors = append(factors, r)
+			j.Mul(j, r)
+		}
+
+		if lo.Cmp(helpers.BigTwo) < 0 {
+			lo.Set(helpers.BigTwo)
+		}
+		if hi.Cmp(smoothBound) >= 0 {
+			hi.Sub(smoothBound, helpers.BigOne)
+		}
+		if lo.Cmp(hi) > 0 {
+			continue
+		}
+
+		for i := 0; i < maxSmoothTries; i++ {
+			r, err := randomPrime(rng, lo, new(big.Int).Add(hi, helpers.BigOne))
+			if err != nil {
+				return nil, nil, err
+			}
+			if r == nil {
+				break
+			}
+
+			// P = J*r + 1
+			p := new(big.Int).Mul(j, r)
+			p.Add(p, helpers.BigOne)
+			if !p.ProbablyPrime(20) {
+				continue
+			}
+
+			factors = append(factors, r)
+			sort.Slice(factors, func(i, j int) bool { return factors[i].Cmp(factors[j]) < 0 })
+
+			g, err := groupGenerator(rng, p, factors)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return &GroupParams{
+				P:       p,
+				G:       g,
+				Q:       new(big.Int).Sub(p, helpers.BigOne),
+				Name:    fmt.Sprintf("SMOOTH-%d", pBits),
+				BitSize: pBits,
+			}, factors, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("dh: no %d-bit smooth group found after %d sets of factors", pBits, maxFactorSets)
+}
+
+// primesReach reports whether the product of the primes in [lo, hi) reaches
+// target.
+func primesReach(lo, hi, target *big.Int) bool {
+	product := big.NewInt(1)
+	for r := new(big.Int).Set(lo); r.Cmp(hi) < 0 && product.Cmp(target) < 0; r.Add(r, helpers.BigOne) {
+		if r.ProbablyPrime(20) {
+			product.Mul(product, r)
+		}
+	}
+
+	return product.Cmp(target) >= 0
+}
+
+// randomPrime returns a uniform random prime in [lo, hi), or nil if there
+// isn't one after a number of tries.
+func randomPrime(rng io.Reader, lo, hi *big.Int) (*big.Int, error) {
+	width := new(big.Int).Sub(hi, lo)
+	if width.Sign() <= 0 {
+		return nil, nil
+	}
+
+	for i := 0; i < maxSmoothTries; i++ {
+		r, err := rand.Int(rng, width)
+		if err != nil {
+			return nil, err
+		}
+		r.Add(r, lo)
+
+		if r.ProbablyPrime(20) {
+			return r, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// subgroupGenerator returns h^j mod p != 1 for a random h.
+func subgroupGenerator(rng io.Reader, p, j *big.Int) (*big.Int, error) {
+	pMinusThree := new(big.Int).Sub(p, helpers.BigThree)
+
+	for {
+		// h in [2, p-2]
+		h, err := rand.Int(rng, pMinusThree)
+		if err != nil {
+			return nil, err
+		}
+		h.Add(h, helpers.BigTwo)
+
+		if g := h.Exp(h, j, p); g.Cmp(helpers.BigOne) != 0 {
+			return g, nil
+		}
+	}
+}
+
+// groupGenerator returns a random generator of the multiplicative group
+// modulo p, i.e. h such that h^((p-1)/r) != 1 mod p for all the prime
+// factors r of p-1.
+func groupGenerator(rng io.Reader, p *big.Int, factors []*big.Int) (*big.Int, error) {
+	pMinusOne := new(big.Int).Sub(p, helpers.BigOne)
+	pMinusThree := new(big.Int).Sub(p, helpers.BigThree)
+	e, power := new(big.Int), new(big.Int)
+
+	for {
+		// h in [2, p-2]
+		h, err := rand.Int(rng, pMinusThree)
+		if err != nil {
+			return nil, err
+		}
+		h.Add(h, helpers.BigTwo)
+
+		generator := true
+		for _, r := range factors {
+			if power.Exp(h, e.Div(pMinusOne, r), p).Cmp(helpers.BigOne) == 0 {
+				generator = false
+				break
+			}
+		}
+		if generator {
+			return h, nil
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package dh
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+func TestGenerateWeakGroup(t *testing.T) {
+	group, factors, err := GenerateWeakGroup(helpers.NewSeededReader(1), 128, 32, big.NewInt(1<<16))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	// P-1 = Q * J
+	j := big.NewInt(1)
+	for _, r := range factors {
+		j.Mul(j, r)
+	}
+	if p := j.Mul(j, group.Q).Add(j, helpers.BigOne); p.Cmp(group.P) != 0 || group.P.BitLen() != 128 {
+		t.Fatalf("%s: got P = %d, want %d-bit Q*J+1 = %d", t.Name(), group.P, 128, p)
+	}
+}
+
+func TestGenerateWeakGroupErrors(t *testing.T) {
+	tests := []struct {
+		pBits, qBits int
+		smoothBound  int64
+	}{
+		{48, 32, 4},
+		{48, 32, 1 << 16},
+		// 3 is the only odd prime less than 5
+		{48, 32, 5},
+		{64, 32, 8},
+	}
+
+	for _, e := range tests {
+		if _, _, err := GenerateWeakGroup(helpers.NewSeededReader(1), e.pBits, e.qBits, big.NewInt(e.smoothBound)); err == nil {
+			t.Errorf("%s: no error for %d-bit p, %d-bit q and the bound %d", t.Name(), e.pBits, e.qBits, e.smoothBound)
+		}
+	}
+}
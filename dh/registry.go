@@ -0,0 +1,65 @@
+package dh
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]func() DHScheme{
+		"MODP-512-V57":     MODP512V57,
+		"MODP-512-V58":     MODP512V58,
+		"MODP-1536":        MODP1536,
+		"MODP-2048":        MODP2048,
+		"MODP-3072":        MODP3072,
+		"MODP-4096":        MODP4096,
+		"MODP-6144":        MODP6144,
+		"MODP-8192":        MODP8192,
+		"FFDHE-2048":       FFDHE2048,
+		"FFDHE-3072":       FFDHE3072,
+		"FFDHE-4096":       FFDHE4096,
+		"FFDHE-6144":       FFDHE6144,
+		"FFDHE-8192":       FFDHE8192,
+		"RFC5114-1024-160": RFC5114P1024Q160,
+		"RFC5114-2048-224": RFC5114P2048Q224,
+		"RFC5114-2048-256": RFC5114P2048Q256,
+	}
+)
+
+// Register makes a group available by its DHName, e.g. a group made by
+// GenerateWeakGroup. It replaces a group registered under the same name.
+func Register(group DHScheme) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[group.DHName()] = func() DHScheme { return group }
+}
+
+// GroupByName returns the registered group with the given name.
+func GroupByName(name string) (DHScheme, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	group, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("dh: unknown group %q", name)
+	}
+
+	return group(), nil
+}
+
+// GroupNames returns the names of all registered groups in sorted order.
+func GroupNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,511 @@
+package dh
+
+import (
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+// More Modular Exponential (MODP) groups from RFC 3526
+var modp1536, modp2048, modp3072, modp4096, modp6144, modp8192 *GroupParams
+
+// Negotiated Finite Field Diffie-Hellman Ephemeral groups from RFC 7919
+var ffdhe2048, ffdhe3072, ffdhe4096, ffdhe6144, ffdhe8192 *GroupParams
+
+// Groups with prime order subgroups from RFC 5114
+var rfc5114p1024q160, rfc5114p2048q224, rfc5114p2048q256 *GroupParams
+
+func initStandardGroups() {
+	initModp1536()
+	initModp2048()
+	initModp3072()
+	initModp4096()
+	initModp6144()
+	initModp8192()
+	initFfdhe2048()
+	initFfdhe3072()
+	initFfdhe4096()
+	initFfdhe6144()
+	initFfdhe8192()
+	initRfc5114p1024q160()
+	initRfc5114p2048q224()
+	initRfc5114p2048q256()
+}
+
+// safePrimeSubgroupOrder returns (p-1)/2, the order of the subgroup of
+// quadratic residues modulo a safe prime p.
+func safePrimeSubgroupOrder(p *big.Int) *big.Int {
+	q := new(big.Int).Sub(p, helpers.BigOne)
+	return q.Rsh(q, 1)
+}
+
+func initModp1536() {
+	// See RFC 3526, section 2
+	modp1536 = &GroupParams{
+		Name:    "MODP-1536",
+		BitSize: 1536,
+	}
+	modp1536.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74"+
+		"020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F1437"+
+		"4FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF05"+
+		"98DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB"+
+		"9ED529077096966D670C354E4ABC9804F1746C08CA237327FFFFFFFFFFFFFFFF", 16)
+	modp1536.G, _ = new(big.Int).SetString("2", 16)
+	modp1536.Q = safePrimeSubgroupOrder(modp1536.P)
+}
+
+func initModp2048() {
+	// See RFC 3526, section 3
+	modp2048 = &GroupParams{
+		Name:    "MODP-2048",
+		BitSize: 2048,
+	}
+	modp2048.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74"+
+		"020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F1437"+
+		"4FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF05"+
+		"98DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB"+
+		"9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF695581718"+
+		"3995497CEA956AE515D2261898FA051015728E5A8AACAA68FFFFFFFFFFFFFFFF", 16)
+	modp2048.G, _ = new(big.Int).SetString("2", 16)
+	modp2048.Q = safePrimeSubgroupOrder(modp2048.P)
+}
+
+func initModp3072() {
+	// See RFC 3526, section 4
+	modp3072 = &GroupParams{
+		Name:    "MODP-3072",
+		BitSize: 3072,
+	}
+	modp3072.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74"+
+		"020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F1437"+
+		"4FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF05"+
+		"98DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB"+
+		"9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF695581718"+
+		"3995497CEA956AE515D2261898FA051015728E5A8AAAC42DAD33170D04507A33"+
+		"A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7"+
+		"ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864"+
+		"D87602733EC86A64521F2B18177B200CBBE117577A615D6C770988C0BAD946E2"+
+		"08E24FA074E5AB3143DB5BFCE0FD108E4B82D120A93AD2CAFFFFFFFFFFFFFFFF", 16)
+	modp3072.G, _ = new(big.Int).SetString("2", 16)
+	modp3072.Q = safePrimeSubgroupOrder(modp3072.P)
+}
+
+func initModp4096() {
+	// See RFC 3526, section 5
+	modp4096 = &GroupParams{
+		Name:    "MODP-4096",
+		BitSize: 4096,
+	}
+	modp4096.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74"+
+		"020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F1437"+
+		"4FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF05"+
+		"98DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB"+
+		"9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF695581718"+
+		"3995497CEA956AE515D2261898FA051015728E5A8AAAC42DAD33170D04507A33"+
+		"A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7"+
+		"ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864"+
+		"D87602733EC86A64521F2B18177B200CBBE117577A615D6C770988C0BAD946E2"+
+		"08E24FA074E5AB3143DB5BFCE0FD108E4B82D120A92108011A723C12A787E6D7"+
+		"88719A10BDBA5B2699C327186AF4E23C1A946834B6150BDA2583E9CA2AD44CE8"+
+		"DBBBC2DB04DE8EF92E8EFC141FBECAA6287C59474E6BC05D99B2964FA090C3A2"+
+		"233BA186515BE7ED1F612970CEE2D7AFB81BDD762170481CD0069127D5B05AA9"+
+		"93B4EA988D8FDDC186FFB7DC90A6C08F4DF435C934063199FFFFFFFFFFFFFFFF", 16)
+	modp4096.G, _ = new(big.Int).SetString("2", 16)
+	modp4096.Q = safePrimeSubgroupOrder(modp4096.P)
+}
+
+func initModp6144() {
+	// See RFC 3526, section 6
+	modp6144 = &GroupParams{
+		Name:    "MODP-6144",
+		BitSize: 6144,
+	}
+	modp6144.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74"+
+		"020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F1437"+
+		"4FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF05"+
+		"98DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB"+
+		"9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF695581718"+
+		"3995497CEA956AE515D2261898FA051015728E5A8AAAC42DAD33170D04507A33"+
+		"A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7"+
+		"ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864"+
+		"D87602733EC86A64521F2B18177B200CBBE117577A615D6C770988C0BAD946E2"+
+		"08E24FA074E5AB3143DB5BFCE0FD108E4B82D120A92108011A723C12A787E6D7"+
+		"88719A10BDBA5B2699C327186AF4E23C1A946834B6150BDA2583E9CA2AD44CE8"+
+		"DBBBC2DB04DE8EF92E8EFC141FBECAA6287C59474E6BC05D99B2964FA090C3A2"+
+		"233BA186515BE7ED1F612970CEE2D7AFB81BDD762170481CD0069127D5B05AA9"+
+		"93B4EA988D8FDDC186FFB7DC90A6C08F4DF435C93402849236C3FAB4D27C7026"+
+		"C1D4DCB2602646DEC9751E763DBA37BDF8FF9406AD9E530EE5DB382F413001AE"+
+		"B06A53ED9027D831179727B0865A8918DA3EDBEBCF9B14ED44CE6CBACED4BB1B"+
+		"DB7F1447E6CC254B332051512BD7AF426FB8F401378CD2BF5983CA01C64B92EC"+
+		"F032EA15D1721D03F482D7CE6E74FEF6D55E702F46980C82B5A84031900B1C9E"+
+		"59E7C97FBEC7E8F323A97A7E36CC88BE0F1D45B7FF585AC54BD407B22B4154AA"+
+		"CC8F6D7EBF48E1D814CC5ED20F8037E0A79715EEF29BE32806A1D58BB7C5DA76"+
+		"F550AA3D8A1FBFF0EB19CCB1A313D55CDA56C9EC2EF29632387FE8D76E3C0468"+
+		"043E8F663F4860EE12BF2D5B0B7474D6E694F91E6DCC4024FFFFFFFFFFFFFFFF", 16)
+	modp6144.G, _ = new(big.Int).SetString("2", 16)
+	modp6144.Q = safePrimeSubgroupOrder(modp6144.P)
+}
+
+func initModp8192() {
+	// See RFC 3526, section 7
+	modp8192 = &GroupParams{
+		Name:    "MODP-8192",
+		BitSize: 8192,
+	}
+	modp8192.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74"+
+		"020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F1437"+
+		"4FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF05"+
+		"98DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB"+
+		"9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF695581718"+
+		"3995497CEA956AE515D2261898FA051015728E5A8AAAC42DAD33170D04507A33"+
+		"A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7"+
+		"ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864"+
+		"D87602733EC86A64521F2B18177B200CBBE117577A615D6C770988C0BAD946E2"+
+		"08E24FA074E5AB3143DB5BFCE0FD108E4B82D120A92108011A723C12A787E6D7"+
+		"88719A10BDBA5B2699C327186AF4E23C1A946834B6150BDA2583E9CA2AD44CE8"+
+		"DBBBC2DB04DE8EF92E8EFC141FBECAA6287C59474E6BC05D99B2964FA090C3A2"+
+		"233BA186515BE7ED1F612970CEE2D7AFB81BDD762170481CD0069127D5B05AA9"+
+		"93B4EA988D8FDDC186FFB7DC90A6C08F4DF435C93402849236C3FAB4D27C7026"+
+		"C1D4DCB2602646DEC9751E763DBA37BDF8FF9406AD9E530EE5DB382F413001AE"+
+		"B06A53ED9027D831179727B0865A8918DA3EDBEBCF9B14ED44CE6CBACED4BB1B"+
+		"DB7F1447E6CC254B332051512BD7AF426FB8F401378CD2BF5983CA01C64B92EC"+
+		"F032EA15D1721D03F482D7CE6E74FEF6D55E702F46980C82B5A84031900B1C9E"+
+		"59E7C97FBEC7E8F323A97A7E36CC88BE0F1D45B7FF585AC54BD407B22B4154AA"+
+		"CC8F6D7EBF48E1D814CC5ED20F8037E0A79715EEF29BE32806A1D58BB7C5DA76"+
+		"F550AA3D8A1FBFF0EB19CCB1A313D55CDA56C9EC2EF29632387FE8D76E3C0468"+
+		"043E8F663F4860EE12BF2D5B0B7474D6E694F91E6DBE115974A3926F12FEE5E4"+
+		"38777CB6A932DF8CD8BEC4D073B931BA3BC832B68D9DD300741FA7BF8AFC47ED"+
+		"2576F6936BA424663AAB639C5AE4F5683423B4742BF1C978238F16CBE39D652D"+
+		"E3FDB8BEFC848AD922222E04A4037C0713EB57A81A23F0C73473FC646CEA306B"+
+		"4BCBC8862F8385DDFA9D4B7FA2C087E879683303ED5BDD3A062B3CF5B3A278A6"+
+		"6D2A13F83F44F82DDF310EE074AB6A364597E899A0255DC164F31CC50846851D"+
+		"F9AB48195DED7EA1B1D510BD7EE74D73FAF36BC31ECFA268359046F4EB879F92"+
+		"4009438B481C6CD7889A002ED5EE382BC9190DA6FC026E479558E4475677E9AA"+
+		"9E3050E2765694DFC81F56E880B96E7160C980DD98EDD3DFFFFFFFFFFFFFFFFF", 16)
+	modp8192.G, _ = new(big.Int).SetString("2", 16)
+	modp8192.Q = safePrimeSubgroupOrder(modp8192.P)
+}
+
+func initFfdhe2048() {
+	// See RFC 7919, appendix A.1
+	ffdhe2048 = &GroupParams{
+		Name:    "FFDHE-2048",
+		BitSize: 2048,
+	}
+	ffdhe2048.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1D8B9C583CE2D3695"+
+		"A9E13641146433FBCC939DCE249B3EF97D2FE363630C75D8F681B202AEC4617A"+
+		"D3DF1ED5D5FD65612433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+		"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE73530ACCA4F483A797A"+
+		"BC0AB182B324FB61D108A94BB2C8E3FBB96ADAB760D7F4681D4F42A3DE394DF4"+
+		"AE56EDE76372BB190B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+		"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD733BB5FCBC2EC22005"+
+		"C58EF1837D1683B2C6F34A26C1B2EFFA886B423861285C97FFFFFFFFFFFFFFFF", 16)
+	ffdhe2048.G, _ = new(big.Int).SetString("2", 16)
+	ffdhe2048.Q = safePrimeSubgroupOrder(ffdhe2048.P)
+}
+
+func initFfdhe3072() {
+	// See RFC 7919, appendix A.2
+	ffdhe3072 = &GroupParams{
+		Name:    "FFDHE-3072",
+		BitSize: 3072,
+	}
+	ffdhe3072.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1D8B9C583CE2D3695"+
+		"A9E13641146433FBCC939DCE249B3EF97D2FE363630C75D8F681B202AEC4617A"+
+		"D3DF1ED5D5FD65612433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+		"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE73530ACCA4F483A797A"+
+		"BC0AB182B324FB61D108A94BB2C8E3FBB96ADAB760D7F4681D4F42A3DE394DF4"+
+		"AE56EDE76372BB190B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+		"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD733BB5FCBC2EC22005"+
+		"C58EF1837D1683B2C6F34A26C1B2EFFA886B4238611FCFDCDE355B3B6519035B"+
+		"BC34F4DEF99C023861B46FC9D6E6C9077AD91D2691F7F7EE598CB0FAC186D91C"+
+		"AEFE130985139270B4130C93BC437944F4FD4452E2D74DD364F2E21E71F54BFF"+
+		"5CAE82AB9C9DF69EE86D2BC522363A0DABC521979B0DEADA1DBF9A42D5C4484E"+
+		"0ABCD06BFA53DDEF3C1B20EE3FD59D7C25E41D2B66C62E37FFFFFFFFFFFFFFFF", 16)
+	ffdhe3072.G, _ = new(big.Int).SetString("2", 16)
+	ffdhe3072.Q = safePrimeSubgroupOrder(ffdhe3072.P)
+}
+
+func initFfdhe4096() {
+	// See RFC 7919, appendix A.3
+	ffdhe4096 = &GroupParams{
+		Name:    "FFDHE-4096",
+		BitSize: 4096,
+	}
+	ffdhe4096.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1D8B9C583CE2D3695"+
+		"A9E13641146433FBCC939DCE249B3EF97D2FE363630C75D8F681B202AEC4617A"+
+		"D3DF1ED5D5FD65612433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+		"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE73530ACCA4F483A797A"+
+		"BC0AB182B324FB61D108A94BB2C8E3FBB96ADAB760D7F4681D4F42A3DE394DF4"+
+		"AE56EDE76372BB190B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+		"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD733BB5FCBC2EC22005"+
+		"C58EF1837D1683B2C6F34A26C1B2EFFA886B4238611FCFDCDE355B3B6519035B"+
+		"BC34F4DEF99C023861B46FC9D6E6C9077AD91D2691F7F7EE598CB0FAC186D91C"+
+		"AEFE130985139270B4130C93BC437944F4FD4452E2D74DD364F2E21E71F54BFF"+
+		"5CAE82AB9C9DF69EE86D2BC522363A0DABC521979B0DEADA1DBF9A42D5C4484E"+
+		"0ABCD06BFA53DDEF3C1B20EE3FD59D7C25E41D2B669E1EF16E6F52C3164DF4FB"+
+		"7930E9E4E58857B6AC7D5F42D69F6D187763CF1D5503400487F55BA57E31CC7A"+
+		"7135C886EFB4318AED6A1E012D9E6832A907600A918130C46DC778F971AD0038"+
+		"092999A333CB8B7A1A1DB93D7140003C2A4ECEA9F98D0ACC0A8291CDCEC97DCF"+
+		"8EC9B55A7F88A46B4DB5A851F44182E1C68A007E5E655F6AFFFFFFFFFFFFFFFF", 16)
+	ffdhe4096.G, _ = new(big.Int).SetString("2", 16)
+	ffdhe4096.Q = safePrimeSubgroupOrder(ffdhe4096.P)
+}
+
+func initFfdhe6144() {
+	// See RFC 7919, appendix A.4
+	ffdhe6144 = &GroupParams{
+		Name:    "FFDHE-6144",
+		BitSize: 6144,
+	}
+	ffdhe6144.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1D8B9C583CE2D3695"+
+		"A9E13641146433FBCC939DCE249B3EF97D2FE363630C75D8F681B202AEC4617A"+
+		"D3DF1ED5D5FD65612433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+		"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE73530ACCA4F483A797A"+
+		"BC0AB182B324FB61D108A94BB2C8E3FBB96ADAB760D7F4681D4F42A3DE394DF4"+
+		"AE56EDE76372BB190B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+		"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD733BB5FCBC2EC22005"+
+		"C58EF1837D1683B2C6F34A26C1B2EFFA886B4238611FCFDCDE355B3B6519035B"+
+		"BC34F4DEF99C023861B46FC9D6E6C9077AD91D2691F7F7EE598CB0FAC186D91C"+
+		"AEFE130985139270B4130C93BC437944F4FD4452E2D74DD364F2E21E71F54BFF"+
+		"5CAE82AB9C9DF69EE86D2BC522363A0DABC521979B0DEADA1DBF9A42D5C4484E"+
+		"0ABCD06BFA53DDEF3C1B20EE3FD59D7C25E41D2B669E1EF16E6F52C3164DF4FB"+
+		"7930E9E4E58857B6AC7D5F42D69F6D187763CF1D5503400487F55BA57E31CC7A"+
+		"7135C886EFB4318AED6A1E012D9E6832A907600A918130C46DC778F971AD0038"+
+		"092999A333CB8B7A1A1DB93D7140003C2A4ECEA9F98D0ACC0A8291CDCEC97DCF"+
+		"8EC9B55A7F88A46B4DB5A851F44182E1C68A007E5E0DD9020BFD64B645036C7A"+
+		"4E677D2C38532A3A23BA4442CAF53EA63BB454329B7624C8917BDD64B1C0FD4C"+
+		"B38E8C334C701C3ACDAD0657FCCFEC719B1F5C3E4E46041F388147FB4CFDB477"+
+		"A52471F7A9A96910B855322EDB6340D8A00EF092350511E30ABEC1FFF9E3A26E"+
+		"7FB29F8C183023C3587E38DA0077D9B4763E4E4B94B2BBC194C6651E77CAF992"+
+		"EEAAC0232A281BF6B3A739C1226116820AE8DB5847A67CBEF9C9091B462D538C"+
+		"D72B03746AE77F5E62292C311562A846505DC82DB854338AE49F5235C95B9117"+
+		"8CCF2DD5CACEF403EC9D1810C6272B045B3B71F9DC6B80D63FDD4A8E9ADB1E69"+
+		"62A69526D43161C1A41D570D7938DAD4A40E329CD0E40E65FFFFFFFFFFFFFFFF", 16)
+	ffdhe6144.G, _ = new(big.Int).SetString("2", 16)
+	ffdhe6144.Q = safePrimeSubgroupOrder(ffdhe6144.P)
+}
+
+func initFfdhe8192() {
+	// See RFC 7919, appendix A.5
+	ffdhe8192 = &GroupParams{
+		Name:    "FFDHE-8192",
+		BitSize: 8192,
+	}
+	ffdhe8192.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1D8B9C583CE2D3695"+
+		"A9E13641146433FBCC939DCE249B3EF97D2FE363630C75D8F681B202AEC4617A"+
+		"D3DF1ED5D5FD65612433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+		"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE73530ACCA4F483A797A"+
+		"BC0AB182B324FB61D108A94BB2C8E3FBB96ADAB760D7F4681D4F42A3DE394DF4"+
+		"AE56EDE76372BB190B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+		"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD733BB5FCBC2EC22005"+
+		"C58EF1837D1683B2C6F34A26C1B2EFFA886B4238611FCFDCDE355B3B6519035B"+
+		"BC34F4DEF99C023861B46FC9D6E6C9077AD91D2691F7F7EE598CB0FAC186D91C"+
+		"AEFE130985139270B4130C93BC437944F4FD4452E2D74DD364F2E21E71F54BFF"+
+		"5CAE82AB9C9DF69EE86D2BC522363A0DABC521979B0DEADA1DBF9A42D5C4484E"+
+		"0ABCD06BFA53DDEF3C1B20EE3FD59D7C25E41D2B669E1EF16E6F52C3164DF4FB"+
+		"7930E9E4E58857B6AC7D5F42D69F6D187763CF1D5503400487F55BA57E31CC7A"+
+		"7135C886EFB4318AED6A1E012D9E6832A907600A918130C46DC778F971AD0038"+
+		"092999A333CB8B7A1A1DB93D7140003C2A4ECEA9F98D0ACC0A8291CDCEC97DCF"+
+		"8EC9B55A7F88A46B4DB5A851F44182E1C68A007E5E0DD9020BFD64B645036C7A"+
+		"4E677D2C38532A3A23BA4442CAF53EA63BB454329B7624C8917BDD64B1C0FD4C"+
+		"B38E8C334C701C3ACDAD0657FCCFEC719B1F5C3E4E46041F388147FB4CFDB477"+
+		"A52471F7A9A96910B855322EDB6340D8A00EF092350511E30ABEC1FFF9E3A26E"+
+		"7FB29F8C183023C3587E38DA0077D9B4763E4E4B94B2BBC194C6651E77CAF992"+
+		"EEAAC0232A281BF6B3A739C1226116820AE8DB5847A67CBEF9C9091B462D538C"+
+		"D72B03746AE77F5E62292C311562A846505DC82DB854338AE49F5235C95B9117"+
+		"8CCF2DD5CACEF403EC9D1810C6272B045B3B71F9DC6B80D63FDD4A8E9ADB1E69"+
+		"62A69526D43161C1A41D570D7938DAD4A40E329CCFF46AAA36AD004CF600C838"+
+		"1E425A31D951AE64FDB23FCEC9509D43687FEB69EDD1CC5E0B8CC3BDF64B10EF"+
+		"86B63142A3AB8829555B2F747C932665CB2C0F1CC01BD70229388839D2AF05E4"+
+		"54504AC78B7582822846C0BA35C35F5C59160CC046FD8251541FC68C9C86B022"+
+		"BB7099876A460E7451A8A93109703FEE1C217E6C3826E52C51AA691E0E423CFC"+
+		"99E9E31650C1217B624816CDAD9A95F9D5B8019488D9C0A0A1FE3075A577E231"+
+		"83F81D4A3F2FA4571EFC8CE0BA8A4FE8B6855DFE72B0A66EDED2FBABFBE58A30"+
+		"FAFABE1C5D71A87E2F741EF8C1FE86FEA6BBFDE530677F0D97D11D49F7A8443D"+
+		"0822E506A9F4614E011E2A94838FF88CD68C8BB7C5C6424CFFFFFFFFFFFFFFFF", 16)
+	ffdhe8192.G, _ = new(big.Int).SetString("2", 16)
+	ffdhe8192.Q = safePrimeSubgroupOrder(ffdhe8192.P)
+}
+
+func initRfc5114p1024q160() {
+	// See RFC 5114, section 2.1
+	rfc5114p1024q160 = &GroupParams{
+		Name:    "RFC5114-1024-160",
+		BitSize: 1024,
+	}
+	rfc5114p1024q160.P, _ = new(big.Int).SetString("B10B8F96A080E01DDE92DE5EAE5D54EC52C99FBCFB06A3C69A6A9DCA52D23B61"+
+		"6073E28675A23D189838EF1E2EE652C013ECB4AEA906112324975C3CD49B83BF"+
+		"ACCBDD7D90C4BD7098488E9C219A73724EFFD6FAE5644738FAA31A4FF55BCCC0"+
+		"A151AF5F0DC8B4BD45BF37DF365C1A65E68CFDA76D4DA708DF1FB2BC2E4A4371", 16)
+	rfc5114p1024q160.G, _ = new(big.Int).SetString("A4D1CBD5C3FD34126765A442EFB99905F8104DD258AC507FD6406CFF14266D31"+
+		"266FEA1E5C41564B777E690F5504F213160217B4B01B886A5E91547F9E2749F4"+
+		"D7FBD7D3B9A92EE1909D0D2263F80A76A6A24C087A091F531DBF0A0169B6A28A"+
+		"D662A4D18E73AFA32D779D5918D08BC8858F4DCEF97C2A24855E6EEB22B3B2E5", 16)
+	rfc5114p1024q160.Q, _ = new(big.Int).SetString("F518AA8781A8DF278ABA4E7D64B7CB9D49462353", 16)
+}
+
+func initRfc5114p2048q224() {
+	// See RFC 5114, section 2.2
+	rfc5114p2048q224 = &GroupParams{
+		Name:    "RFC5114-2048-224",
+		BitSize: 2048,
+	}
+	rfc5114p2048q224.P, _ = new(big.Int).SetString("AD107E1E9123A9D0D660FAA79559C51FA20D64E5683B9FD1B54B1597B61D0A75"+
+		"E6FA141DF95A56DBAF9A3C407BA1DF15EB3D688A309C180E1DE6B85A1274A0A6"+
+		"6D3F8152AD6AC2129037C9EDEFDA4DF8D91E8FEF55B7394B7AD5B7D0B6C12207"+
+		"C9F98D11ED34DBF6C6BA0B2C8BBC27BE6A00E0A0B9C49708B3BF8A3170918836"+
+		"81286130BC8985DB1602E714415D9330278273C7DE31EFDC7310F7121FD5A074"+
+		"15987D9ADC0A486DCDF93ACC44328387315D75E198C641A480CD86A1B9E587E8"+
+		"BE60E69CC928B2B9C52172E413042E9B23F10B0E16E79763C9B53DCF4BA80A29"+
+		"E3FB73C16B8E75B97EF363E2FFA31F71CF9DE5384E71B81C0AC4DFFE0C10E64F", 16)
+	rfc5114p2048q224.G, _ = new(big.Int).SetString("AC4032EF4F2D9AE39DF30B5C8FFDAC506CDEBE7B89998CAF74866A08CFE4FFE3"+
+		"A6824A4E10B9A6F0DD921F01A70C4AFAAB739D7700C29F52C57DB17C620A8652"+
+		"BE5E9001A8D66AD7C17669101999024AF4D027275AC1348BB8A762D0521BC98A"+
+		"E247150422EA1ED409939D54DA7460CDB5F6C6B250717CBEF180EB34118E98D1"+
+		"19529A45D6F834566E3025E316A330EFBB77A86F0C1AB15B051AE3D428C8F8AC"+
+		"B70A8137150B8EEB10E183EDD19963DDD9E263E4770589EF6AA21E7F5F2FF381"+
+		"B539CCE3409D13CD566AFBB48D6C019181E1BCFE94B30269EDFE72FE9B6AA4BD"+
+		"7B5A0F1C71CFFF4C19C418E1F6EC017981BC087F2A7065B384B890D3191F2BFA", 16)
+	rfc5114p2048q224.Q, _ = new(big.Int).SetString("801C0D34C58D93FE997177101F80535A4738CEBCBF389A99B36371EB", 16)
+}
+
+func initRfc5114p2048q256() {
+	// See RFC 5114, section 2.3
+	rfc5114p2048q256 = &GroupParams{
+		Name:    "RFC5114-2048-256",
+		BitSize: 2048,
+	}
+	rfc5114p2048q256.P, _ = new(big.Int).SetString("87A8E61DB4B6663CFFBBD19C651959998CEEF608660DD0F25D2CEED4435E3B00"+
+		"E00DF8F1D61957D4FAF7DF4561B2AA3016C3D91134096FAA3BF4296D830E9A7C"+
+		"209E0C6497517ABD5A8A9D306BCF67ED91F9E6725B4758C022E0B1EF4275BF7B"+
+		"6C5BFC11D45F9088B941F54EB1E59BB8BC39A0BF12307F5C4FDB70C581B23F76"+
+		"B63ACAE1CAA6B7902D52526735488A0EF13C6D9A51BFA4AB3AD8347796524D8E"+
+		"F6A167B5A41825D967E144E5140564251CCACB83E6B486F6B3CA3F7971506026"+
+		"C0B857F689962856DED4010ABD0BE621C3A3960A54E710C375F26375D7014103"+
+		"A4B54330C198AF126116D2276E11715F693877FAD7EF09CADB094AE91E1A1597", 16)
+	rfc5114p2048q256.G, _ = new(big.Int).SetString("3FB32C9B73134D0B2E77506660EDBD484CA7B18F21EF205407F4793A1A0BA125"+
+		"10DBC15077BE463FFF4FED4AAC0BB555BE3A6C1B0C6B47B1BC3773BF7E8C6F62"+
+		"901228F8C28CBB18A55AE31341000A650196F931C77A57F2DDF463E5E9EC144B"+
+		"777DE62AAAB8A8628AC376D282D6ED3864E67982428EBC831D14348F6F2F9193"+
+		"B5045AF2767164E1DFC967C1FB3F2E55A4BD1BFFE83B9C80D052B985D182EA0A"+
+		"DB2A3B7313D3FE14C8484B1E052588B9B7D2BBD2DF016199ECD06E1557CD0915"+
+		"B3353BBB64E0EC377FD028370DF92B52C7891428CDC67EB6184B523D1DB246C3"+
+		"2F63078490F00EF8D647D148D47954515E2327CFEF98C582664B4C0F6CC41659", 16)
+	rfc5114p2048q256.Q, _ = new(big.Int).SetString("8CF83642A709A097B447997640129DA299B1A47D1EB3750BA308B0FE64F5FBD3", 16)
+}
+
+// MODP1536 returns a DHScheme which implements the 1536-bit MODP group from
+// RFC 3526. P is a safe prime, so the only small subgroup has order 2.
+func MODP1536() DHScheme {
+	initonce.Do(initAll)
+	return modp1536
+}
+
+// MODP2048 returns a DHScheme which implements the 2048-bit MODP group from
+// RFC 3526. P is a safe prime, so the only small subgroup has order 2.
+func MODP2048() DHScheme {
+	initonce.Do(initAll)
+	return modp2048
+}
+
+// MODP3072 returns a DHScheme which implements the 3072-bit MODP group from
+// RFC 3526. P is a safe prime, so the only small subgroup has order 2.
+func MODP3072() DHScheme {
+	initonce.Do(initAll)
+	return modp3072
+}
+
+// MODP4096 returns a DHScheme which implements the 4096-bit MODP group from
+// RFC 3526. P is a safe prime, so the only small subgroup has order 2.
+func MODP4096() DHScheme {
+	initonce.Do(initAll)
+	return modp4096
+}
+
+// MODP6144 returns a DHScheme which implements the 6144-bit MODP group from
+// RFC 3526. P is a safe prime, so the only small subgroup has order 2.
+func MODP6144() DHScheme {
+	initonce.Do(initAll)
+	return modp6144
+}
+
+// MODP8192 returns a DHScheme which implements the 8192-bit MODP group from
+// RFC 3526. P is a safe prime, so the only small subgroup has order 2.
+func MODP8192() DHScheme {
+	initonce.Do(initAll)
+	return modp8192
+}
+
+// FFDHE2048 returns a DHScheme which implements the ffdhe2048 group from
+// RFC 7919. P is a safe prime, so the only small subgroup has order 2.
+func FFDHE2048() DHScheme {
+	initonce.Do(initAll)
+	return ffdhe2048
+}
+
+// FFDHE3072 returns a DHScheme which implements the ffdhe3072 group from
+// RFC 7919. P is a safe prime, so the only small subgroup has order 2.
+func FFDHE3072() DHScheme {
+	initonce.Do(initAll)
+	return ffdhe3072
+}
+
+// FFDHE4096 returns a DHScheme which implements the ffdhe4096 group from
+// RFC 7919. P is a safe prime, so the only small subgroup has order 2.
+func FFDHE4096() DHScheme {
+	initonce.Do(initAll)
+	return ffdhe4096
+}
+
+// FFDHE6144 returns a DHScheme which implements the ffdhe6144 group from
+// RFC 7919. P is a safe prime, so the only small subgroup has order 2.
+func FFDHE6144() DHScheme {
+	initonce.Do(initAll)
+	return ffdhe6144
+}
+
+// FFDHE8192 returns a DHScheme which implements the ffdhe8192 group from
+// RFC 7919. P is a safe prime, so the only small subgroup has order 2.
+func FFDHE8192() DHScheme {
+	initonce.Do(initAll)
+	return ffdhe8192
+}
+
+// RFC5114P1024Q160 returns a DHScheme which implements the 1024-bit MODP group
+// with a 160-bit prime order subgroup from RFC 5114, section 2.1. (P-1)/Q has
+// small factors, so the group is open to small subgroup confinement attacks
+// unless public keys are validated.
+func RFC5114P1024Q160() DHScheme {
+	initonce.Do(initAll)
+	return rfc5114p1024q160
+}
+
+// RFC5114P2048Q224 returns a DHScheme which implements the 2048-bit MODP group
+// with a 224-bit prime order subgroup from RFC 5114, section 2.2. (P-1)/Q has
+// small factors, so the group is open to small subgroup confinement attacks
+// unless public keys are validated.
+func RFC5114P2048Q224() DHScheme {
+	initonce.Do(initAll)
+	return rfc5114p2048q224
+}
+
+// RFC5114P2048Q256 returns a DHScheme which implements the 2048-bit MODP group
+// with a 256-bit prime order subgroup from RFC 5114, section 2.3. (P-1)/Q has
+// small factors, so the group is open to small subgroup confinement attacks
+// unless public keys are validated.
+func RFC5114P2048Q256() DHScheme {
+	initonce.Do(initAll)
+	return rfc5114p2048q256
+}
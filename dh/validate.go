@@ -0,0 +1,98 @@
+package dh
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+// ValidationPolicy defines how a peer's public key is checked before it is
+// used in a DH calculation.
+type ValidationPolicy int
+
+const (
+	// NoValidation uses any public key as is.
+	NoValidation ValidationPolicy = iota
+
+	// RangeCheck rejects public keys outside of 1 < y < p-1.
+	RangeCheck
+
+	// SubgroupCheck rejects public keys which fail the range check or aren't
+	// in the subgroup of order q, i.e. y^q != 1.
+	SubgroupCheck
+
+	// CofactorClearing raises public keys which pass the range check to the
+	// cofactor (p-1)/q and rejects them if the result is 1. The shared secret
+	// is computed with the cleared key, so it's y^((p-1)/q * x) rather than
+	// y^x.
+	CofactorClearing
+)
+
+// ErrInvalidPublicKey is returned when a public key is rejected by
+// a validation policy.
+var ErrInvalidPublicKey = errors.New("dh: invalid public key")
+
+func (v ValidationPolicy) String() string {
+	switch v {
+	case NoValidation:
+		return "none"
+	case RangeCheck:
+		return "range"
+	case SubgroupCheck:
+		return "subgroup"
+	case CofactorClearing:
+		return "cofactor"
+	default:
+		return fmt.Sprintf("ValidationPolicy(%d)", int(v))
+	}
+}
+
+// ParseValidationPolicy returns the policy with the given name, as returned
+// by ValidationPolicy.String.
+func ParseValidationPolicy(name string) (ValidationPolicy, error) {
+	for _, v := range []ValidationPolicy{NoValidation, RangeCheck, SubgroupCheck, CofactorClearing} {
+		if v.String() == name {
+			return v, nil
+		}
+	}
+
+	return NoValidation, fmt.Errorf("dh: unknown validation policy %q", name)
+}
+
+func (g GroupParams) ValidatedDH(private, public *big.Int, policy ValidationPolicy) (*big.Int, error) {
+	if policy == NoValidation {
+		return g.DH(private, public), nil
+	}
+
+	// 1 < y < p-1
+	pMinusOne := new(big.Int).Sub(g.P, helpers.BigOne)
+	if public.Cmp(helpers.BigOne) <= 0 || public.Cmp(pMinusOne) >= 0 {
+		return nil, fmt.Errorf("%w: out of range", ErrInvalidPublicKey)
+	}
+
+	switch policy {
+	case RangeCheck:
+		return g.DH(private, public), nil
+
+	case SubgroupCheck:
+		// y^q == 1
+		if new(big.Int).Exp(public, g.Q, g.P).Cmp(helpers.BigOne) != 0 {
+			return nil, fmt.Errorf("%w: not in the subgroup of order q", ErrInvalidPublicKey)
+		}
+		return g.DH(private, public), nil
+
+	case CofactorClearing:
+		// y' = y^((p-1)/q) != 1
+		j := new(big.Int).Div(pMinusOne, g.Q)
+		cleared := new(big.Int).Exp(public, j, g.P)
+		if cleared.Cmp(helpers.BigOne) == 0 {
+			return nil, fmt.Errorf("%w: in a small subgroup", ErrInvalidPublicKey)
+		}
+		return g.DH(private, cleared), nil
+
+	default:
+		return nil, fmt.Errorf("dh: unknown validation policy %s", policy)
+	}
+}
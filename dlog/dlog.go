@@ -0,0 +1,267 @@
+// Package dlog finds discrete logarithms on elliptic curves which are known
+// to lie in an interval, e.g. the rest of a private key recovered modulo r.
+package dlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/kangaroo"
+	"github.com/svkirillov/cryptopals-go/progress"
+)
+
+// jumpsPerEvent is the number of jumps between the progress reports of
+// a kangaroo.
+const jumpsPerEvent = 1 << 12
+
+// ErrNotFound is returned if the logarithm isn't found in the interval.
+var ErrNotFound = errors.New("dlog: no logarithm in the interval")
+
+// Problem is finding x in [A, B] such that x*(Gx, Gy) = (X, Y) on Curve.
+type Problem struct {
+	Curve  elliptic.Curve
+	Gx, Gy *big.Int
+	X, Y   *big.Int
+	A, B   *big.Int
+}
+
+func (p *Problem) check() error {
+	if p.A.Sign() < 0 || p.A.Cmp(p.B) > 0 {
+		return fmt.Errorf("dlog: bad interval [%d, %d]", p.A, p.B)
+	}
+	return nil
+}
+
+// Trap is where the tame kangaroo ended up after jumping Distance from
+// B*(Gx, Gy).
+type Trap struct {
+	Distance *big.Int
+	X, Y     *big.Int
+	Jumps    kangaroo.Jumps
+}
+
+func reportJumps(events progress.Reporter, kangaroo string, jumps uint64, distance *big.Int) {
+	events.Report(progress.Event{
+		Kind:     progress.KangarooJumps,
+		Kangaroo: kangaroo,
+		Jumps:    jumps,
+		Distance: new(big.Int).Set(distance),
+	})
+}
+
+// Tame runs the tame kangaroo of the problem, or returns nil once ctx is
+// done. The trap doesn't depend on the target, so it's good for every
+// target with the same base and interval. The jumps are reported to events,
+// which may be nil.
+func (p *Problem) Tame(ctx context.Context, params *kangaroo.Params, events progress.Reporter) *Trap {
+	events = progress.OrDiscard(events)
+
+	// xT := 0
+	// xyT, yyT := b * base
+	xT := new(big.Int).Set(helpers.BigZero)
+	xyT, yyT := p.Curve.ScalarMult(p.Gx, p.Gy, p.B.Bytes())
+
+	// for i in 1..N:
+	for i := new(big.Int).Set(helpers.BigZero); i.Cmp(params.N) < 0; i.Add(i, helpers.BigOne) {
+		fVal := params.Jumps.Jump(xyT)
+
+		// xT := xT + f(xyT)
+		xT.Add(xT, fVal)
+
+		// xyT, yyT := (xyT, yyT) + (base * f(xyT))
+		tmpX, tmpY := p.Curve.ScalarMult(p.Gx, p.Gy, fVal.Bytes())
+		xyT, yyT = p.Curve.Add(xyT, yyT, tmpX, tmpY)
+
+		if jumps := i.Uint64() + 1; jumps%jumpsPerEvent == 0 {
+			reportJumps(events, "tame", jumps, xT)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			// pass
+		}
+	}
+	reportJumps(events, "tame", params.N.Uint64(), xT)
+
+	return &Trap{Distance: xT, X: xyT, Y: yyT, Jumps: params.Jumps}
+}
+
+// Wild runs the wild kangaroo from the target and returns the logarithm if
+// he falls into the trap, or nil if he escapes or ctx is done. A kangaroo
+// starting a little above B may fall into the trap as well, so the
+// logarithm may be above the interval. The jumps are reported to events,
+// which may be nil.
+func (p *Problem) Wild(ctx context.Context, trap *Trap, events progress.Reporter) *big.Int {
+	events = progress.OrDiscard(events)
+
+	// xW := 0
+	// xyW, yyW := x, y
+	xW := new(big.Int).Set(helpers.BigZero)
+	xyW := new(big.Int).Set(p.X)
+	yyW := new(big.Int).Set(p.Y)
+
+	tmp := new(big.Int)
+
+	tmp.Sub(p.B, p.A).Add(tmp, trap.Distance)
+	xWUpperBound := new(big.Int).Set(tmp) // xWUpperBound := b - a + xT
+
+	var n uint64
+
+	// while xW < b - a + xT:
+	for xW.Cmp(xWUpperBound) < 0 {
+		fVal := trap.Jumps.Jump(xyW)
+
+		// xW := xW + f(xyW)
+		xW.Add(xW, fVal)
+
+		// xyW, yyW := (xyW, yyW) + (base * f(xyW))
+		tmpX, tmpY := p.Curve.ScalarMult(p.Gx, p.Gy, fVal.Bytes())
+		xyW, yyW = p.Curve.Add(xyW, yyW, tmpX, tmpY)
+
+		// if yW = yT:
+		if xyW.Cmp(trap.X) == 0 && yyW.Cmp(trap.Y) == 0 {
+			// b + xT - xW
+			tmp.Add(p.B, trap.Distance).Sub(tmp, xW)
+			reportJumps(events, "wild", n+1, xW)
+			return tmp
+		}
+
+		if n++; n%jumpsPerEvent == 0 {
+			reportJumps(events, "wild", n, xW)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			// pass
+		}
+	}
+
+	return nil
+}
+
+// Kangaroo solves the problem with Pollard's method for catching kangaroos
+// in O(sqrt(B-A)) time, running them according to cfg, which may be nil.
+// Unlike Wild, it only returns a logarithm in the interval. It returns
+// ErrNotFound if the wild kangaroo escapes or lands outside the interval
+// every time, which may also happen if the logarithm is in the interval, and
+// ctx.Err() once ctx is done. The jumps are reported to events, which may be
+// nil.
+func (p *Problem) Kangaroo(ctx context.Context, cfg *kangaroo.Config, events progress.Reporter) (*big.Int, error) {
+	cfg = kangaroo.OrDefault(cfg)
+
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	if p.A.Cmp(p.B) == 0 {
+		return p.BSGS(ctx)
+	}
+
+	for attempt := 0; attempt < cfg.MaxAttempts(); attempt++ {
+		params, err := cfg.Params(p.A, p.B, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		trap := p.Tame(ctx, params, events)
+		if trap == nil {
+			return nil, ctx.Err()
+		}
+
+		if x := p.Wild(ctx, trap, events); x != nil && p.solves(x) {
+			return x, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// solves reduces x modulo the order of the curve and reports whether it's
+// in the interval and x*(Gx, Gy) = (X, Y).
+func (p *Problem) solves(x *big.Int) bool {
+	if n := p.Curve.Params().N; n != nil && n.Sign() > 0 {
+		x.Mod(x, n)
+	}
+	if x.Cmp(p.A) < 0 || x.Cmp(p.B) > 0 {
+		return false
+	}
+
+	px, py := p.Curve.ScalarMult(p.Gx, p.Gy, x.Bytes())
+	return px.Cmp(p.X) == 0 && py.Cmp(p.Y) == 0
+}
+
+// pointKey is the key of a point in the table of the baby steps.
+func pointKey(x, y *big.Int) string {
+	return x.Text(16) + "," + y.Text(16)
+}
+
+// BSGS solves the problem with Shanks' baby-step giant-step algorithm in
+// O(sqrt(B-A)) time and memory. Unlike Kangaroo, it always finds the
+// logarithm if it's in the interval, and returns ErrNotFound otherwise. It
+// stops with ctx.Err() once ctx is done.
+func (p *Problem) BSGS(ctx context.Context) (*big.Int, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+
+	// x = a + i*m + j with 0 <= i, j < m, m = ceil(sqrt(b - a + 1))
+	width := new(big.Int).Sub(p.B, p.A)
+	width.Add(width, helpers.BigOne)
+	m := new(big.Int).Sqrt(width)
+	if new(big.Int).Mul(m, m).Cmp(width) < 0 {
+		m.Add(m, helpers.BigOne)
+	}
+	if !m.IsInt64() {
+		return nil, fmt.Errorf("dlog: interval [%d, %d] is too large for baby steps", p.A, p.B)
+	}
+
+	// baby steps: j*G for j in [0, m)
+	babySteps := make(map[string]int64, m.Int64())
+	x, y := new(big.Int), new(big.Int)
+	for j := int64(0); j < m.Int64(); j++ {
+		if _, ok := babySteps[pointKey(x, y)]; !ok {
+			babySteps[pointKey(x, y)] = j
+		}
+		x, y = p.Curve.Add(x, y, p.Gx, p.Gy)
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	// giant steps: (X, Y) - a*G - i*m*G for i in [0, m)
+	stepX, stepY := p.Curve.ScalarMult(p.Gx, p.Gy, m.Bytes())
+	stepX, stepY = elliptic.Inverse(p.Curve, stepX, stepY)
+
+	x, y = p.Curve.ScalarMult(p.Gx, p.Gy, p.A.Bytes())
+	x, y = elliptic.Inverse(p.Curve, x, y)
+	x, y = p.Curve.Add(p.X, p.Y, x, y)
+
+	for i := int64(0); i < m.Int64(); i++ {
+		if j, ok := babySteps[pointKey(x, y)]; ok {
+			// a + i*m + j
+			k := new(big.Int).Mul(big.NewInt(i), m)
+			k.Add(k, big.NewInt(j)).Add(k, p.A)
+			if k.Cmp(p.B) <= 0 {
+				return k, nil
+			}
+		}
+		x, y = p.Curve.Add(x, y, stepX, stepY)
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, ErrNotFound
+}
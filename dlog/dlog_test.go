@@ -0,0 +1,128 @@
+package dlog
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/kangaroo"
+)
+
+// intervals are sub-intervals [a, a + width] of the curves' orders with the
+// logarithm x somewhere inside.
+var intervals = []struct {
+	curve    elliptic.Curve
+	a, x     string
+	widthLog uint
+}{
+	{elliptic.P48(), "100000000000", "100000123456", 24},
+	{elliptic.P48(), "0", "16777215", 24},
+	{elliptic.P128(), "1000000000000000000000000000000", "1000000000000000000000002718281", 26},
+	{elliptic.P224(), "12345678901234567890123456789012345678901234567890", "12345678901234567890123456789012345678901234771828", 24},
+}
+
+func problems(t *testing.T) []*Problem {
+	var res []*Problem
+
+	for _, e := range intervals {
+		params := e.curve.Params()
+
+		a := helpers.SetBigIntFromDec(e.a)
+		x := helpers.SetBigIntFromDec(e.x)
+		b := new(big.Int).Lsh(helpers.BigOne, e.widthLog)
+		b.Add(b, a)
+
+		if x.Cmp(a) < 0 || x.Cmp(b) > 0 || b.Cmp(params.N) >= 0 {
+			t.Fatalf("%s: bad test interval on %s", t.Name(), params.Name)
+		}
+
+		px, py := e.curve.ScalarBaseMult(x.Bytes())
+		res = append(res, &Problem{Curve: e.curve, Gx: params.Gx, Gy: params.Gy, X: px, Y: py, A: a, B: b})
+	}
+
+	return res
+}
+
+func TestKangaroo(t *testing.T) {
+	for i, p := range problems(t) {
+		x, err := p.Kangaroo(context.Background(), nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", t.Name(), p.Curve.Params().Name, err.Error())
+		}
+		if want := helpers.SetBigIntFromDec(intervals[i].x); x.Cmp(want) != 0 {
+			t.Errorf("%s: %s: got %d, want %d", t.Name(), p.Curve.Params().Name, x, want)
+		}
+	}
+}
+
+func TestKangarooWithRandomTable(t *testing.T) {
+	cfg := &kangaroo.Config{Jumps: kangaroo.RandomTable(helpers.NewSeededReader(47))}
+
+	for i, p := range problems(t) {
+		x, err := p.Kangaroo(context.Background(), cfg, nil)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", t.Name(), p.Curve.Params().Name, err.Error())
+		}
+		if want := helpers.SetBigIntFromDec(intervals[i].x); x.Cmp(want) != 0 {
+			t.Errorf("%s: %s: got %d, want %d", t.Name(), p.Curve.Params().Name, x, want)
+		}
+	}
+}
+
+func TestBSGS(t *testing.T) {
+	for i, p := range problems(t) {
+		x, err := p.BSGS(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %s: %s", t.Name(), p.Curve.Params().Name, err.Error())
+		}
+		if want := helpers.SetBigIntFromDec(intervals[i].x); x.Cmp(want) != 0 {
+			t.Errorf("%s: %s: got %d, want %d", t.Name(), p.Curve.Params().Name, x, want)
+		}
+	}
+
+	// the ends of the interval
+	p := problems(t)[0]
+	for _, x := range []*big.Int{p.A, p.B} {
+		p.X, p.Y = p.Curve.ScalarBaseMult(x.Bytes())
+		if got, err := p.BSGS(context.Background()); err != nil || got.Cmp(x) != 0 {
+			t.Errorf("%s: got %d, %v, want %d", t.Name(), got, err, x)
+		}
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	p := problems(t)[0]
+
+	// the logarithm is just above the interval
+	x := new(big.Int).Add(p.B, helpers.BigOne)
+	p.X, p.Y = p.Curve.ScalarBaseMult(x.Bytes())
+
+	if _, err := p.BSGS(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("%s: BSGS: got error %v, want %v", t.Name(), err, ErrNotFound)
+	}
+
+	// the wild kangaroo lands in the trap from just above the interval, but
+	// his logarithm isn't returned
+	if x, err := p.Kangaroo(context.Background(), nil, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("%s: Kangaroo: got %d, %v, want %v", t.Name(), x, err, ErrNotFound)
+	}
+
+	// the wild kangaroo may land in the trap from just above the interval,
+	// but not from far away
+	x.Lsh(p.B, 1)
+	p.X, p.Y = p.Curve.ScalarBaseMult(x.Bytes())
+
+	if _, err := p.Kangaroo(context.Background(), &kangaroo.Config{Attempts: 1}, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("%s: Kangaroo: got error %v, want %v", t.Name(), err, ErrNotFound)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := p.Kangaroo(ctx, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+}
@@ -0,0 +1,232 @@
+package elliptic
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+const (
+	// naiveCountBits is the largest size of the underlying field for which
+	// the points are counted one by one.
+	naiveCountBits = 16
+
+	// mestreCountBits is the largest size of the underlying field for which
+	// the points are counted with the baby-step giant-step method only.
+	mestreCountBits = 64
+
+	// maxBSGSWidth is the largest number of candidates for the trace of
+	// Frobenius which are left to the baby-step giant-step method after
+	// Schoof's algorithm.
+	maxBSGSWidth = 1 << 36
+
+	// bsgsAttempts is the number of random points tried before giving up.
+	bsgsAttempts = 64
+)
+
+var errSmallOrder = errors.New("elliptic: point has small order")
+
+// CountPoints returns the number of points on the curve, including the point
+// at infinity. For tiny fields the points are counted one by one, for fields
+// up to 64 bits Mestre's baby-step giant-step method is used, and for larger
+// fields Schoof's algorithm narrows the trace of Frobenius down first.
+func CountPoints(curve Curve) (*big.Int, error) {
+	params := curve.Params()
+	p := params.P
+	a := new(big.Int).Mod(params.A, p)
+	b := new(big.Int).Mod(params.B, p)
+
+	// 4a^3 + 27b^2 != 0
+	disc := new(big.Int).Mul(big.NewInt(4), new(big.Int).Exp(a, helpers.BigThree, p))
+	disc.Add(disc, new(big.Int).Mul(big.NewInt(27), new(big.Int).Exp(b, helpers.BigTwo, p))).Mod(disc, p)
+	if disc.Sign() == 0 {
+		return nil, errors.New("elliptic: the curve is singular")
+	}
+
+	switch {
+	case p.BitLen() <= naiveCountBits:
+		return countPointsNaive(a, b, p), nil
+	case p.BitLen() <= mestreCountBits:
+		return countPointsMestre(a, b, p, helpers.BigZero, helpers.BigOne)
+	default:
+		// the trace t lies in [-2*sqrt(p), 2*sqrt(p)]
+		limit := new(big.Int).Sqrt(p)
+		limit.Lsh(limit, 2).Add(limit, helpers.BigTwo)
+		limit.Div(limit, big.NewInt(maxBSGSWidth))
+
+		t, m := schoof(a, b, p, limit)
+
+		return countPointsMestre(a, b, p, t, m)
+	}
+}
+
+// countPointsNaive returns #E = p + 1 + sum of Legendre symbols of x^3 + ax + b.
+func countPointsNaive(a, b, p *big.Int) *big.Int {
+	n := new(big.Int).Add(p, helpers.BigOne)
+
+	rhs := new(big.Int)
+	for x := big.NewInt(0); x.Cmp(p) < 0; x.Add(x, helpers.BigOne) {
+		rhs.Mul(x, x).Add(rhs, a).Mul(rhs, x).Add(rhs, b).Mod(rhs, p)
+		n.Add(n, big.NewInt(int64(big.Jacobi(rhs, p))))
+	}
+
+	return n
+}
+
+// countPointsMestre returns #E = p + 1 - t where the trace t is known to be
+// t0 modulo m. The candidates for t are checked with the baby-step
+// giant-step method on random points of the curve and of its quadratic
+// twist, whose order is p + 1 + t.
+func countPointsMestre(a, b, p, t0, m *big.Int) (*big.Int, error) {
+	curve := &CurveParams{P: p, A: a, B: b, BitSize: p.BitLen()}
+	twist := Twist(curve)
+
+	// t = t0 + m*(jlo + i) for 0 <= i < width covers [-2*sqrt(p), 2*sqrt(p)]
+	bound := new(big.Int).Sqrt(p)
+	bound.Lsh(bound, 1).Add(bound, helpers.BigOne)
+
+	jlo := new(big.Int).Sub(new(big.Int).Neg(bound), t0)
+	jlo.Div(jlo, m)
+	jhi := new(big.Int).Sub(bound, t0)
+	jhi.Div(jhi, m)
+
+	width := new(big.Int).Sub(jhi, jlo)
+	width.Add(width, helpers.BigOne)
+	if width.Cmp(big.NewInt(maxBSGSWidth)) > 0 {
+		return nil, fmt.Errorf("elliptic: %d candidates are too many for baby-step giant-step", width)
+	}
+
+	tlo := new(big.Int).Add(t0, new(big.Int).Mul(m, jlo))
+	p1 := new(big.Int).Add(p, helpers.BigOne)
+
+	var candidates []int64
+
+	for attempt := 0; attempt < bsgsAttempts; attempt++ {
+		var c Curve = curve
+		q := new(big.Int).Sub(p1, tlo)
+		step := new(big.Int).Set(m)
+		if attempt%2 == 1 {
+			// [p + 1 + tlo + m*i]P' = O on the twist
+			c = twist
+			q.Add(p1, tlo)
+			step.Neg(step)
+		}
+
+		// [q - step*i]P = O, i.e. [q]P = [i]([step]P)
+		x, y := GeneratePoint(c, nil)
+		qx, qy := c.ScalarMult(x, y, q.Bytes())
+		rx, ry := c.ScalarMult(x, y, m.Bytes())
+		if step.Sign() < 0 {
+			rx, ry = Inverse(c, rx, ry)
+		}
+
+		matches, err := findMultiples(c, qx, qy, rx, ry, width.Int64())
+		if err == errSmallOrder {
+			continue
+		}
+
+		if candidates == nil {
+			candidates = matches
+		} else {
+			candidates = intersect(candidates, matches)
+		}
+
+		if len(candidates) == 0 {
+			return nil, errors.New("elliptic: no candidates for the number of points")
+		}
+
+		if len(candidates) == 1 {
+			t := new(big.Int).Mul(m, big.NewInt(candidates[0]))
+			t.Add(t, tlo)
+			return t.Sub(p1, t), nil
+		}
+	}
+
+	return nil, errors.New("elliptic: couldn't count points")
+}
+
+// findMultiples returns all 0 <= i < n such that (qx, qy) = [i](rx, ry).
+func findMultiples(curve Curve, qx, qy, rx, ry *big.Int, n int64) ([]int64, error) {
+	m := int64(1)
+	for m*m < n {
+		m++
+	}
+
+	key := func(x, y *big.Int) string {
+		return x.String() + "," + y.String()
+	}
+
+	// baby steps: j*R for 0 <= j < m
+	baby := make(map[string]int64, m)
+	x, y := new(big.Int), new(big.Int)
+	for j := int64(0); j < m; j++ {
+		if j > 0 && x.Sign() == 0 && y.Sign() == 0 {
+			return nil, errSmallOrder
+		}
+		baby[key(x, y)] = j
+		x, y = curve.Add(x, y, rx, ry)
+	}
+
+	// giant steps: Q - i*m*R
+	gx, gy := curve.ScalarMult(rx, ry, big.NewInt(m).Bytes())
+	gx, gy = Inverse(curve, gx, gy)
+
+	var matches []int64
+
+	x, y = qx, qy
+	for i := int64(0); i*m < n; i++ {
+		if j, ok := baby[key(x, y)]; ok && i*m+j < n {
+			matches = append(matches, i*m+j)
+		}
+		x, y = curve.Add(x, y, gx, gy)
+	}
+
+	return matches, nil
+}
+
+// intersect returns the elements of a which are also in b.
+func intersect(a, b []int64) []int64 {
+	in := make(map[int64]bool, len(b))
+	for _, v := range b {
+		in[v] = true
+	}
+
+	var res []int64
+	for _, v := range a {
+		if in[v] {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+// Twist returns the quadratic twist y^2 = x^3 + a*d^2*x + b*d^3 of the curve,
+// where d is the smallest quadratic non-residue mod P. If the curve has
+// p + 1 - t points, its twist has p + 1 + t points. Only P, A, B, BitSize
+// and Name of the twist are set.
+func Twist(curve Curve) *CurveParams {
+	params := curve.Params()
+	p := params.P
+
+	d := big.NewInt(2)
+	for big.Jacobi(d, p) != -1 {
+		d.Add(d, helpers.BigOne)
+	}
+
+	d2 := new(big.Int).Mul(d, d)
+	a := new(big.Int).Mul(params.A, d2)
+	a.Mod(a, p)
+	b := new(big.Int).Mul(params.B, d2.Mul(d2, d))
+	b.Mod(b, p)
+
+	return &CurveParams{
+		P:       new(big.Int).Set(p),
+		A:       a,
+		B:       b,
+		BitSize: params.BitSize,
+		Name:    params.Name + "-twist",
+	}
+}
@@ -0,0 +1,126 @@
+package elliptic
+
+import (
+	"math/big"
+	"testing"
+)
+
+type countTest struct {
+	p, a, b string
+}
+
+var smallCountTests = []countTest{
+	{"1009", "1", "10"},
+	{"1009", "-3", "5"},
+	{"10007", "2", "17"},
+	{"10007", "544", "1242"},
+	{"65521", "5", "38"},
+	{"65521", "-95051", "210"},
+}
+
+func TestCountPointsSmall(t *testing.T) {
+	for i, e := range smallCountTests {
+		p, _ := new(big.Int).SetString(e.p, 10)
+		a, _ := new(big.Int).SetString(e.a, 10)
+		b, _ := new(big.Int).SetString(e.b, 10)
+		a.Mod(a, p)
+
+		naive := countPointsNaive(a, b, p)
+
+		mestre, err := countPointsMestre(a, b, p, big.NewInt(0), big.NewInt(1))
+		if err != nil {
+			t.Fatalf("%s - #%d: %s", t.Name(), i, err)
+		}
+		if mestre.Cmp(naive) != 0 {
+			t.Errorf("%s - #%d: Mestre's method: got %d, want %d", t.Name(), i, mestre, naive)
+		}
+
+		// t = p + 1 - #E
+		trace := new(big.Int).Add(p, big.NewInt(1))
+		trace.Sub(trace, naive)
+
+		tm, m := schoof(a, b, p, big.NewInt(1<<20))
+		if d := new(big.Int).Sub(tm, trace); d.Mod(d, m).Sign() != 0 {
+			t.Errorf("%s - #%d: Schoof's algorithm: got t = %d mod %d, want %d", t.Name(), i, tm, m, trace)
+		}
+	}
+}
+
+func TestCountPoints(t *testing.T) {
+	countPointsTests := []struct {
+		curve Curve
+		n     string
+	}{
+		{P48(), "146150168402890"},
+		{P128(), "233970423115425145498902418297807005944"},
+	}
+
+	for _, e := range countPointsTests {
+		n, err := CountPoints(e.curve)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", t.Name(), e.curve.Params().Name, err)
+		}
+		if n.String() != e.n {
+			t.Errorf("%s: %s: got %d, want %s", t.Name(), e.curve.Params().Name, n, e.n)
+		}
+	}
+}
+
+func TestTwist(t *testing.T) {
+	p48 := P48()
+	twist := Twist(p48)
+
+	n, err := CountPoints(p48)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	nt, err := CountPoints(twist)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+
+	// |E| + |E'| = 2*p + 2
+	sum := new(big.Int).Add(n, nt)
+	want := new(big.Int).Lsh(p48.Params().P, 1)
+	want.Add(want, big.NewInt(2))
+	if sum.Cmp(want) != 0 {
+		t.Errorf("%s: |E| + |E'| = %d, want %d", t.Name(), sum, want)
+	}
+
+	x, y := GeneratePoint(twist, nil)
+	if x, y = twist.ScalarMult(x, y, nt.Bytes()); x.Sign() != 0 || y.Sign() != 0 {
+		t.Errorf("%s: |E'| * P != O", t.Name())
+	}
+}
+
+func TestGenerateInvalidCurves(t *testing.T) {
+	p48 := P48()
+
+	invalidCurves, err := GenerateInvalidCurves(p48, big.NewInt(1<<16), 100)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+
+	product := big.NewInt(1)
+	for _, c := range invalidCurves {
+		if c.B.Cmp(p48.Params().B) == 0 {
+			t.Errorf("%s: %s: the target curve was returned", t.Name(), c.Name)
+		}
+
+		x, y := GeneratePoint(c, nil)
+		if x, y = c.ScalarMult(x, y, c.N.Bytes()); x.Sign() != 0 || y.Sign() != 0 {
+			t.Errorf("%s: %s: N * P != O", t.Name(), c.Name)
+		}
+
+		for _, f := range c.Factors {
+			if new(big.Int).Mod(c.N, f).Sign() != 0 {
+				t.Errorf("%s: %s: %d doesn't divide %d", t.Name(), c.Name, f, c.N)
+			}
+			product.Mul(product, f)
+		}
+	}
+
+	if product.Cmp(p48.Params().N) <= 0 {
+		t.Errorf("%s: product of factors %d doesn't exceed %d", t.Name(), product, p48.Params().N)
+	}
+}
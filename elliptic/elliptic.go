@@ -34,6 +34,7 @@ type Curve interface {
 type CurveParams struct {
 	P       *big.Int // the order of the underlying field
 	N       *big.Int // the order of the base point
+	H       *big.Int // the cofactor of the base point, if known
 	B       *big.Int // b parameter
 	A       *big.Int // a parameter
 	Gx, Gy  *big.Int // (x,y) of the base point
@@ -107,6 +108,23 @@ func (curve *CurveParams) Double(x1, y1 *big.Int) (x, y *big.Int) {
 }
 
 func (curve *CurveParams) ScalarMult(xIn, yIn *big.Int, k []byte) (x, y *big.Int) {
+	return DoubleAndAdd(curve, xIn, yIn, k, nil)
+}
+
+// Operation is an addition or a doubling made by DoubleAndAdd. A trivial
+// operation has the point at infinity as an operand, so it returns early in
+// CurveParams.Add.
+type Operation struct {
+	Double  bool
+	Trivial bool
+	X, Y    *big.Int // the result
+}
+
+// DoubleAndAdd returns k*(xIn, yIn), where k is a number in big-endian form,
+// with the non-constant time double-and-add loop of CurveParams.ScalarMult,
+// which goes from the least significant bit of k. Each operation of the loop
+// is passed to observe, which may be nil.
+func DoubleAndAdd(curve Curve, xIn, yIn *big.Int, k []byte, observe func(Operation)) (x, y *big.Int) {
 	// https://en.wikipedia.org/wiki/Elliptic_curve_point_multiplication#Double-and-add
 
 	x = new(big.Int).Set(helpers.BigZero)
@@ -121,21 +139,29 @@ func (curve *CurveParams) ScalarMult(xIn, yIn *big.Int, k []byte) (x, y *big.Int
 
 	bigK := new(big.Int).SetBytes(k)
 
-	tmp := new(big.Int)
-
-	for bigK.Cmp(helpers.BigZero) != 0 {
-		if tmp.And(bigK, helpers.BigOne).Cmp(helpers.BigOne) == 0 {
+	for i := 0; i < bigK.BitLen(); i++ {
+		if bigK.Bit(i) == 1 {
+			trivial := isInfinity(x, y) || isInfinity(pointX, pointY)
 			x, y = curve.Add(x, y, pointX, pointY)
+			if observe != nil {
+				observe(Operation{Trivial: trivial, X: x, Y: y})
+			}
 		}
 
+		trivial := isInfinity(pointX, pointY)
 		pointX, pointY = curve.Double(pointX, pointY)
-
-		bigK.Rsh(bigK, 1)
+		if observe != nil {
+			observe(Operation{Double: true, Trivial: trivial, X: pointX, Y: pointY})
+		}
 	}
 
 	return
 }
 
+func isInfinity(x, y *big.Int) bool {
+	return x.Sign() == 0 && y.Sign() == 0
+}
+
 func (curve *CurveParams) ScalarBaseMult(k []byte) (x, y *big.Int) {
 	return curve.ScalarMult(curve.Gx, curve.Gy, k)
 }
@@ -174,9 +200,15 @@ func Inverse(curve Curve, x, y *big.Int) (ix *big.Int, iy *big.Int) {
 	return
 }
 
-func GeneratePoint(curve Curve) (*big.Int, *big.Int) {
+// GeneratePoint returns a random point on the curve. If rng is nil,
+// crypto/rand.Reader is used.
+func GeneratePoint(curve Curve, rng io.Reader) (*big.Int, *big.Int) {
+	if rng == nil {
+		rng = rand.Reader
+	}
+
 	for {
-		x, err := rand.Int(rand.Reader, curve.Params().P)
+		x, err := rand.Int(rng, curve.Params().P)
 
 		if err != nil {
 			panic(err)
@@ -268,6 +300,7 @@ func initP256() {
 	p256.A, _ = new(big.Int).SetString("-3", 10)
 	p256.Gx, _ = new(big.Int).SetString("6b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296", 16)
 	p256.Gy, _ = new(big.Int).SetString("4fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5", 16)
+	p256.H = big.NewInt(1)
 	p256.BitSize = 127
 }
 
@@ -280,6 +313,7 @@ func initP224() {
 	p224.A, _ = new(big.Int).SetString("-3", 10)
 	p224.Gx, _ = new(big.Int).SetString("b70e0cbd6bb4bf7f321390b94a03c1d356c21122343280d6115c1d21", 16)
 	p224.Gy, _ = new(big.Int).SetString("bd376388b5f723fb4c22dfe6cd4375a05a07476444d5819985007e34", 16)
+	p224.H = big.NewInt(1)
 	p224.BitSize = 224
 }
 
@@ -299,6 +333,7 @@ func initP128() {
 	p128.A, _ = new(big.Int).SetString("-95051", 10)
 	p128.Gx, _ = new(big.Int).SetString("182", 10)
 	p128.Gy, _ = new(big.Int).SetString("85518893674295321206118380980485522083", 10)
+	p128.H = big.NewInt(8)
 	p128.BitSize = 128
 }
 
@@ -310,6 +345,7 @@ func initP128V1() {
 	p128v1.A, _ = new(big.Int).SetString("-95051", 10)
 	p128v1.Gx, _ = new(big.Int).SetString("182", 10)
 	p128v1.Gy, _ = new(big.Int).SetString("85518893674295321206118380980485522083", 10)
+	p128v1.H = big.NewInt(1)
 	p128v1.BitSize = 128
 }
 
@@ -321,6 +357,7 @@ func initP128V2() {
 	p128v2.A, _ = new(big.Int).SetString("-95051", 10)
 	p128v2.Gx, _ = new(big.Int).SetString("182", 10)
 	p128v2.Gy, _ = new(big.Int).SetString("85518893674295321206118380980485522083", 10)
+	p128v2.H = big.NewInt(1)
 	p128v2.BitSize = 128
 }
 
@@ -332,6 +369,7 @@ func initP128V3() {
 	p128v3.A, _ = new(big.Int).SetString("-95051", 10)
 	p128v3.Gx, _ = new(big.Int).SetString("182", 10)
 	p128v3.Gy, _ = new(big.Int).SetString("85518893674295321206118380980485522083", 10)
+	p128v3.H = big.NewInt(1)
 	p128v3.BitSize = 128
 }
 
@@ -343,6 +381,7 @@ func initP48() {
 	p48.A, _ = new(big.Int).SetString("544333", 10)
 	p48.Gx, _ = new(big.Int).SetString("27249639878388", 10)
 	p48.Gy, _ = new(big.Int).SetString("14987583413657", 10)
+	p48.H = big.NewInt(1)
 	p48.BitSize = 48
 }
 
@@ -121,6 +121,34 @@ func TestP3Mult(t *testing.T) {
 	}
 }
 
+func TestDoubleAndAdd(t *testing.T) {
+	p48 := P48()
+	gx, gy := p48.Params().Gx, p48.Params().Gy
+
+	// 0b101101: a doubling per bit and an addition per set bit, the first
+	// addition is made to the point at infinity
+	k := big.NewInt(45)
+
+	var doubles, adds, trivial int
+	x, y := DoubleAndAdd(p48, gx, gy, k.Bytes(), func(op Operation) {
+		switch {
+		case op.Trivial:
+			trivial++
+		case op.Double:
+			doubles++
+		default:
+			adds++
+		}
+	})
+
+	if wantX, wantY := p48.ScalarMult(gx, gy, k.Bytes()); x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0 {
+		t.Errorf("%s: got (%d, %d), want (%d, %d)", t.Name(), x, y, wantX, wantY)
+	}
+	if doubles != 6 || adds != 3 || trivial != 1 {
+		t.Errorf("%s: got %d doublings, %d additions and %d trivial operations, want 6, 3 and 1", t.Name(), doubles, adds, trivial)
+	}
+}
+
 var p256MultTests = []scalarMultTest{
 	{
 		"2a265f8bcbdcaf94d58519141e578124cb40d64a501fba9c11847b28965bc737",
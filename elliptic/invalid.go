@@ -0,0 +1,115 @@
+package elliptic
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+// InvalidCurve is a curve y^2 = x^3 + a*x + b' which shares P and A with
+// a target curve, so that the target's addition law works on its points.
+type InvalidCurve struct {
+	*CurveParams // N is the order of the whole curve
+
+	// Factors are the prime factors of N which are useful for an invalid
+	// curve attack on the target and weren't already provided by the curves
+	// ranked before this one.
+	Factors []*big.Int
+}
+
+// GenerateInvalidCurves searches for invalid curves for the target curve by
+// trying b' = 1, 2, ... up to maxTries values. It counts the points of each
+// curve and collects the odd prime factors of its order below factorBound.
+//
+// The curves are ranked greedily: each next curve is the one whose new
+// factors, not covered by the curves ranked before it, have the largest
+// product. The result stops as soon as the product of all factors exceeds
+// the order of the target's base point, so that a private key can be
+// reassembled with the Chinese Remainder Theorem.
+func GenerateInvalidCurves(curve Curve, factorBound *big.Int, maxTries int) ([]*InvalidCurve, error) {
+	params := curve.Params()
+
+	var found []*InvalidCurve
+
+	for b := int64(1); b <= int64(maxTries); b++ {
+		bb := big.NewInt(b)
+		if bb.Cmp(new(big.Int).Mod(params.B, params.P)) == 0 {
+			continue
+		}
+
+		c := &CurveParams{
+			P:       params.P,
+			A:       params.A,
+			B:       bb,
+			Gx:      params.Gx,
+			Gy:      params.Gy,
+			BitSize: params.BitSize,
+			Name:    fmt.Sprintf("%s-B%d", params.Name, b),
+		}
+
+		n, err := CountPoints(c)
+		if err != nil {
+			// the curve is singular
+			continue
+		}
+		c.N = n
+
+		var factors []*big.Int
+		for _, f := range helpers.Factorize(n, factorBound) {
+			if f.Cmp(helpers.BigTwo) != 0 && f.Cmp(factorBound) < 0 {
+				factors = append(factors, f)
+			}
+		}
+		if len(factors) == 0 {
+			continue
+		}
+
+		found = append(found, &InvalidCurve{CurveParams: c, Factors: factors})
+	}
+
+	return rankInvalidCurves(found, params.N)
+}
+
+// rankInvalidCurves greedily picks curves covering the largest product of
+// new factors until the product exceeds order.
+func rankInvalidCurves(curves []*InvalidCurve, order *big.Int) ([]*InvalidCurve, error) {
+	covered := make(map[string]bool)
+	product := new(big.Int).Set(helpers.BigOne)
+
+	var ranked []*InvalidCurve
+
+	for product.Cmp(order) <= 0 {
+		var best *InvalidCurve
+		bestProduct := new(big.Int).Set(helpers.BigOne)
+
+		for _, c := range curves {
+			p := new(big.Int).Set(helpers.BigOne)
+			var factors []*big.Int
+			for _, f := range c.Factors {
+				if !covered[f.String()] {
+					p.Mul(p, f)
+					factors = append(factors, f)
+				}
+			}
+
+			if p.Cmp(bestProduct) > 0 {
+				bestProduct = p
+				best = &InvalidCurve{CurveParams: c.CurveParams, Factors: factors}
+			}
+		}
+
+		if best == nil {
+			return ranked, errors.New("elliptic: not enough invalid curves to cover the order")
+		}
+
+		for _, f := range best.Factors {
+			covered[f.String()] = true
+		}
+		product.Mul(product, bestProduct)
+		ranked = append(ranked, best)
+	}
+
+	return ranked, nil
+}
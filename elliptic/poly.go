@@ -0,0 +1,340 @@
+package elliptic
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// poly is a polynomial over GF(p) stored as coefficients from the lowest
+// degree to the highest one.
+type poly []*big.Int
+
+// newPoly returns a zero polynomial with n coefficients.
+func newPoly(n int) poly {
+	a := make(poly, n)
+	for i := range a {
+		a[i] = new(big.Int)
+	}
+	return a
+}
+
+// trim removes leading zero coefficients.
+func (a poly) trim() poly {
+	n := len(a)
+	for n > 0 && a[n-1].Sign() == 0 {
+		n--
+	}
+	return a[:n]
+}
+
+// isZero reports whether all coefficients of a are zero.
+func (a poly) isZero() bool {
+	return len(a.trim()) == 0
+}
+
+// equal reports whether a and b have the same coefficients.
+func (a poly) equal(b poly) bool {
+	a, b = a.trim(), b.trim()
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Cmp(b[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// polyMul returns a*b mod p using schoolbook multiplication.
+func polyMul(a, b poly, p *big.Int) poly {
+	a, b = a.trim(), b.trim()
+	if len(a) == 0 || len(b) == 0 {
+		return poly{}
+	}
+
+	c := newPoly(len(a) + len(b) - 1)
+	tmp := new(big.Int)
+	for i := range a {
+		if a[i].Sign() == 0 {
+			continue
+		}
+		for j := range b {
+			c[i+j].Add(c[i+j], tmp.Mul(a[i], b[j]))
+		}
+	}
+	for i := range c {
+		c[i].Mod(c[i], p)
+	}
+
+	return c
+}
+
+// polySub returns a-b mod p.
+func polySub(a, b poly, p *big.Int) poly {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+
+	c := newPoly(n)
+	for i := range c {
+		if i < len(a) {
+			c[i].Set(a[i])
+		}
+		if i < len(b) {
+			c[i].Sub(c[i], b[i])
+		}
+		c[i].Mod(c[i], p)
+	}
+
+	return c.trim()
+}
+
+// polyMonic returns a scaled so that its leading coefficient is one.
+func polyMonic(a poly, p *big.Int) poly {
+	a = a.trim()
+	inv := new(big.Int).ModInverse(a[len(a)-1], p)
+
+	c := newPoly(len(a))
+	for i := range a {
+		c[i].Mul(a[i], inv).Mod(c[i], p)
+	}
+
+	return c
+}
+
+// polyDivMod returns q and r such that a = q*b + r and deg(r) < deg(b).
+// b must be non-zero.
+func polyDivMod(a, b poly, p *big.Int) (q, r poly) {
+	a, b = a.trim(), b.trim()
+	if len(a) < len(b) {
+		return poly{}, a
+	}
+
+	r = newPoly(len(a))
+	for i := range a {
+		r[i].Set(a[i])
+	}
+	q = newPoly(len(a) - len(b) + 1)
+
+	inv := new(big.Int).ModInverse(b[len(b)-1], p)
+	tmp := new(big.Int)
+
+	for i := len(a) - 1; i >= len(b)-1; i-- {
+		if r[i].Sign() == 0 {
+			continue
+		}
+
+		k := i - len(b) + 1
+		q[k].Mul(r[i], inv).Mod(q[k], p)
+		for j := range b {
+			r[k+j].Sub(r[k+j], tmp.Mul(q[k], b[j])).Mod(r[k+j], p)
+		}
+	}
+
+	return q.trim(), r[:len(b)-1].trim()
+}
+
+// polyGCD returns the monic greatest common divisor of a and b.
+func polyGCD(a, b poly, p *big.Int) poly {
+	a, b = a.trim(), b.trim()
+	for len(b) != 0 {
+		_, r := polyDivMod(a, b, p)
+		a, b = b, r
+	}
+	if len(a) == 0 {
+		return a
+	}
+	return polyMonic(a, p)
+}
+
+// polyRing is the quotient ring GF(p)[x]/(h) for a monic polynomial h of
+// degree d. Elements of the ring are polys with exactly d coefficients.
+//
+// Multiplication uses Kronecker substitution, so that the heavy lifting is
+// done by big.Int multiplication, and the reduction modulo h is done with a
+// precomputed inverse of the reversed modulus.
+type polyRing struct {
+	p    *big.Int
+	h    poly // monic modulus
+	d    int  // degree of h
+	hinv poly // rev(h)^-1 mod x^(d-1)
+	wb   int  // Kronecker slot size in bytes
+}
+
+// newPolyRing creates GF(p)[x]/(h) for a monic h of degree at least one.
+func newPolyRing(h poly, p *big.Int) *polyRing {
+	h = h.trim()
+	d := len(h) - 1
+
+	r := &polyRing{
+		p:  p,
+		h:  h,
+		d:  d,
+		wb: (2*p.BitLen() + bits.Len(uint(d)) + 1 + 7) >> 3,
+	}
+
+	// hinv = rev(h)^-1 mod x^(d-1), where rev(h)[i] = h[d-i] and rev(h)[0] = 1
+	if d > 1 {
+		r.hinv = newPoly(d - 1)
+		r.hinv[0].SetInt64(1)
+		tmp := new(big.Int)
+		for k := 1; k < d-1; k++ {
+			for j := 1; j <= k; j++ {
+				r.hinv[k].Sub(r.hinv[k], tmp.Mul(h[d-j], r.hinv[k-j]))
+			}
+			r.hinv[k].Mod(r.hinv[k], p)
+		}
+	}
+
+	return r
+}
+
+// kronecker returns a*b mod p. a and b must be reduced mod p.
+func (r *polyRing) kronecker(a, b poly) poly {
+	n := len(a) + len(b) - 1
+	if len(a) == 0 || len(b) == 0 {
+		return newPoly(0)
+	}
+
+	pack := func(a poly) *big.Int {
+		buf := make([]byte, len(a)*r.wb)
+		for i, c := range a {
+			c.FillBytes(buf[(len(a)-1-i)*r.wb : (len(a)-i)*r.wb])
+		}
+		return new(big.Int).SetBytes(buf)
+	}
+
+	za := pack(a)
+	zb := za
+	if &a[0] != &b[0] || len(a) != len(b) {
+		zb = pack(b)
+	}
+	za.Mul(za, zb)
+
+	buf := make([]byte, n*r.wb)
+	za.FillBytes(buf)
+
+	c := make(poly, n)
+	for i := range c {
+		c[i] = new(big.Int).SetBytes(buf[(n-1-i)*r.wb : (n-i)*r.wb])
+		c[i].Mod(c[i], r.p)
+	}
+
+	return c
+}
+
+// reduce returns a mod h for a polynomial a of any degree.
+func (r *polyRing) reduce(a poly) poly {
+	_, rem := polyDivMod(a, r.h, r.p)
+	c := newPoly(r.d)
+	for i := range rem {
+		c[i].Set(rem[i])
+	}
+	return c
+}
+
+// fromBig returns the constant c as an element of the ring.
+func (r *polyRing) fromBig(c *big.Int) poly {
+	return r.reduce(poly{new(big.Int).Mod(c, r.p)})
+}
+
+// fromInt returns the constant n as an element of the ring.
+func (r *polyRing) fromInt(n int64) poly {
+	return r.fromBig(big.NewInt(n))
+}
+
+// x returns the indeterminate x as an element of the ring.
+func (r *polyRing) x() poly {
+	return r.reduce(poly{new(big.Int), big.NewInt(1)})
+}
+
+func (r *polyRing) mul(a, b poly) poly {
+	d := r.d
+	c := r.kronecker(a, b)
+	if d < 2 {
+		return c[:d]
+	}
+
+	// rev(q) = rev(c) * rev(h)^-1 mod x^(d-1)
+	crev := make(poly, d-1)
+	for i := range crev {
+		crev[i] = c[2*d-2-i]
+	}
+	qrev := r.kronecker(crev, r.hinv)[:d-1]
+
+	q := make(poly, d-1)
+	for i := range q {
+		q[i] = qrev[d-2-i]
+	}
+
+	// c - q*h
+	qh := r.kronecker(q, r.h)
+	res := c[:d]
+	for i := range res {
+		res[i].Sub(res[i], qh[i]).Mod(res[i], r.p)
+	}
+
+	return res
+}
+
+func (r *polyRing) sqr(a poly) poly {
+	return r.mul(a, a)
+}
+
+func (r *polyRing) add(a, b poly) poly {
+	c := newPoly(r.d)
+	for i := range c {
+		c[i].Add(a[i], b[i]).Mod(c[i], r.p)
+	}
+	return c
+}
+
+func (r *polyRing) sub(a, b poly) poly {
+	c := newPoly(r.d)
+	for i := range c {
+		c[i].Sub(a[i], b[i]).Mod(c[i], r.p)
+	}
+	return c
+}
+
+func (r *polyRing) neg(a poly) poly {
+	c := newPoly(r.d)
+	for i := range c {
+		c[i].Neg(a[i]).Mod(c[i], r.p)
+	}
+	return c
+}
+
+// scale returns k*a.
+func (r *polyRing) scale(a poly, k int64) poly {
+	bk := big.NewInt(k)
+	c := newPoly(r.d)
+	for i := range c {
+		c[i].Mul(a[i], bk).Mod(c[i], r.p)
+	}
+	return c
+}
+
+// exp returns a^e.
+func (r *polyRing) exp(a poly, e *big.Int) poly {
+	res := r.fromInt(1)
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		res = r.sqr(res)
+		if e.Bit(i) == 1 {
+			res = r.mul(res, a)
+		}
+	}
+	return res
+}
+
+// gcd returns the monic gcd of a and h.
+func (r *polyRing) gcd(a poly) poly {
+	return polyGCD(r.h, a, r.p)
+}
+
+// isUnit reports whether g, as returned by gcd, is a constant.
+func isUnit(g poly) bool {
+	return len(g.trim()) == 1
+}
@@ -0,0 +1,58 @@
+package elliptic
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]func() Curve{
+		"P-4":      P4,
+		"P-48":     P48,
+		"P-128":    P128,
+		"P-128-V1": P128V1,
+		"P-128-V2": P128V2,
+		"P-128-V3": P128V3,
+		"P-224":    P224,
+		"P-256":    P256,
+	}
+)
+
+// Register makes a curve available by its name, e.g. an invalid curve made
+// by GenerateInvalidCurves. It replaces a curve registered under the same
+// name.
+func Register(curve Curve) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[curve.Params().Name] = func() Curve { return curve }
+}
+
+// CurveByName returns the registered curve with the given name.
+func CurveByName(name string) (Curve, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	curve, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("elliptic: unknown curve %q", name)
+	}
+
+	return curve(), nil
+}
+
+// CurveNames returns the names of all registered curves in sorted order.
+func CurveNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,334 @@
+package elliptic
+
+import (
+	"errors"
+	"math/big"
+	"math/bits"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+// divisionPolynomial returns f_n, where f_n = psi_n for odd n and
+// f_n = psi_n / (2y) for even n, so that every f_n is a polynomial in x.
+// See https://en.wikipedia.org/wiki/Division_polynomials for details.
+func divisionPolynomial(n int, a, b, p *big.Int, memo map[int]poly) poly {
+	if f, ok := memo[n]; ok {
+		return f
+	}
+
+	var f poly
+
+	switch n {
+	case 0:
+		f = poly{}
+	case 1, 2:
+		f = poly{big.NewInt(1)}
+	case 3:
+		// 3x^4 + 6ax^2 + 12bx - a^2
+		f = poly{
+			new(big.Int).Neg(new(big.Int).Mul(a, a)),
+			new(big.Int).Mul(big.NewInt(12), b),
+			new(big.Int).Mul(big.NewInt(6), a),
+			new(big.Int),
+			big.NewInt(3),
+		}
+	case 4:
+		// 2 * (x^6 + 5ax^4 + 20bx^3 - 5a^2x^2 - 4abx - 8b^2 - a^3)
+		a2 := new(big.Int).Mul(a, a)
+		f = poly{
+			new(big.Int).Mul(big.NewInt(-2), new(big.Int).Add(new(big.Int).Mul(big.NewInt(8), new(big.Int).Mul(b, b)), new(big.Int).Mul(a2, a))),
+			new(big.Int).Mul(big.NewInt(-8), new(big.Int).Mul(a, b)),
+			new(big.Int).Mul(big.NewInt(-10), a2),
+			new(big.Int).Mul(big.NewInt(40), b),
+			new(big.Int).Mul(big.NewInt(10), a),
+			new(big.Int),
+			big.NewInt(2),
+		}
+	default:
+		m := n / 2
+		fm := func(i int) poly { return divisionPolynomial(i, a, b, p, memo) }
+		cube := func(g poly) poly { return polyMul(polyMul(g, g, p), g, p) }
+		sqr := func(g poly) poly { return polyMul(g, g, p) }
+
+		// F = (2y)^4 = 16 * (x^3 + ax + b)^2
+		rhs := poly{new(big.Int).Set(b), new(big.Int).Set(a), new(big.Int), big.NewInt(1)}
+		F := polyMul(poly{big.NewInt(16)}, sqr(rhs), p)
+
+		if n%2 == 1 {
+			// psi_{2m+1} = psi_{m+2} * psi_m^3 - psi_{m-1} * psi_{m+1}^3
+			t1 := polyMul(fm(m+2), cube(fm(m)), p)
+			t2 := polyMul(fm(m-1), cube(fm(m+1)), p)
+			if m%2 == 0 {
+				t1 = polyMul(F, t1, p)
+			} else {
+				t2 = polyMul(F, t2, p)
+			}
+			f = polySub(t1, t2, p)
+		} else {
+			// psi_{2m} = psi_m / (2y) * (psi_{m+2} * psi_{m-1}^2 - psi_{m-2} * psi_{m+1}^2)
+			t1 := polyMul(fm(m+2), sqr(fm(m-1)), p)
+			t2 := polyMul(fm(m-2), sqr(fm(m+1)), p)
+			f = polyMul(fm(m), polySub(t1, t2, p), p)
+		}
+	}
+
+	for _, c := range f {
+		c.Mod(c, p)
+	}
+	f = f.trim()
+	memo[n] = f
+
+	return f
+}
+
+// jacobian is a point (X/Z^2, Y/Z^3 * y) over the ring GF(p)[x, y]/(h(x), y^2 - x^3 - ax - b),
+// that is, the y coordinate is always stored divided by the generic y.
+type jacobian struct {
+	x, y, z poly
+}
+
+// errSplit is returned when a zero divisor of the ring has been found, so
+// that the computation has to be restarted modulo factor.
+type errSplit struct {
+	factor poly
+}
+
+func (e *errSplit) Error() string {
+	return "elliptic: division polynomial has split"
+}
+
+// torsionGroup implements the group law on E[l] over the polyRing.
+type torsionGroup struct {
+	*polyRing
+	a poly // the a parameter
+	f poly // x^3 + ax + b
+}
+
+func newTorsionGroup(h poly, a, b, p *big.Int) *torsionGroup {
+	r := newPolyRing(polyMonic(h, p), p)
+	return &torsionGroup{
+		polyRing: r,
+		a:        r.fromBig(a),
+		f:        r.reduce(poly{new(big.Int).Set(b), new(big.Int).Set(a), new(big.Int), big.NewInt(1)}),
+	}
+}
+
+// double returns 2*P.
+func (g *torsionGroup) double(pt jacobian) jacobian {
+	// S = 4*X*Y^2*F
+	y2 := g.sqr(pt.y)
+	s := g.scale(g.mul(g.mul(pt.x, y2), g.f), 4)
+
+	// M = 3*X^2 + a*Z^4
+	z2 := g.sqr(pt.z)
+	m := g.add(g.scale(g.sqr(pt.x), 3), g.mul(g.a, g.sqr(z2)))
+
+	// X3 = M^2 - 2*S
+	x3 := g.sub(g.sqr(m), g.scale(s, 2))
+
+	// Y3 = M*(S - X3) - 8*Y^4*F^2
+	y3 := g.sub(g.mul(m, g.sub(s, x3)), g.scale(g.sqr(g.mul(y2, g.f)), 8))
+
+	// Z3 = 2*Y*Z, which carries a factor of y, so the point is rescaled by y
+	// to keep y out of the Z coordinate.
+	z3 := g.scale(g.mul(pt.y, pt.z), 2)
+
+	return jacobian{
+		x: g.mul(x3, g.f),
+		y: g.mul(y3, g.f),
+		z: g.mul(z3, g.f),
+	}
+}
+
+// addPoints returns P1 + P2 for points with different x coordinates at every
+// root of h.
+func (g *torsionGroup) addPoints(p1, p2 jacobian) jacobian {
+	z1s := g.sqr(p1.z)
+	z2s := g.sqr(p2.z)
+
+	// U1 = X1*Z2^2, U2 = X2*Z1^2
+	u1 := g.mul(p1.x, z2s)
+	u2 := g.mul(p2.x, z1s)
+
+	// S1 = Y1*Z2^3, S2 = Y2*Z1^3
+	s1 := g.mul(p1.y, g.mul(z2s, p2.z))
+	s2 := g.mul(p2.y, g.mul(z1s, p1.z))
+
+	h := g.sub(u2, u1)
+	r := g.sub(s2, s1)
+
+	h2 := g.sqr(h)
+	h3 := g.mul(h2, h)
+	u1h2 := g.mul(u1, h2)
+
+	// X3 = R^2*F - H^3 - 2*U1*H^2
+	x3 := g.sub(g.sub(g.mul(g.sqr(r), g.f), h3), g.scale(u1h2, 2))
+
+	// Y3 = R*(U1*H^2 - X3) - S1*H^3
+	y3 := g.sub(g.mul(r, g.sub(u1h2, x3)), g.mul(s1, h3))
+
+	// Z3 = H*Z1*Z2
+	z3 := g.mul(h, g.mul(p1.z, p2.z))
+
+	return jacobian{x: x3, y: y3, z: z3}
+}
+
+// addPointsChecked returns P1 + P2 for any two points. It returns errSplit if P1
+// and P2 have the same x coordinate at some, but not all, of the roots of h.
+// ok is false if the sum is the point at infinity.
+func (g *torsionGroup) addPointsChecked(p1, p2 jacobian) (sum jacobian, ok bool, err error) {
+	h := g.sub(g.mul(p2.x, g.sqr(p1.z)), g.mul(p1.x, g.sqr(p2.z)))
+
+	gcd := g.gcd(h)
+	if isUnit(gcd) {
+		return g.addPoints(p1, p2), true, nil
+	}
+	if len(gcd) <= g.d {
+		return jacobian{}, false, &errSplit{factor: gcd}
+	}
+
+	// P1 and P2 have the same x coordinate, so either P1 = P2 or P1 = -P2
+	s1 := g.mul(p1.y, g.mul(g.sqr(p2.z), p2.z))
+	s2 := g.mul(p2.y, g.mul(g.sqr(p1.z), p1.z))
+	r := g.sub(s2, s1)
+
+	if r.isZero() {
+		return g.double(p1), true, nil
+	}
+	if g.add(s1, s2).isZero() {
+		return jacobian{}, false, nil
+	}
+
+	return jacobian{}, false, &errSplit{factor: g.gcd(r)}
+}
+
+// scalarMult returns k*P for 0 < k < l using the double-and-add method.
+// Multiples of a point of order l never coincide on the way, so no checks
+// are needed.
+func (g *torsionGroup) scalarMult(pt jacobian, k int64) jacobian {
+	res := pt
+	for i := bits.Len64(uint64(k)) - 2; i >= 0; i-- {
+		res = g.double(res)
+		if (k>>uint(i))&1 == 1 {
+			res = g.addPoints(res, pt)
+		}
+	}
+	return res
+}
+
+// sameX reports whether P1 and P2 have the same x coordinate.
+func (g *torsionGroup) sameX(p1, p2 jacobian) bool {
+	return g.mul(p1.x, g.sqr(p2.z)).equal(g.mul(p2.x, g.sqr(p1.z)))
+}
+
+// sameY reports whether P1 and P2 have the same y coordinate.
+func (g *torsionGroup) sameY(p1, p2 jacobian) bool {
+	return g.mul(p1.y, g.mul(g.sqr(p2.z), p2.z)).equal(g.mul(p2.y, g.mul(g.sqr(p1.z), p1.z)))
+}
+
+// reduceTo returns the same point over the ring modulo factor of h.
+func (pt jacobian) reduceTo(g *torsionGroup) jacobian {
+	return jacobian{x: g.reduce(pt.x), y: g.reduce(pt.y), z: g.reduce(pt.z)}
+}
+
+// schoofTraceModTwo returns the trace of Frobenius modulo 2.
+func schoofTraceModTwo(a, b, p *big.Int) int64 {
+	// t = 0 mod 2 iff E has a point of order 2 iff x^3 + ax + b has a root in GF(p)
+	r := newPolyRing(poly{new(big.Int).Set(b), new(big.Int).Set(a), new(big.Int), big.NewInt(1)}, p)
+	xp := r.exp(r.x(), p)
+	if isUnit(r.gcd(r.sub(xp, r.x()))) {
+		return 1
+	}
+	return 0
+}
+
+// schoofTraceModPrime returns the trace of Frobenius modulo an odd prime l,
+// by finding tau such that phi^2(P) + [p mod l]P = [tau]phi(P) for the
+// l-torsion points P, where phi is the Frobenius endomorphism.
+func schoofTraceModPrime(l int64, a, b, p *big.Int) int64 {
+	g := newTorsionGroup(divisionPolynomial(int(l), a, b, p, make(map[int]poly)), a, b, p)
+
+	// phi(P) = (x^p, y^p) = (x^p, (x^3 + ax + b)^((p-1)/2) * y)
+	e := new(big.Int).Rsh(p, 1)
+	phi := jacobian{
+		x: g.exp(g.x(), p),
+		y: g.exp(g.f, e),
+		z: g.fromInt(1),
+	}
+
+	// phi^2(P) = (x^(p^2), y^(p^2)), where y^(p^2) = (y^p)^p = Y^p * Y * y
+	phi2 := jacobian{
+		x: g.exp(phi.x, p),
+		y: g.mul(g.exp(phi.y, p), phi.y),
+		z: g.fromInt(1),
+	}
+
+	k := new(big.Int).Mod(p, big.NewInt(l)).Int64()
+
+	for {
+		pt := jacobian{x: g.x(), y: g.fromInt(1), z: g.fromInt(1)}
+		kp := g.scalarMult(pt, k)
+
+		s, ok, err := g.addPointsChecked(phi2, kp)
+		if err != nil {
+			var split *errSplit
+			if !errors.As(err, &split) {
+				panic(err)
+			}
+
+			// restart the computation modulo the found factor of f_l
+			g = newTorsionGroup(split.factor, a, b, p)
+			phi = phi.reduceTo(g)
+			phi2 = phi2.reduceTo(g)
+			continue
+		}
+
+		if !ok {
+			return 0
+		}
+
+		t := phi
+		for tau := int64(1); tau <= l/2; tau++ {
+			if tau == 2 {
+				t = g.double(phi)
+			} else if tau > 2 {
+				t = g.addPoints(t, phi)
+			}
+
+			if g.sameX(s, t) {
+				if g.sameY(s, t) {
+					return tau
+				}
+				return l - tau
+			}
+		}
+
+		panic("elliptic: trace of Frobenius not found")
+	}
+}
+
+// schoof computes the trace of Frobenius t modulo the product of the small
+// primes l while that product is less than limit. It returns the residue
+// and the modulus.
+func schoof(a, b, p, limit *big.Int) (t, m *big.Int) {
+	remainders := []*big.Int{big.NewInt(schoofTraceModTwo(a, b, p))}
+	moduli := []*big.Int{big.NewInt(2)}
+	m = big.NewInt(2)
+
+	for l := int64(3); m.Cmp(limit) < 0; l += 2 {
+		if !big.NewInt(l).ProbablyPrime(0) {
+			continue
+		}
+		if new(big.Int).Mod(p, big.NewInt(l)).Sign() == 0 {
+			continue
+		}
+
+		remainders = append(remainders, big.NewInt(schoofTraceModPrime(l, a, b, p)))
+		moduli = append(moduli, big.NewInt(l))
+		m.Mul(m, big.NewInt(l))
+	}
+
+	t, m, _ = helpers.ChineseRemainderTheorem(remainders, moduli)
+
+	return t, m
+}
@@ -0,0 +1,125 @@
+package elliptic
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ValidationPolicy defines how a peer's public point is checked before it is
+// used in an ECDH calculation.
+type ValidationPolicy int
+
+const (
+	// NoValidation uses any point as is.
+	NoValidation ValidationPolicy = iota
+
+	// OnCurveCheck rejects points which don't lie on the curve.
+	OnCurveCheck
+
+	// OrderCheck rejects points which don't lie on the curve or whose order
+	// doesn't divide N, i.e. [N]P != O.
+	OrderCheck
+
+	// CofactorMultiplication multiplies the point by the cofactor H along
+	// with the private key and rejects the result if it's the point at
+	// infinity. The shared secret is [H*d]P rather than [d]P. Note that it
+	// doesn't check that the point is on the curve, it only changes what
+	// leaks through points of small order: H*d instead of d.
+	CofactorMultiplication
+)
+
+// ErrInvalidPoint is returned when a point is rejected by a validation policy.
+var ErrInvalidPoint = errors.New("elliptic: invalid point")
+
+func (v ValidationPolicy) String() string {
+	switch v {
+	case NoValidation:
+		return "none"
+	case OnCurveCheck:
+		return "on-curve"
+	case OrderCheck:
+		return "order"
+	case CofactorMultiplication:
+		return "cofactor"
+	default:
+		return fmt.Sprintf("ValidationPolicy(%d)", int(v))
+	}
+}
+
+// ParseValidationPolicy returns the policy with the given name, as returned
+// by ValidationPolicy.String.
+func ParseValidationPolicy(name string) (ValidationPolicy, error) {
+	for _, v := range []ValidationPolicy{NoValidation, OnCurveCheck, OrderCheck, CofactorMultiplication} {
+		if v.String() == name {
+			return v, nil
+		}
+	}
+
+	return NoValidation, fmt.Errorf("elliptic: unknown validation policy %q", name)
+}
+
+// Cofactor returns the cofactor H of the base point of the curve. If H isn't
+// set in the curve parameters, it's computed as #E/N.
+func Cofactor(curve Curve) (*big.Int, error) {
+	params := curve.Params()
+	if params.H != nil {
+		return params.H, nil
+	}
+
+	n, err := CountPoints(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	h, m := new(big.Int).DivMod(n, params.N, new(big.Int))
+	if m.Sign() != 0 {
+		return nil, fmt.Errorf("elliptic: %s: N doesn't divide the number of points", params.Name)
+	}
+
+	return h, nil
+}
+
+// ValidatedECDH checks the point (x, y) according to the policy and returns
+// [priv](x, y) if the point is accepted.
+func ValidatedECDH(curve Curve, priv []byte, x, y *big.Int, policy ValidationPolicy) (sx, sy *big.Int, err error) {
+	switch policy {
+	case NoValidation:
+		sx, sy = curve.ScalarMult(x, y, priv)
+		return sx, sy, nil
+
+	case OnCurveCheck, OrderCheck:
+		if !curve.IsOnCurve(x, y) {
+			return nil, nil, fmt.Errorf("%w: not on the curve", ErrInvalidPoint)
+		}
+
+		if policy == OrderCheck {
+			if ox, oy := curve.ScalarMult(x, y, curve.Params().N.Bytes()); ox.Sign() != 0 || oy.Sign() != 0 {
+				return nil, nil, fmt.Errorf("%w: [N]P isn't the point at infinity", ErrInvalidPoint)
+			}
+		}
+
+		sx, sy = curve.ScalarMult(x, y, priv)
+		return sx, sy, nil
+
+	case CofactorMultiplication:
+		h, err := Cofactor(curve)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// [H*d]P
+		k := new(big.Int).SetBytes(priv)
+		k.Mul(k, h)
+
+		sx, sy = curve.ScalarMult(x, y, k.Bytes())
+		if sx.Sign() == 0 && sy.Sign() == 0 {
+			return nil, nil, fmt.Errorf("%w: [H*d]P is the point at infinity", ErrInvalidPoint)
+		}
+
+		return sx, sy, nil
+
+	default:
+		return nil, nil, fmt.Errorf("elliptic: unknown validation policy %s", policy)
+	}
+}
@@ -0,0 +1,55 @@
+package elliptic
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestValidatedECDH(t *testing.T) {
+	p128 := P128()
+	priv, _, _, err := GenerateKey(p128, nil)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+
+	// the base point is accepted by all policies
+	for _, policy := range []ValidationPolicy{NoValidation, OnCurveCheck, OrderCheck, CofactorMultiplication} {
+		sx, sy, err := ValidatedECDH(p128, priv, p128.Params().Gx, p128.Params().Gy, policy)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", t.Name(), policy, err)
+		}
+
+		k := new(big.Int).SetBytes(priv)
+		if policy == CofactorMultiplication {
+			k.Mul(k, big.NewInt(8))
+		}
+		if x, y := p128.ScalarBaseMult(k.Bytes()); x.Cmp(sx) != 0 || y.Cmp(sy) != 0 {
+			t.Errorf("%s: %s: wrong shared point", t.Name(), policy)
+		}
+	}
+
+	// a point on an invalid curve is rejected by the curve checks
+	x, y := GeneratePoint(P128V1(), nil)
+	for _, policy := range []ValidationPolicy{OnCurveCheck, OrderCheck} {
+		if _, _, err := ValidatedECDH(p128, priv, x, y, policy); !errors.Is(err, ErrInvalidPoint) {
+			t.Errorf("%s: %s: a point on an invalid curve was accepted", t.Name(), policy)
+		}
+	}
+
+	// a point of order dividing 8 is rejected by the order check and is
+	// turned into the point at infinity by the cofactor
+	var sx, sy *big.Int
+	for {
+		x, y = GeneratePoint(p128, nil)
+		sx, sy = p128.ScalarMult(x, y, p128.Params().N.Bytes())
+		if sx.Sign() != 0 || sy.Sign() != 0 {
+			break
+		}
+	}
+	for _, policy := range []ValidationPolicy{OrderCheck, CofactorMultiplication} {
+		if _, _, err := ValidatedECDH(p128, priv, sx, sy, policy); !errors.Is(err, ErrInvalidPoint) {
+			t.Errorf("%s: %s: a point of small order was accepted", t.Name(), policy)
+		}
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/svkirillov/cryptopals-go
 
 go 1.15
+
+require golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
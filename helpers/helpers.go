@@ -2,7 +2,9 @@ package helpers
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
 )
 
@@ -13,9 +15,55 @@ var (
 	BigThree = big.NewInt(3)
 )
 
-// GenerateBigInt returns a uniform random value in [0, max)
-func GenerateBigInt(max *big.Int) (n *big.Int, err error) {
-	return rand.Int(rand.Reader, max)
+// GenerateBigInt returns a uniform random value in [0, max). If rng is nil,
+// crypto/rand.Reader is used.
+func GenerateBigInt(rng io.Reader, max *big.Int) (n *big.Int, err error) {
+	if rng == nil {
+		rng = rand.Reader
+	}
+
+	return rand.Int(rng, max)
+}
+
+// GeneratePrime returns a random prime of the given bit length with its top
+// two bits set. Unlike crypto/rand.Prime, it always reads from rng, so that
+// the result is reproducible with a seeded reader. If rng is nil,
+// crypto/rand.Reader is used.
+func GeneratePrime(rng io.Reader, bits int) (*big.Int, error) {
+	if bits < 3 {
+		return nil, errors.New("prime size must be at least 3-bit")
+	}
+	if rng == nil {
+		rng = rand.Reader
+	}
+
+	b := uint(bits % 8)
+	if b == 0 {
+		b = 8
+	}
+
+	bytes := make([]byte, (bits+7)/8)
+	p := new(big.Int)
+
+	for {
+		if _, err := io.ReadFull(rng, bytes); err != nil {
+			return nil, err
+		}
+
+		// clear the extra bits and set the top two ones
+		bytes[0] &= uint8(int(1<<b) - 1)
+		if b >= 2 {
+			bytes[0] |= 3 << (b - 2)
+		} else {
+			bytes[0] |= 1
+			bytes[1] |= 0x80
+		}
+		bytes[len(bytes)-1] |= 1
+
+		if p.SetBytes(bytes); p.ProbablyPrime(20) {
+			return p, nil
+		}
+	}
 }
 
 // SetBigIntFromDec creates new *big.Int from decimal number as a string
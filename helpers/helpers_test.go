@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestGenerateBigInt(t *testing.T) {
+	rng := NewSeededReader(1)
+
+	for _, max := range []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(1000), new(big.Int).Lsh(BigOne, 130)} {
+		for i := 0; i < 100; i++ {
+			n, err := GenerateBigInt(rng, max)
+			if err != nil {
+				t.Fatalf("%s: %s", t.Name(), err.Error())
+			}
+			if n.Sign() < 0 || n.Cmp(max) >= 0 {
+				t.Fatalf("%s: got %d, want a value in [0, %d)", t.Name(), n, max)
+			}
+		}
+	}
+
+	a, err := GenerateBigInt(NewSeededReader(2), big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	b, err := GenerateBigInt(NewSeededReader(2), big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if a.Cmp(b) != 0 {
+		t.Errorf("%s: the same seed gives %d and %d", t.Name(), a, b)
+	}
+}
+
+func TestGeneratePrime(t *testing.T) {
+	rng := NewSeededReader(3)
+
+	for _, bits := range []int{3, 8, 9, 17, 64, 128} {
+		p, err := GeneratePrime(rng, bits)
+		if err != nil {
+			t.Fatalf("%s: %d bits: %s", t.Name(), bits, err.Error())
+		}
+		if p.BitLen() != bits || !p.ProbablyPrime(20) {
+			t.Errorf("%s: got %d, want a %d-bit prime", t.Name(), p, bits)
+		}
+	}
+
+	if _, err := GeneratePrime(rng, 2); err == nil {
+		t.Errorf("%s: got a 2-bit prime, want an error", t.Name())
+	}
+}
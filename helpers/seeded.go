@@ -0,0 +1,63 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math/big"
+)
+
+// SeededReader is a deterministic source of random bytes. The stream is
+// SHA-256(seed || counter) for counter = 0, 1, ..., so the same seed always
+// produces the same bytes. It must not be used for real keys.
+type SeededReader struct {
+	seed    int64
+	counter uint64
+	buf     []byte
+}
+
+// NewSeededReader returns a SeededReader for the given seed.
+func NewSeededReader(seed int64) *SeededReader {
+	return &SeededReader{seed: seed}
+}
+
+// NewRandomSeed returns a random seed for NewSeededReader, so that it can be
+// logged before a run and the run can be replayed later.
+func NewRandomSeed() (int64, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(BigOne, 63))
+	if err != nil {
+		return 0, err
+	}
+
+	return n.Int64(), nil
+}
+
+// Seed returns the seed of the reader.
+func (r *SeededReader) Seed() int64 {
+	return r.seed
+}
+
+func (r *SeededReader) Read(p []byte) (int, error) {
+	n := 0
+
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			var block [16]byte
+			binary.BigEndian.PutUint64(block[:8], uint64(r.seed))
+			binary.BigEndian.PutUint64(block[8:], r.counter)
+			r.counter++
+
+			sum := sha256.Sum256(block[:])
+			r.buf = sum[:]
+		}
+
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+
+	return n, nil
+}
+
+var _ io.Reader = (*SeededReader)(nil)
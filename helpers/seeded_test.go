@@ -0,0 +1,40 @@
+package helpers
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSeededReader(t *testing.T) {
+	read := func(r io.Reader, n int) []byte {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+		return buf
+	}
+
+	want := read(NewSeededReader(7), 100)
+
+	if got := read(NewSeededReader(7), 100); !bytes.Equal(got, want) {
+		t.Errorf("%s: the same seed gives %x, want %x", t.Name(), got, want)
+	}
+
+	// the stream doesn't depend on the size of the reads
+	r := NewSeededReader(7)
+	var got []byte
+	for _, n := range []int{1, 31, 33, 35} {
+		got = append(got, read(r, n)...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s: the reads of 1, 31, 33 and 35 bytes give %x, want %x", t.Name(), got, want)
+	}
+
+	if got := read(NewSeededReader(8), 100); bytes.Equal(got, want) {
+		t.Errorf("%s: seeds 7 and 8 give the same stream %x", t.Name(), got)
+	}
+	if got := read(NewSeededReader(-7), 100); bytes.Equal(got, want) {
+		t.Errorf("%s: seeds 7 and -7 give the same stream %x", t.Name(), got)
+	}
+}
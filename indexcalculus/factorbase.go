@@ -0,0 +1,245 @@
+package indexcalculus
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// primesBelow returns the primes below bound with the sieve of Eratosthenes.
+func primesBelow(bound uint64) []uint64 {
+	composite := make([]bool, bound)
+	var primes []uint64
+
+	for n := uint64(2); n < bound; n++ {
+		if composite[n] {
+			continue
+		}
+		primes = append(primes, n)
+
+		for m := n * n; m < bound; m += n {
+			composite[m] = true
+		}
+	}
+
+	return primes
+}
+
+// divisor is an odd prime with what it takes to divide by it quickly: n is
+// divisible by prime iff n*inverse mod 2^64 is at most limit, and then
+// n*inverse is the quotient, see Hacker's Delight, 10-17.
+type divisor struct {
+	prime, inverse, limit uint64
+}
+
+func newDivisor(prime uint64) divisor {
+	// Newton's iteration doubles the correct low bits of the inverse, and
+	// prime*prime = 1 mod 8
+	inverse := prime
+	for i := 0; i < 5; i++ {
+		inverse *= 2 - prime*inverse
+	}
+
+	return divisor{prime: prime, inverse: inverse, limit: math.MaxUint64 / prime}
+}
+
+// maxPower returns n^e, or the largest uint64 if it overflows.
+func maxPower(n uint64, e int) uint64 {
+	res := uint64(1)
+	for i := 0; i < e; i++ {
+		hi, lo := bits.Mul64(res, n)
+		if hi != 0 {
+			return math.MaxUint64
+		}
+		res = lo
+	}
+	return res
+}
+
+// factorBase is the primes below a bound, in ascending order.
+type factorBase struct {
+	primes []uint64
+
+	// divisors are the odd primes, primes[1:].
+	divisors []divisor
+
+	// bound is above the primes.
+	bound uint64
+
+	// checks are where smooth gives up on n, see there.
+	checks []check
+}
+
+// check is that n is at most max after dividing by divisors[:end].
+type check struct {
+	end int
+	max uint64
+}
+
+func newFactorBase(bound uint64) *factorBase {
+	fb := &factorBase{primes: primesBelow(bound), bound: bound}
+
+	for _, prime := range fb.primes[1:] {
+		fb.divisors = append(fb.divisors, newDivisor(prime))
+	}
+
+	fb.checks = []check{
+		{len(fb.divisors) / 8, maxPower(bound, 3)},
+		{len(fb.divisors) / 2, maxPower(bound, 2)},
+		{len(fb.divisors), 1},
+	}
+
+	return fb
+}
+
+// smooth reports whether n > 0 is a product of the primes of the base.
+//
+// Most n aren't, and it gives up early on them: once the primes up to
+// an eighth of the base are divided out, the rest is a product of larger
+// ones, and if it's above bound^3, then there are at least four of them,
+// which is unlikely enough to be missed. The same goes for bound^2 at a half
+// of the base.
+func (fb *factorBase) smooth(n uint64) bool {
+	n >>= uint(bits.TrailingZeros64(n))
+
+	start := 0
+	for _, check := range fb.checks {
+		for _, d := range fb.divisors[start:check.end] {
+			if d.prime*d.prime > n {
+				// n is 1 or a prime
+				return n < fb.bound
+			}
+			for n*d.inverse <= d.limit {
+				n *= d.inverse
+			}
+		}
+		if n > check.max {
+			return false
+		}
+		start = check.end
+	}
+
+	return true
+}
+
+// factor divides n > 0 by the primes of the base for which known is true, or
+// by all of them if known is nil. It adds their exponents multiplied by sign
+// to exps, indexed like the primes, and returns the part of n which is left.
+func (fb *factorBase) factor(n uint64, sign int64, known []bool, exps map[int]int64) uint64 {
+	add := func(i int, e int64) {
+		if exps[i] += sign * e; exps[i] == 0 {
+			delete(exps, i)
+		}
+	}
+
+	if known == nil || known[0] {
+		z := bits.TrailingZeros64(n)
+		n >>= uint(z)
+		add(0, int64(z))
+	}
+
+	for j, d := range fb.divisors {
+		if n == 1 {
+			break
+		}
+		if known != nil && !known[j+1] {
+			continue
+		}
+
+		e := int64(0)
+		for n*d.inverse <= d.limit {
+			n *= d.inverse
+			e++
+		}
+		if e != 0 {
+			add(j+1, e)
+		}
+	}
+
+	return n
+}
+
+// uint128 is hi*2^64 + lo.
+type uint128 struct {
+	hi, lo uint64
+}
+
+func (u uint128) less(v uint128) bool {
+	return u.hi < v.hi || u.hi == v.hi && u.lo < v.lo
+}
+
+func (u uint128) sub(v uint128) uint128 {
+	lo, borrow := bits.Sub64(u.lo, v.lo, 0)
+	hi, _ := bits.Sub64(u.hi, v.hi, borrow)
+	return uint128{hi, lo}
+}
+
+// mul64 returns u*q mod 2^128.
+func (u uint128) mul64(q uint64) uint128 {
+	hi, lo := bits.Mul64(u.lo, q)
+	return uint128{hi + u.hi*q, lo}
+}
+
+// divMod returns u/v and u mod v for v > 0, if the quotient fits into
+// uint64, see Hacker's Delight, 9-5.
+func (u uint128) divMod(v uint128) (uint64, uint128) {
+	var q uint64
+	if v.hi == 0 {
+		q, _ = bits.Div64(u.hi, u.lo, v.lo)
+	} else {
+		// the estimate from the normalized top word of v is q or q+1
+		n := uint(bits.LeadingZeros64(v.hi))
+		v1 := v.hi<<n | v.lo>>(64-n)
+		q, _ = bits.Div64(u.hi>>1, u.hi<<63|u.lo>>1, v1)
+		if q >>= 63 - n; q != 0 {
+			q--
+		}
+		if !u.sub(v.mul64(q)).less(v) {
+			q++
+		}
+	}
+
+	return q, u.sub(v.mul64(q))
+}
+
+// reconstructor writes elements modulo p as fractions of small numbers.
+type reconstructor struct {
+	p     uint128
+	sqrtP uint64
+}
+
+func newReconstructor(p *big.Int) *reconstructor {
+	rc := &reconstructor{sqrtP: new(big.Int).Sqrt(p).Uint64()}
+	rc.p = rc.uint128(p)
+	return rc
+}
+
+// uint128 returns n < 2^128.
+func (rc *reconstructor) uint128(n *big.Int) uint128 {
+	hi := new(big.Int).Rsh(n, 64).Uint64()
+	lo := new(big.Int).And(n, new(big.Int).SetUint64(math.MaxUint64)).Uint64()
+	return uint128{hi, lo}
+}
+
+// reconstruct returns a, b <= sqrt(p) such that h = ±a/b mod p, see
+// the rational reconstruction in Shoup's "A Computational Introduction to
+// Number Theory and Algebra", 4.6. The numbers to factor are about sqrt(p)
+// instead of p, so they are much more likely to be smooth. The sign doesn't
+// matter, as -1 has order 2 and its logarithm is 0 modulo an odd q.
+//
+// p must be at most 128 bits for a and b to fit into uint64.
+func (rc *reconstructor) reconstruct(h *big.Int) (a, b uint64) {
+	// r_i = t_i * h mod p. The signs of t_i alternate, so
+	// |t_i+1| = |t_i-1| + q_i*|t_i|, and |t_i+1| <= p/r_i < 2^64 while
+	// r_i > sqrt(p), so q_i < 2^64 as well.
+	r0, r1 := rc.p, rc.uint128(h)
+	t0, t1 := uint64(0), uint64(1)
+
+	for r1.hi != 0 || r1.lo > rc.sqrtP {
+		q, r := r0.divMod(r1)
+		r0, r1 = r1, r
+		t0, t1 = t1, t0+q*t1
+	}
+
+	return r1.lo, t1
+}
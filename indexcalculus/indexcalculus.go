@@ -0,0 +1,360 @@
+// Package indexcalculus finds discrete logarithms in prime order subgroups
+// of the multiplicative group modulo a prime P by the index calculus.
+//
+// Unlike the generic algorithms of the challenges, which take O(sqrt(Q))
+// steps in a subgroup of order Q, it uses the primes: it finds the
+// logarithms of the small primes once, and then of any element which is a
+// product of them. That takes subexponential time in the size of P, which is
+// why P has to be so much larger than the order of an elliptic curve group
+// of the same strength.
+package indexcalculus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	"github.com/svkirillov/cryptopals-go/progress"
+)
+
+// maxBits is the size of the largest P. The fractions of the elements
+// modulo P have to fit into uint64, and the larger ones take too long
+// anyway.
+const maxBits = 128
+
+// candidatesPerEvent is the number of elements tested for smoothness between
+// the progress reports.
+const candidatesPerEvent = 1 << 16
+
+// ErrNotInSubgroup is returned if the element isn't in the subgroup
+// generated by G.
+var ErrNotInSubgroup = errors.New("indexcalculus: element isn't in the subgroup")
+
+// Config is the parameters of the index calculus. The zero value picks them
+// from the size of P.
+type Config struct {
+	// Bound is the bound of the primes in the factor base. The larger it is,
+	// the more smooth elements there are, but the more relations between
+	// them have to be found. It's chosen from the size of P if zero.
+	Bound uint64
+
+	// Excess is the number of relations found beyond the size of the factor
+	// base, 16 if not positive. Some relations depend on the others, so a
+	// few more are needed for the logarithms of most primes to be known.
+	Excess int
+}
+
+// OrDefault returns c, or the zero Config if c is nil.
+func OrDefault(c *Config) *Config {
+	if c == nil {
+		return &Config{}
+	}
+	return c
+}
+
+// bound returns the bound of the factor base for a P of the given size.
+func (c *Config) bound(bits int) uint64 {
+	if c.Bound != 0 {
+		return c.Bound
+	}
+
+	// The fractions are about sqrt(P), and (bits/2) / log2(bound) about 3.4
+	// balances the cost of finding the relations and of solving them: 2^10
+	// for 64 bits, 2^14 for 96 bits.
+	log2 := bits/7 + 1
+	if log2 < 8 {
+		log2 = 8
+	}
+	return 1 << uint(log2)
+}
+
+func (c *Config) excess() int {
+	if c.Excess <= 0 {
+		return 16
+	}
+	return c.Excess
+}
+
+// Logs is the logarithms of the primes in the factor base of a group, which
+// are the same for all the elements of the group.
+type Logs struct {
+	group *dh.GroupParams
+	base  *factorBase
+
+	// logs are the logarithms of the primes, or nils for the unknown ones.
+	logs  []*big.Int
+	known []bool
+}
+
+// checkGroup checks that the logarithms of the primes are defined in the
+// group. A prime l isn't in the subgroup of G in general, but if Q^2 doesn't
+// divide P-1, then l^((P-1)/Q) is, and its logarithm divided by (P-1)/Q is
+// the "virtual" logarithm of l modulo Q: the logarithms of a product and of
+// its factors add up, which is all the index calculus needs.
+func checkGroup(group *dh.GroupParams) error {
+	if group.P.BitLen() > maxBits {
+		return fmt.Errorf("indexcalculus: %d-bit P is too large", group.P.BitLen())
+	}
+	if group.Q.Bit(0) == 0 || !group.Q.ProbablyPrime(20) {
+		return fmt.Errorf("indexcalculus: Q = %d isn't an odd prime", group.Q)
+	}
+
+	pMinusOne := new(big.Int).Sub(group.P, helpers.BigOne)
+	if new(big.Int).Mod(pMinusOne, group.Q).Sign() != 0 {
+		return fmt.Errorf("indexcalculus: Q = %d doesn't divide P-1", group.Q)
+	}
+	if new(big.Int).Mod(pMinusOne, new(big.Int).Mul(group.Q, group.Q)).Sign() == 0 {
+		return fmt.Errorf("indexcalculus: Q^2 divides P-1")
+	}
+	if new(big.Int).Exp(group.G, group.Q, group.P).Cmp(helpers.BigOne) != 0 || group.G.Cmp(helpers.BigOne) == 0 {
+		return fmt.Errorf("indexcalculus: G isn't of order Q")
+	}
+
+	return nil
+}
+
+func note(events progress.Reporter, format string, a ...interface{}) {
+	events.Report(progress.Event{Kind: progress.Note, Message: fmt.Sprintf(format, a...)})
+}
+
+// Precompute finds the logarithms of the primes in the factor base of the
+// group with cfg, which may be nil. The group has to be of prime order Q
+// such that Q^2 doesn't divide P-1. It returns ctx.Err() once ctx is done.
+// The random elements are drawn from rng, or from crypto/rand.Reader if rng
+// is nil, and the progress is reported to events, which may be nil.
+func Precompute(ctx context.Context, group *dh.GroupParams, cfg *Config, rng io.Reader, events progress.Reporter) (*Logs, error) {
+	cfg = OrDefault(cfg)
+	events = progress.OrDiscard(events)
+
+	if err := checkGroup(group); err != nil {
+		return nil, err
+	}
+
+	bound := cfg.bound(group.P.BitLen())
+	if bound < 3 || bound > 1<<32 {
+		return nil, fmt.Errorf("indexcalculus: bad bound %d", bound)
+	}
+
+	base := newFactorBase(bound)
+	n := len(base.primes)
+	note(events, "factor base of %d primes below %d", n, bound)
+
+	rows, err := collectRelations(ctx, group, base, n+cfg.excess(), rng, events)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := solve(ctx, rows, n, group.Q)
+	if err != nil {
+		return nil, err
+	}
+
+	// l^((P-1)/Q) = G^(log(l) * (P-1)/Q), see checkGroup
+	e := new(big.Int).Sub(group.P, helpers.BigOne)
+	e.Div(e, group.Q)
+	gE := new(big.Int).Exp(group.G, e, group.P)
+
+	l := &Logs{group: group, base: base, logs: logs, known: make([]bool, n)}
+	lE, tmp := new(big.Int), new(big.Int)
+	count := 0
+
+	for i, prime := range base.primes {
+		if logs[i] == nil {
+			continue
+		}
+		if lE.Exp(tmp.SetUint64(prime), e, group.P).Cmp(tmp.Exp(gE, logs[i], group.P)) != 0 {
+			logs[i] = nil
+			continue
+		}
+
+		l.known[i] = true
+		count++
+	}
+	note(events, "the logarithms of %d of %d primes are known", count, n)
+
+	if count == 0 {
+		return nil, errors.New("indexcalculus: no logarithms of the primes are known")
+	}
+
+	return l, nil
+}
+
+// collectRelations returns the relations G^k = a/b for smooth a and b as
+// equations for the logarithms of the primes:
+//
+//	k = sum(e_i * log(l_i)) - sum(f_i * log(l_i)) mod Q
+//
+// where a and b are the products of l_i^e_i and l_i^f_i.
+func collectRelations(ctx context.Context, group *dh.GroupParams, base *factorBase, count int, rng io.Reader, events progress.Reporter) ([]*row, error) {
+	k, err := helpers.GenerateBigInt(rng, group.Q)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate random big.Int: %s", err.Error())
+	}
+
+	h := new(big.Int).Exp(group.G, k, group.P)
+	rc := newReconstructor(group.P)
+
+	var rows []*row
+	var candidates uint64
+
+	for len(rows) < count {
+		// h = G^k
+		h.Mul(h, group.G).Mod(h, group.P)
+		k.Add(k, helpers.BigOne)
+
+		a, b := rc.reconstruct(h)
+		if base.smooth(a) && base.smooth(b) {
+			exps := make(map[int]int64)
+			base.factor(a, 1, nil, exps)
+			base.factor(b, -1, nil, exps)
+
+			r := &row{coefs: make(map[int]*big.Int, len(exps)), rhs: new(big.Int).Mod(k, group.Q)}
+			for i, e := range exps {
+				r.coefs[i] = new(big.Int).Mod(big.NewInt(e), group.Q)
+			}
+			rows = append(rows, r)
+		}
+
+		if candidates++; candidates%candidatesPerEvent == 0 {
+			note(events, "%d of %d relations in %d elements", len(rows), count, candidates)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// pass
+		}
+	}
+	note(events, "%d relations in %d elements", len(rows), candidates)
+
+	return rows, nil
+}
+
+// Known returns the number of primes in the factor base whose logarithms are
+// known.
+func (l *Logs) Known() int {
+	count := 0
+	for _, known := range l.known {
+		if known {
+			count++
+		}
+	}
+	return count
+}
+
+// find looks for s such that h*G^s = a/b where a and b are the products of
+// the primes with known logarithms and of the rest, which is below maxRest.
+// It returns the sum of the logarithms of the known primes, minus s, and the
+// rests of a and b.
+func (l *Logs) find(ctx context.Context, h *big.Int, maxRest uint64, rng io.Reader) (*big.Int, uint64, uint64, error) {
+	group := l.group
+
+	s, err := helpers.GenerateBigInt(rng, group.Q)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("couldn't generate random big.Int: %s", err.Error())
+	}
+
+	hs := new(big.Int).Exp(group.G, s, group.P)
+	hs.Mul(hs, h).Mod(hs, group.P)
+	rc := newReconstructor(group.P)
+
+	for {
+		// hs = h*G^s
+		hs.Mul(hs, group.G).Mod(hs, group.P)
+		s.Add(s, helpers.BigOne)
+
+		a, b := rc.reconstruct(hs)
+		exps := make(map[int]int64)
+
+		restA := l.base.factor(a, 1, l.known, exps)
+		if restA <= maxRest {
+			if restB := l.base.factor(b, -1, l.known, exps); restB <= maxRest {
+				sum := new(big.Int).Neg(s)
+				for i, e := range exps {
+					sum.Add(sum, new(big.Int).Mul(big.NewInt(e), l.logs[i]))
+				}
+				return sum.Mod(sum, group.Q), restA, restB, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, 0, 0, ctx.Err()
+		default:
+			// pass
+		}
+	}
+}
+
+// descend returns the logarithm of n by finding n*G^t which is smooth over
+// the known primes.
+func (l *Logs) descend(ctx context.Context, n uint64, rng io.Reader) (*big.Int, error) {
+	if n == 1 {
+		return new(big.Int), nil
+	}
+
+	log, _, _, err := l.find(ctx, new(big.Int).SetUint64(n), 1, rng)
+	return log, err
+}
+
+// Log returns x in [0, Q) such that G^x = y mod P, or ErrNotInSubgroup if
+// there isn't one. It finds y*G^s = a/b where a and b are products of the
+// known primes and of rests below the square of the bound of the factor
+// base, which are much more likely than smooth ones, and then descends to
+// the logarithms of the rests the same way. It returns ctx.Err() once ctx is
+// done. The random elements are drawn from rng, or from crypto/rand.Reader
+// if rng is nil, and the progress is reported to events, which may be nil.
+func (l *Logs) Log(ctx context.Context, y *big.Int, rng io.Reader, events progress.Reporter) (*big.Int, error) {
+	events = progress.OrDiscard(events)
+	group := l.group
+
+	if y.Sign() <= 0 || y.Cmp(group.P) >= 0 || new(big.Int).Exp(y, group.Q, group.P).Cmp(helpers.BigOne) != 0 {
+		return nil, ErrNotInSubgroup
+	}
+
+	maxRest := uint64(math.MaxUint64)
+	if bound := l.base.primes[len(l.base.primes)-1]; bound < 1<<32 {
+		maxRest = bound * bound
+	}
+
+	x, restA, restB, err := l.find(ctx, y, maxRest, rng)
+	if err != nil {
+		return nil, err
+	}
+	note(events, "y*G^s is smooth but for %d and %d", restA, restB)
+
+	// log(y) = log(a) - log(b) - s
+	logA, err := l.descend(ctx, restA, rng)
+	if err != nil {
+		return nil, err
+	}
+	logB, err := l.descend(ctx, restB, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	x.Add(x, logA).Sub(x, logB).Mod(x, group.Q)
+
+	if new(big.Int).Exp(group.G, x, group.P).Cmp(y) != 0 {
+		return nil, fmt.Errorf("indexcalculus: wrong logarithm %d", x)
+	}
+
+	return x, nil
+}
+
+// Log returns x in [0, Q) such that G^x = y mod P with the index calculus,
+// see Precompute and Logs.Log.
+func Log(ctx context.Context, group *dh.GroupParams, y *big.Int, cfg *Config, rng io.Reader, events progress.Reporter) (*big.Int, error) {
+	logs, err := Precompute(ctx, group, cfg, rng, events)
+	if err != nil {
+		return nil, err
+	}
+
+	return logs.Log(ctx, y, rng, events)
+}
@@ -0,0 +1,141 @@
+package indexcalculus
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+// groups are the sizes of P with a subgroup of order Q 16 bits shorter.
+var groups = []int{64, 80, 96}
+
+func generateGroup(t *testing.T, bits int) *dh.GroupParams {
+	group, _, err := dh.GenerateWeakGroup(helpers.NewSeededReader(int64(bits)), bits, bits-16, big.NewInt(1<<12))
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	return group
+}
+
+func TestLog(t *testing.T) {
+	for _, bits := range groups {
+		group := generateGroup(t, bits)
+		rng := helpers.NewSeededReader(48)
+
+		logs, err := Precompute(context.Background(), group, nil, rng, nil)
+		if err != nil {
+			t.Fatalf("%s: %d bits: %s", t.Name(), bits, err.Error())
+		}
+		t.Logf("%s: %d bits: the logarithms of %d primes are known", t.Name(), bits, logs.Known())
+
+		// the logarithms of the primes are good for every key
+		for i := 0; i < 3; i++ {
+			key, err := group.GenerateKey(rng)
+			if err != nil {
+				t.Fatalf("%s: %s", t.Name(), err.Error())
+			}
+
+			x, err := logs.Log(context.Background(), key.Public, rng, nil)
+			if err != nil {
+				t.Fatalf("%s: %d bits: %s", t.Name(), bits, err.Error())
+			}
+			if x.Cmp(key.Private) != 0 {
+				t.Errorf("%s: %d bits: got %d, want %d", t.Name(), bits, x, key.Private)
+			}
+		}
+	}
+}
+
+func TestLogErrors(t *testing.T) {
+	group := generateGroup(t, 64)
+
+	// 2 is rarely in the subgroup of order Q
+	if _, err := Log(context.Background(), group, big.NewInt(2), nil, nil, nil); !errors.Is(err, ErrNotInSubgroup) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, ErrNotInSubgroup)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Log(ctx, group, group.G, nil, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+
+	// the virtual logarithms aren't defined modulo Q = 2
+	bad := *group
+	bad.Q = big.NewInt(2)
+	bad.G = new(big.Int).Sub(group.P, helpers.BigOne)
+
+	if _, err := Log(context.Background(), &bad, bad.G, nil, nil, nil); err == nil {
+		t.Errorf("%s: no error for Q = 2", t.Name())
+	}
+}
+
+func TestReconstruct(t *testing.T) {
+	rng := helpers.NewSeededReader(1)
+
+	for _, bits := range []int{32, 64, 96, 128} {
+		p, err := helpers.GeneratePrime(rng, bits)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+
+		rc := newReconstructor(p)
+		sqrtP := new(big.Int).Sqrt(p)
+
+		for i := 0; i < 1000; i++ {
+			h, err := helpers.GenerateBigInt(rng, p)
+			if err != nil {
+				t.Fatalf("%s: %s", t.Name(), err.Error())
+			}
+			if h.Sign() == 0 {
+				continue
+			}
+
+			a, b := rc.reconstruct(h)
+			bigA := new(big.Int).SetUint64(a)
+			bigB := new(big.Int).SetUint64(b)
+
+			if bigA.Cmp(sqrtP) > 0 || bigB.Cmp(sqrtP) > 0 {
+				t.Fatalf("%s: %d bits: %d/%d is above sqrt(p)", t.Name(), bits, a, b)
+			}
+
+			// h*b = ±a mod p
+			hb := new(big.Int).Mul(h, bigB)
+			hb.Mod(hb, p)
+			if hb.Cmp(bigA) != 0 && hb.Add(hb, bigA).Cmp(p) != 0 {
+				t.Fatalf("%s: %d bits: %d isn't ±%d/%d", t.Name(), bits, h, a, b)
+			}
+		}
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	fb := newFactorBase(1 << 10)
+
+	for _, e := range []struct {
+		n      uint64
+		smooth bool
+	}{
+		{1, true},
+		{2 * 3 * 1021, true},
+		{1 << 63, true},
+		{3486784401 * 1021 * 1019, true},
+		{1031, false},
+		{3 * 1031, false},
+		{1021 * 1031, false},
+	} {
+		if fb.smooth(e.n) != e.smooth {
+			t.Errorf("%s: smooth(%d) = %t, want %t", t.Name(), e.n, !e.smooth, e.smooth)
+		}
+
+		exps := make(map[int]int64)
+		if rest := fb.factor(e.n, 1, nil, exps); (rest == 1) != e.smooth {
+			t.Errorf("%s: factor(%d) leaves %d", t.Name(), e.n, rest)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package indexcalculus
+
+import (
+	"context"
+	"math/big"
+)
+
+// row is a sparse linear equation sum(coefs[j] * L_j) = rhs modulo q. The
+// coefficients aren't reduced modulo q until they are needed, as most of
+// them are only subtracted from, so some of them may be 0 modulo q.
+type row struct {
+	coefs map[int]*big.Int
+	rhs   *big.Int
+}
+
+// subMul sets r to r - f*s, where s is reduced.
+func (r *row) subMul(f *big.Int, s *row) {
+	tmp := new(big.Int)
+
+	for j, c := range s.coefs {
+		rc, ok := r.coefs[j]
+		if !ok {
+			rc = new(big.Int)
+			r.coefs[j] = rc
+		}
+		rc.Sub(rc, tmp.Mul(f, c))
+	}
+
+	r.rhs.Sub(r.rhs, tmp.Mul(f, s.rhs))
+}
+
+// reduce reduces r modulo q, dropping the zero coefficients.
+func (r *row) reduce(q *big.Int) {
+	for j, c := range r.coefs {
+		if c.Mod(c, q).Sign() == 0 {
+			delete(r.coefs, j)
+		}
+	}
+	r.rhs.Mod(r.rhs, q)
+}
+
+// scale multiplies the reduced r by f modulo q.
+func (r *row) scale(f *big.Int, q *big.Int) {
+	for _, c := range r.coefs {
+		c.Mul(c, f).Mod(c, q)
+	}
+	r.rhs.Mul(r.rhs, f).Mod(r.rhs, q)
+}
+
+// solve solves the equations for the n unknowns modulo the prime q. It
+// returns the unknowns which are determined by the equations and nil for
+// the rest, or ctx.Err() once ctx is done. The equations are modified.
+//
+// It's the Gaussian elimination with the pivots picked to keep the rows
+// sparse, after Markowitz: the next unknown to eliminate is the one in the
+// fewest rows, and the pivot is the shortest of them. The large primes are
+// in few relations, so most of the fill-in goes to the small ones, which
+// are in most rows anyway.
+func solve(ctx context.Context, rows []*row, n int, q *big.Int) ([]*big.Int, error) {
+	// rowsOf[j] are the rows with L_j which aren't pivots yet
+	rowsOf := make([]map[int]bool, n)
+	for j := range rowsOf {
+		rowsOf[j] = make(map[int]bool)
+	}
+	for i, r := range rows {
+		for j := range r.coefs {
+			rowsOf[j][i] = true
+		}
+	}
+
+	pivots := make([]*row, n)
+	var order []int
+
+	for {
+		j := -1
+		for k, rs := range rowsOf {
+			if pivots[k] == nil && len(rs) > 0 && (j == -1 || len(rs) < len(rowsOf[j])) {
+				j = k
+			}
+		}
+		if j == -1 {
+			break
+		}
+
+		pivot := -1
+		for i := range rowsOf[j] {
+			if c := rows[i].coefs[j]; c.Mod(c, q).Sign() == 0 {
+				delete(rows[i].coefs, j)
+				delete(rowsOf[j], i)
+				continue
+			}
+			if pivot == -1 || len(rows[i].coefs) < len(rows[pivot].coefs) {
+				pivot = i
+			}
+		}
+		if pivot == -1 {
+			continue
+		}
+
+		p := rows[pivot]
+		pivots[j] = p
+		order = append(order, j)
+		for k := range p.coefs {
+			delete(rowsOf[k], pivot)
+		}
+
+		p.reduce(q)
+		p.scale(new(big.Int).ModInverse(p.coefs[j], q), q)
+
+		for i := range rowsOf[j] {
+			r := rows[i]
+			f := new(big.Int).Mod(r.coefs[j], q)
+
+			// L_j is gone even if its coefficient isn't 0 before the
+			// reduction, and only the other unknowns of the pivot come
+			delete(r.coefs, j)
+			delete(rowsOf[j], i)
+			if f.Sign() == 0 {
+				continue
+			}
+
+			r.subMul(f, p)
+			delete(r.coefs, j)
+			for k := range p.coefs {
+				if k != j {
+					rowsOf[k][i] = true
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// pass
+		}
+	}
+
+	// The pivot row of L_j has L_j, the unknowns eliminated after it and
+	// the unknowns without a pivot, which aren't determined.
+	res := make([]*big.Int, n)
+	tmp := new(big.Int)
+
+	for o := len(order) - 1; o >= 0; o-- {
+		j := order[o]
+		p := pivots[j]
+
+		l := new(big.Int).Set(p.rhs)
+		for k, c := range p.coefs {
+			if k == j {
+				continue
+			}
+			if res[k] == nil {
+				l = nil
+				break
+			}
+			l.Sub(l, tmp.Mul(c, res[k]))
+		}
+
+		if l != nil {
+			res[j] = l.Mod(l, q)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,221 @@
+// Package kangaroo derives the parameters of Pollard's method for catching
+// kangaroos, which finds a logarithm in an interval [a, b]. They are shared
+// by the attacks on finite field DH and on elliptic curves.
+package kangaroo
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+// Jumps is the pseudorandom map from the position of a kangaroo to the
+// length of his next jump. The position is a group element for finite
+// field DH and the x coordinate of the point for elliptic curves.
+type Jumps interface {
+	// Jump returns the length of the jump from y. The result must not be
+	// modified.
+	Jump(y *big.Int) *big.Int
+
+	// Mean returns the mean length of the jumps.
+	Mean() *big.Int
+
+	// String identifies the jumps, the trap of the tame kangaroo is only
+	// good for the wild kangaroos with the same jumps.
+	String() string
+}
+
+// NewJumps returns the jumps for an interval of the given width in the
+// given attempt, starting with 0. Each attempt should use other jumps, so
+// that a wild kangaroo who escaped once may be caught the next time.
+type NewJumps func(width *big.Int, attempt int) (Jumps, error)
+
+// log2 returns the binary logarithm of n > 0.
+func log2(n *big.Int) float64 {
+	// the top 64 bits are as precise as a float64 gets
+	shift := n.BitLen() - 64
+	if shift < 0 {
+		shift = 0
+	}
+	top := new(big.Int).Rsh(n, uint(shift)).Uint64()
+	return math.Log2(float64(top)) + float64(shift)
+}
+
+// K returns the number of jumps of different lengths for an interval of the
+// given width, see https://arxiv.org/pdf/0812.0789.pdf:
+//
+//	k = log2(sqrt(b-a)) + log2(log2(sqrt(b-a))) - 2
+//
+// It's at least 1.
+func K(width *big.Int) int {
+	if width.Cmp(big.NewInt(4)) <= 0 {
+		return 1
+	}
+
+	logSqrt := log2(width) / 2
+	k := int(logSqrt + math.Log2(logSqrt) - 2)
+	if k < 1 {
+		return 1
+	}
+	return k
+}
+
+type powersOfTwo struct {
+	k    *big.Int
+	mean *big.Int
+}
+
+// PowersOfTwo jumps 2^(y mod k) like in the challenges, with k = K(width) in
+// the first attempt and one more in each next attempt.
+func PowersOfTwo(width *big.Int, attempt int) (Jumps, error) {
+	k := K(width) + attempt
+
+	// mean = (2^0 + 2^1 + ... + 2^(k-1)) / k
+	mean := new(big.Int).Lsh(helpers.BigOne, uint(k))
+	mean.Sub(mean, helpers.BigOne).Div(mean, big.NewInt(int64(k)))
+
+	return &powersOfTwo{k: big.NewInt(int64(k)), mean: mean}, nil
+}
+
+func (j *powersOfTwo) Jump(y *big.Int) *big.Int {
+	return new(big.Int).Lsh(helpers.BigOne, uint(new(big.Int).Mod(y, j.k).Uint64()))
+}
+
+func (j *powersOfTwo) Mean() *big.Int {
+	return j.mean
+}
+
+func (j *powersOfTwo) String() string {
+	return fmt.Sprintf("2^(y mod %d)", j.k)
+}
+
+type table struct {
+	jumps []*big.Int
+	k     *big.Int
+	mean  *big.Int
+}
+
+// RandomTable returns NewJumps which draws K(width) jumps from rng, or from
+// crypto/rand.Reader if rng is nil, for each attempt. The jumps are uniform
+// in [1, sqrt(width)), so their mean is about sqrt(width)/2, the optimal
+// one.
+func RandomTable(rng io.Reader) NewJumps {
+	return func(width *big.Int, attempt int) (Jumps, error) {
+		k := K(width)
+
+		max := new(big.Int).Sqrt(width)
+		if max.Cmp(helpers.BigOne) <= 0 {
+			max.Set(helpers.BigTwo)
+		}
+		max.Sub(max, helpers.BigOne)
+
+		t := &table{k: big.NewInt(int64(k)), mean: new(big.Int)}
+		for i := 0; i < k; i++ {
+			jump, err := helpers.GenerateBigInt(rng, max)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't generate random big.Int: %s", err.Error())
+			}
+			jump.Add(jump, helpers.BigOne)
+
+			t.jumps = append(t.jumps, jump)
+			t.mean.Add(t.mean, jump)
+		}
+		t.mean.Div(t.mean, t.k)
+
+		return t, nil
+	}
+}
+
+func (t *table) Jump(y *big.Int) *big.Int {
+	return t.jumps[new(big.Int).Mod(y, t.k).Uint64()]
+}
+
+func (t *table) Mean() *big.Int {
+	return t.mean
+}
+
+func (t *table) String() string {
+	var jumps []string
+	for _, jump := range t.jumps {
+		jumps = append(jumps, jump.Text(16))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(jumps, " ")))
+
+	return fmt.Sprintf("table of %d jumps with mean %d (%x)", len(t.jumps), t.mean, sum[:8])
+}
+
+// Config is how the kangaroos jump. The zero value is the configuration of
+// the challenges with the default number of attempts.
+type Config struct {
+	// Jumps returns the jumps for each attempt, PowersOfTwo if nil.
+	Jumps NewJumps
+
+	// Factor is the number of mean jumps the tame kangaroo travels, 4 if
+	// not positive.
+	Factor int64
+
+	// Attempts is the number of times the kangaroos are run with new jumps
+	// before giving up, 3 if not positive.
+	Attempts int
+}
+
+// OrDefault returns c, or the zero Config if c is nil.
+func OrDefault(c *Config) *Config {
+	if c == nil {
+		return &Config{}
+	}
+	return c
+}
+
+// MaxAttempts returns the number of times the kangaroos are run.
+func (c *Config) MaxAttempts() int {
+	if c.Attempts <= 0 {
+		return 3
+	}
+	return c.Attempts
+}
+
+// Params are the parameters of the kangaroos in one attempt.
+type Params struct {
+	Jumps Jumps
+
+	// N is the number of jumps of the tame kangaroo.
+	N *big.Int
+}
+
+// Params returns the parameters of the given attempt, starting with 0, to
+// find a logarithm in [a, b].
+func (c *Config) Params(a, b *big.Int, attempt int) (*Params, error) {
+	width := new(big.Int).Sub(b, a)
+	if width.Sign() <= 0 {
+		return nil, fmt.Errorf("kangaroo: empty interval [%d, %d]", a, b)
+	}
+
+	newJumps := c.Jumps
+	if newJumps == nil {
+		newJumps = PowersOfTwo
+	}
+
+	jumps, err := newJumps(width, attempt)
+	if err != nil {
+		return nil, fmt.Errorf("kangaroo: %w", err)
+	}
+
+	factor := c.Factor
+	if factor <= 0 {
+		factor = 4
+	}
+
+	// see for details: docs/challenge58.txt:99
+	n := new(big.Int).Mul(jumps.Mean(), big.NewInt(factor))
+	if n.Sign() == 0 {
+		n.SetInt64(factor)
+	}
+
+	return &Params{Jumps: jumps, N: n}, nil
+}
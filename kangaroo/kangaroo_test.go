@@ -0,0 +1,107 @@
+package kangaroo
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+func TestK(t *testing.T) {
+	// the float formula of the challenges is right for small intervals
+	for _, w := range []uint64{1 << 10, 1 << 20, 12345678, 1<<40 + 17, 1 << 52} {
+		logSqrt := math.Log2(math.Sqrt(float64(w)))
+		want := int(logSqrt + math.Log2(logSqrt) - 2)
+
+		if k := K(new(big.Int).SetUint64(w)); k != want {
+			t.Errorf("%s: K(%d) = %d, want %d", t.Name(), w, k, want)
+		}
+	}
+
+	// 50 + log2(50) - 2, the float formula would take the low 64 bits
+	if k := K(new(big.Int).Lsh(helpers.BigOne, 100)); k != 53 {
+		t.Errorf("%s: K(2^100) = %d, want 53", t.Name(), k)
+	}
+
+	if k := K(big.NewInt(3)); k != 1 {
+		t.Errorf("%s: K(3) = %d, want 1", t.Name(), k)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	a := big.NewInt(0)
+	b := big.NewInt(1 << 20)
+
+	var cfg Config
+
+	params, err := cfg.Params(a, b, 0)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	// k = 11, the tame kangaroo makes 4 * (2^11 - 1) / 11 jumps
+	if s := params.Jumps.String(); s != "2^(y mod 11)" {
+		t.Errorf("%s: got jumps %s, want 2^(y mod 11)", t.Name(), s)
+	}
+	if params.N.Int64() != 4*((1<<11-1)/11) {
+		t.Errorf("%s: N = %d, want %d", t.Name(), params.N, 4*((1<<11-1)/11))
+	}
+	if jump := params.Jumps.Jump(big.NewInt(25)); jump.Int64() != 1<<3 {
+		t.Errorf("%s: got jump %d from 25, want 8", t.Name(), jump)
+	}
+
+	next, err := cfg.Params(a, b, 1)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if next.Jumps.String() == params.Jumps.String() {
+		t.Errorf("%s: the next attempt jumps %s again", t.Name(), next.Jumps)
+	}
+
+	if _, err := cfg.Params(b, a, 0); err == nil {
+		t.Errorf("%s: the parameters of an empty interval", t.Name())
+	}
+}
+
+func TestRandomTable(t *testing.T) {
+	width := new(big.Int).Lsh(helpers.BigOne, 80)
+	max := new(big.Int).Lsh(helpers.BigOne, 40)
+
+	cfg := Config{Jumps: RandomTable(helpers.NewSeededReader(1)), Factor: 8}
+
+	params, err := cfg.Params(helpers.BigZero, width, 0)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	table := params.Jumps.(*table)
+	if len(table.jumps) != K(width) {
+		t.Fatalf("%s: %d jumps, want %d", t.Name(), len(table.jumps), K(width))
+	}
+	for _, jump := range table.jumps {
+		if jump.Sign() <= 0 || jump.Cmp(max) >= 0 {
+			t.Errorf("%s: jump %d isn't in [1, 2^40)", t.Name(), jump)
+		}
+	}
+	if params.N.Cmp(new(big.Int).Mul(params.Jumps.Mean(), big.NewInt(8))) != 0 {
+		t.Errorf("%s: N = %d, want 8 mean jumps", t.Name(), params.N)
+	}
+
+	// the same seed gives the same table
+	same, err := (&Config{Jumps: RandomTable(helpers.NewSeededReader(1))}).Params(helpers.BigZero, width, 0)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if same.Jumps.String() != params.Jumps.String() {
+		t.Errorf("%s: got %s with the same seed, want %s", t.Name(), same.Jumps, params.Jumps)
+	}
+
+	next, err := cfg.Params(helpers.BigZero, width, 1)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	if next.Jumps.String() == params.Jumps.String() {
+		t.Errorf("%s: the next attempt jumps %s again", t.Name(), next.Jumps)
+	}
+}
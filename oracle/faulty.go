@@ -0,0 +1,237 @@
+package oracle
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrDropped is returned by a faulty oracle instead of a lost answer.
+var ErrDropped = errors.New("oracle: answer dropped")
+
+// ErrTimeout is returned on replay instead of a recorded timeout.
+var ErrTimeout = errors.New("oracle: timeout")
+
+// IsTransient reports whether a query which failed with err may succeed if
+// it's repeated, e.g. if the answer was dropped or timed out.
+func IsTransient(err error) bool {
+	if errors.Is(err, ErrDropped) || errors.Is(err, ErrNoMajority) || errors.Is(err, ErrTimeout) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Faults are the rates of the faults injected into the answers of an
+// oracle. Each rate is a probability between 0 and 1.
+type Faults struct {
+	Drop     float64       // the answer is lost and ErrDropped is returned
+	Corrupt  float64       // the answer is replaced by random bytes
+	Delay    float64       // the answer is delayed
+	MaxDelay time.Duration // the maximum delay, delays are uniform in [0, MaxDelay)
+}
+
+// FaultStats are the numbers of faults injected so far.
+type FaultStats struct {
+	Dropped   int
+	Corrupted int
+	Delayed   int
+}
+
+// Injector injects faults into the answers of the oracles it wraps. An
+// Injector may be shared between several oracles.
+type Injector struct {
+	faults Faults
+
+	mu    sync.Mutex
+	rng   io.Reader
+	stats FaultStats
+}
+
+// NewInjector returns an Injector with the given fault rates. The faults are
+// drawn from rng, or from crypto/rand.Reader if rng is nil.
+func NewInjector(faults Faults, rng io.Reader) *Injector {
+	if rng == nil {
+		rng = rand.Reader
+	}
+
+	return &Injector{faults: faults, rng: rng}
+}
+
+// Stats returns the numbers of faults injected so far.
+func (i *Injector) Stats() FaultStats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.stats
+}
+
+// inject asks the oracle through f and spoils the answer.
+func (i *Injector) inject(f func() ([]byte, error)) ([]byte, error) {
+	delay, drop, corrupt, err := i.roll()
+	if err != nil {
+		return nil, err
+	}
+
+	time.Sleep(delay)
+
+	res, err := f()
+	switch {
+	case err != nil:
+		return nil, err
+	case drop:
+		return nil, ErrDropped
+	case corrupt:
+		// rejections are corrupted into tag sized garbage
+		n := len(res)
+		if n == 0 {
+			n = sha256.Size
+		}
+		return i.garbage(n)
+	}
+
+	return res, nil
+}
+
+// roll decides which faults are injected into an answer.
+func (i *Injector) roll() (delay time.Duration, drop, corrupt bool, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delayed, err := i.happens(i.faults.Delay)
+	if err != nil {
+		return 0, false, false, err
+	}
+	if delayed && i.faults.MaxDelay > 0 {
+		u, err := i.uniform()
+		if err != nil {
+			return 0, false, false, err
+		}
+		delay = time.Duration(u * float64(i.faults.MaxDelay))
+		i.stats.Delayed++
+	}
+
+	if drop, err = i.happens(i.faults.Drop); err != nil {
+		return 0, false, false, err
+	}
+	if drop {
+		i.stats.Dropped++
+		return delay, true, false, nil
+	}
+
+	if corrupt, err = i.happens(i.faults.Corrupt); err != nil {
+		return 0, false, false, err
+	}
+	if corrupt {
+		i.stats.Corrupted++
+	}
+
+	return delay, false, corrupt, nil
+}
+
+// garbage returns n random bytes.
+func (i *Injector) garbage(n int) ([]byte, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(i.rng, buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// happens reports whether an event of probability rate happens.
+func (i *Injector) happens(rate float64) (bool, error) {
+	if rate <= 0 {
+		return false, nil
+	}
+
+	u, err := i.uniform()
+	if err != nil {
+		return false, err
+	}
+
+	return u < rate, nil
+}
+
+// uniform returns a uniform random number in [0, 1).
+func (i *Injector) uniform() (float64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(i.rng, buf[:]); err != nil {
+		return 0, err
+	}
+
+	return float64(binary.BigEndian.Uint64(buf[:])>>11) / (1 << 53), nil
+}
+
+// FaultyDHOracle injects faults into the answers of a DHOracle.
+type FaultyDHOracle struct {
+	*Injector
+	oracle DHOracle
+}
+
+// NewFaultyDHOracle wraps the oracle with the injector.
+func NewFaultyDHOracle(oracle DHOracle, injector *Injector) *FaultyDHOracle {
+	return &FaultyDHOracle{Injector: injector, oracle: oracle}
+}
+
+func (o *FaultyDHOracle) DH(publicKey *big.Int) ([]byte, error) {
+	return o.inject(func() ([]byte, error) {
+		return o.oracle.DH(publicKey)
+	})
+}
+
+func (o *FaultyDHOracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// FaultyECDHOracle injects faults into the answers of an ECDHOracle.
+type FaultyECDHOracle struct {
+	*Injector
+	oracle ECDHOracle
+}
+
+// NewFaultyECDHOracle wraps the oracle with the injector.
+func NewFaultyECDHOracle(oracle ECDHOracle, injector *Injector) *FaultyECDHOracle {
+	return &FaultyECDHOracle{Injector: injector, oracle: oracle}
+}
+
+func (o *FaultyECDHOracle) ECDH(x, y *big.Int) ([]byte, error) {
+	return o.inject(func() ([]byte, error) {
+		return o.oracle.ECDH(x, y)
+	})
+}
+
+func (o *FaultyECDHOracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// FaultyX128Oracle injects faults into the answers of an X128Oracle.
+type FaultyX128Oracle struct {
+	*Injector
+	oracle X128Oracle
+}
+
+// NewFaultyX128Oracle wraps the oracle with the injector.
+func NewFaultyX128Oracle(oracle X128Oracle, injector *Injector) *FaultyX128Oracle {
+	return &FaultyX128Oracle{Injector: injector, oracle: oracle}
+}
+
+func (o *FaultyX128Oracle) X128DH(u *big.Int) ([]byte, error) {
+	return o.inject(func() ([]byte, error) {
+		return o.oracle.X128DH(u)
+	})
+}
+
+func (o *FaultyX128Oracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
@@ -0,0 +1,69 @@
+package oracle
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+func TestFaultyOracle(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+	rng := helpers.NewSeededReader(38)
+	bob := NewDHAttackOracle(dhGroup, dh.RangeCheck, rng)
+	g := dhGroup.DHParams().G
+
+	want, err := bob.DH(g)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+
+	dropping := NewFaultyDHOracle(bob, NewInjector(Faults{Drop: 1}, rng))
+	if _, err := dropping.DH(g); !errors.Is(err, ErrDropped) || !IsTransient(err) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, ErrDropped)
+	}
+
+	corrupting := NewFaultyDHOracle(bob, NewInjector(Faults{Corrupt: 1}, rng))
+	if res, err := corrupting.DH(g); err != nil || bytes.Equal(res, want) || len(res) != len(want) {
+		t.Fatalf("%s: answer isn't corrupted: %x, %v", t.Name(), res, err)
+	}
+	// rejections are corrupted too
+	if res, err := corrupting.DH(helpers.BigOne); err != nil || res == nil {
+		t.Fatalf("%s: rejection isn't corrupted: %x, %v", t.Name(), res, err)
+	}
+
+	injector := NewInjector(Faults{Drop: 0.2, Corrupt: 0.2}, rng)
+	voting := NewVotingDHOracle(NewFaultyDHOracle(bob, injector), NewVoter(5, 10))
+
+	// a corrupted answer never wins the vote, but the vote may be lost
+	answered := 0
+	for i := 0; i < 20; i++ {
+		res, err := voting.DH(g)
+		if errors.Is(err, ErrNoMajority) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err)
+		}
+		if !bytes.Equal(res, want) {
+			t.Fatalf("%s: got %x, want %x", t.Name(), res, want)
+		}
+		answered++
+	}
+	if answered < 15 {
+		t.Errorf("%s: %d of 20 queries answered", t.Name(), answered)
+	}
+
+	if s := injector.Stats(); s.Dropped == 0 || s.Corrupted == 0 {
+		t.Errorf("%s: no faults were injected: %+v", t.Name(), s)
+	}
+
+	// without a majority the voter gives up, so that the query can be
+	// repeated with another input
+	noisy := NewVotingDHOracle(NewFaultyDHOracle(bob, NewInjector(Faults{Corrupt: 1}, rng)), NewVoter(3, 0))
+	if _, err := noisy.DH(g); !errors.Is(err, ErrNoMajority) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, ErrNoMajority)
+	}
+}
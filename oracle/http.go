@@ -0,0 +1,463 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/svkirillov/cryptopals-go/elliptic"
+)
+
+// The HTTP service hosts Bob's side of the key agreements. Requests and
+// responses are JSON objects:
+//
+//	POST /dh    {"public_key": n}
+//	POST /ecdh  {"x": n, "y": n} or {"point": "04..."}
+//	POST /x128  {"u": n}
+//	GET  /dh/public-key
+//	GET  /ecdh/public-key
+//	GET  /x128/public-key
+//
+// where n is a JSON number, a decimal string or a hex string with the 0x
+// prefix, and point is a hex encoded SEC1 uncompressed point. Bob answers
+// with {"tag": "<hex encoded MAC>"}, or with {"rejected": true} if he rejects
+// the key. His public keys are returned as {"public_key": "0x..."}, and his
+// public point as {"x": "0x...", "y": "0x..."}. Errors are returned with
+// a 4xx or 5xx status as {"error": "..."}.
+
+// BigInt is a big integer encoded in JSON as a number, a decimal string or
+// a hex string with the 0x prefix. It's marshaled as a hex string.
+type BigInt struct {
+	*big.Int
+}
+
+func (n BigInt) MarshalJSON() ([]byte, error) {
+	if n.Int == nil {
+		return []byte("null"), nil
+	}
+	if n.Sign() < 0 {
+		return json.Marshal("-0x" + new(big.Int).Neg(n.Int).Text(16))
+	}
+	return json.Marshal("0x" + n.Text(16))
+}
+
+func (n *BigInt) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		n.Int = nil
+		return nil
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	v, err := parseBigInt(s)
+	if err != nil {
+		return err
+	}
+	n.Int = v
+
+	return nil
+}
+
+// parseBigInt parses a decimal number or a hex one with the 0x prefix.
+func parseBigInt(s string) (*big.Int, error) {
+	neg := strings.HasPrefix(s, "-")
+	t := strings.TrimPrefix(s, "-")
+
+	base := 10
+	if strings.HasPrefix(t, "0x") || strings.HasPrefix(t, "0X") {
+		base = 16
+		t = t[2:]
+	}
+
+	v, ok := new(big.Int).SetString(t, base)
+	if !ok || t == "" {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	if neg {
+		v.Neg(v)
+	}
+
+	return v, nil
+}
+
+type dhRequest struct {
+	PublicKey BigInt `json:"public_key"`
+}
+
+type ecdhRequest struct {
+	X     BigInt `json:"x"`
+	Y     BigInt `json:"y"`
+	Point string `json:"point,omitempty"`
+}
+
+type x128Request struct {
+	U BigInt `json:"u"`
+}
+
+type tagResponse struct {
+	Tag      string `json:"tag,omitempty"`
+	Rejected bool   `json:"rejected,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type publicKeyResponse struct {
+	PublicKey BigInt `json:"public_key"`
+}
+
+type publicPointResponse struct {
+	X BigInt `json:"x"`
+	Y BigInt `json:"y"`
+}
+
+// maxRequestSize is the maximum size of a request body.
+const maxRequestSize = 1 << 16
+
+// NewHTTPHandler returns an http.Handler which serves the oracles and the
+// public keys. Requests for nil oracles and keys are answered with 404 Not
+// Found.
+func NewHTTPHandler(oracles ServerOracles) http.Handler {
+	mux := http.NewServeMux()
+
+	if oracles.DH != nil {
+		mux.HandleFunc("/dh", func(w http.ResponseWriter, r *http.Request) {
+			var req dhRequest
+			if !decodeRequest(w, r, &req) {
+				return
+			}
+			if req.PublicKey.Int == nil {
+				writeError(w, http.StatusBadRequest, "public_key is missing")
+				return
+			}
+
+			res, err := oracles.DH.DH(req.PublicKey.Int)
+			writeTag(w, res, err)
+		})
+	}
+
+	if oracles.ECDH != nil {
+		mux.HandleFunc("/ecdh", func(w http.ResponseWriter, r *http.Request) {
+			var req ecdhRequest
+			if !decodeRequest(w, r, &req) {
+				return
+			}
+
+			x, y := req.X.Int, req.Y.Int
+			if req.Point != "" {
+				var err error
+				if x, y, err = decodePoint(req.Point); err != nil {
+					writeError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			}
+			if x == nil || y == nil {
+				writeError(w, http.StatusBadRequest, "point is missing")
+				return
+			}
+
+			res, err := oracles.ECDH.ECDH(x, y)
+			writeTag(w, res, err)
+		})
+	}
+
+	if oracles.X128 != nil {
+		mux.HandleFunc("/x128", func(w http.ResponseWriter, r *http.Request) {
+			var req x128Request
+			if !decodeRequest(w, r, &req) {
+				return
+			}
+			if req.U.Int == nil {
+				writeError(w, http.StatusBadRequest, "u is missing")
+				return
+			}
+
+			res, err := oracles.X128.X128DH(req.U.Int)
+			writeTag(w, res, err)
+		})
+	}
+
+	if oracles.DHPublicKey != nil {
+		mux.HandleFunc("/dh/public-key", publicKeyHandler(oracles.DHPublicKey))
+	}
+
+	if oracles.X128PublicKey != nil {
+		mux.HandleFunc("/x128/public-key", publicKeyHandler(oracles.X128PublicKey))
+	}
+
+	if oracles.ECDHPublicKey != nil {
+		mux.HandleFunc("/ecdh/public-key", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			x, y, err := oracles.ECDHPublicKey.PublicKey()
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, publicPointResponse{X: BigInt{x}, Y: BigInt{y}})
+		})
+	}
+
+	return mux
+}
+
+func publicKeyHandler(source PublicKeySource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		publicKey, err := source.PublicKey()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, publicKeyResponse{PublicKey: BigInt{publicKey}})
+	}
+}
+
+// decodePoint decodes a hex encoded SEC1 uncompressed point. The point isn't
+// checked to be on any curve, it's up to Bob's validation policy.
+func decodePoint(s string) (x, y *big.Int, err error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid point: %s", err)
+	}
+
+	return unmarshalPoint(data)
+}
+
+// unmarshalPoint splits a SEC1 uncompressed point into its coordinates.
+func unmarshalPoint(data []byte) (x, y *big.Int, err error) {
+	if len(data) < 3 || len(data)%2 != 1 || data[0] != 4 {
+		return nil, nil, fmt.Errorf("invalid point: not in uncompressed form")
+	}
+
+	byteLen := len(data) / 2
+	x = new(big.Int).SetBytes(data[1 : 1+byteLen])
+	y = new(big.Int).SetBytes(data[1+byteLen:])
+
+	return x, y, nil
+}
+
+// marshalPoint encodes a point in the SEC1 uncompressed form with the
+// coordinates as long as the longer of them, as the curve isn't known.
+func marshalPoint(x, y *big.Int) []byte {
+	byteLen := (x.BitLen() + 7) / 8
+	if n := (y.BitLen() + 7) / 8; n > byteLen {
+		byteLen = n
+	}
+	if byteLen == 0 {
+		byteLen = 1
+	}
+
+	data := make([]byte, 1+2*byteLen)
+	data[0] = 4
+	x.FillBytes(data[1 : 1+byteLen])
+	y.FillBytes(data[1+byteLen:])
+
+	return data
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	return true
+}
+
+func writeTag(w http.ResponseWriter, res []byte, err error) {
+	switch {
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	case res == nil:
+		writeJSON(w, http.StatusOK, tagResponse{Rejected: true})
+	default:
+		writeJSON(w, http.StatusOK, tagResponse{Tag: hex.EncodeToString(res)})
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, tagResponse{Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// HTTPClient is an oracle served by NewHTTPHandler. It implements DHOracle,
+// ECDHOracle and X128Oracle, and Bob's public keys are fetched through
+// DHPublicKey, ECDHPublicKey and X128PublicKey.
+type HTTPClient struct {
+	// BaseURL is the URL of the service, e.g. "http://127.0.0.1:8080".
+	BaseURL string
+
+	// Client makes the requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	// Curve is used to send points in the SEC1 form. If it's nil, points are
+	// sent as x and y.
+	Curve elliptic.Curve
+
+	// Checker checks the answers with the protocol of the remote Bob,
+	// MACProtocol if it's nil.
+	Checker SecretChecker
+}
+
+// NewHTTPClient returns a client for the service at baseURL.
+func NewHTTPClient(baseURL string) *HTTPClient {
+	return &HTTPClient{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (c *HTTPClient) DH(publicKey *big.Int) ([]byte, error) {
+	return c.tag("/dh", dhRequest{PublicKey: BigInt{publicKey}})
+}
+
+func (c *HTTPClient) ECDH(x, y *big.Int) ([]byte, error) {
+	req := ecdhRequest{X: BigInt{x}, Y: BigInt{y}}
+	if c.Curve != nil {
+		req = ecdhRequest{Point: hex.EncodeToString(elliptic.Marshal(c.Curve, x, y))}
+	}
+
+	return c.tag("/ecdh", req)
+}
+
+func (c *HTTPClient) X128DH(u *big.Int) ([]byte, error) {
+	return c.tag("/x128", x128Request{U: BigInt{u}})
+}
+
+func (c *HTTPClient) CheckSecret(secret, answer []byte) bool {
+	if c.Checker == nil {
+		return MACProtocol{}.CheckSecret(secret, answer)
+	}
+	return c.Checker.CheckSecret(secret, answer)
+}
+
+// DHPublicKey returns the source of Bob's public key of the DH key
+// agreement.
+func (c *HTTPClient) DHPublicKey() PublicKeySource {
+	return PublicKeyFunc(func() (*big.Int, error) {
+		return c.publicKey("/dh/public-key")
+	})
+}
+
+// X128PublicKey returns the source of Bob's public key on x128.
+func (c *HTTPClient) X128PublicKey() PublicKeySource {
+	return PublicKeyFunc(func() (*big.Int, error) {
+		return c.publicKey("/x128/public-key")
+	})
+}
+
+// ECDHPublicKey returns the source of Bob's public point of the ECDH key
+// agreement.
+func (c *HTTPClient) ECDHPublicKey() ECPublicKeySource {
+	return ECPublicKeyFunc(func() (x, y *big.Int, err error) {
+		var res publicPointResponse
+		if err := c.do(http.MethodGet, "/ecdh/public-key", nil, &res); err != nil {
+			return nil, nil, err
+		}
+		if res.X.Int == nil || res.Y.Int == nil {
+			return nil, nil, errors.New("oracle: no public key")
+		}
+
+		return res.X.Int, res.Y.Int, nil
+	})
+}
+
+func (c *HTTPClient) publicKey(path string) (*big.Int, error) {
+	var res publicKeyResponse
+	if err := c.do(http.MethodGet, path, nil, &res); err != nil {
+		return nil, err
+	}
+	if res.PublicKey.Int == nil {
+		return nil, errors.New("oracle: no public key")
+	}
+
+	return res.PublicKey.Int, nil
+}
+
+// tag posts the request and returns the decoded tag, nil if Bob rejected
+// the request.
+func (c *HTTPClient) tag(path string, req interface{}) ([]byte, error) {
+	var res tagResponse
+	if err := c.do(http.MethodPost, path, req, &res); err != nil {
+		return nil, err
+	}
+
+	if res.Rejected {
+		return nil, nil
+	}
+
+	tag, err := hex.DecodeString(res.Tag)
+	if err != nil || len(tag) == 0 {
+		return nil, fmt.Errorf("oracle: invalid tag %q", res.Tag)
+	}
+
+	return tag, nil
+}
+
+func (c *HTTPClient) do(method, path string, req, res interface{}) error {
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var body io.Reader
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequest(method, c.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if req != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpRes, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(httpRes.Body, maxRequestSize))
+	if err != nil {
+		return err
+	}
+
+	if httpRes.StatusCode != http.StatusOK {
+		var e tagResponse
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%w: %s: %s", ErrRemote, httpRes.Status, e.Error)
+		}
+		return fmt.Errorf("%w: %s", ErrRemote, httpRes.Status)
+	}
+
+	return json.Unmarshal(data, res)
+}
@@ -0,0 +1,104 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/elliptic"
+)
+
+func TestHTTPOracle(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+	dhBob := NewDHAttackOracle(dhGroup, dh.RangeCheck, nil)
+	ecBob := NewECDHAttackOracle(elliptic.P128(), elliptic.NoValidation, nil)
+
+	srv := httptest.NewServer(NewHTTPHandler(ServerOracles{
+		DH:            dhBob,
+		ECDH:          ecBob,
+		DHPublicKey:   dhBob,
+		ECDHPublicKey: ecBob,
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(srv.URL)
+
+	g := dhGroup.DHParams().G
+	want, _ := dhBob.DH(g)
+	if got, err := client.DH(g); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("%s: DH = %x, %v, want %x", t.Name(), got, err, want)
+	}
+
+	if res, err := client.DH(big.NewInt(1)); res != nil || err != nil {
+		t.Errorf("%s: DH(1) = %x, %v, want rejection", t.Name(), res, err)
+	}
+
+	// points are sent both as x and y and in the SEC1 form
+	x, y := elliptic.P128().Params().Gx, elliptic.P128().Params().Gy
+	want, _ = ecBob.ECDH(x, y)
+	for _, curve := range []elliptic.Curve{nil, elliptic.P128()} {
+		client.Curve = curve
+		if got, err := client.ECDH(x, y); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s: ECDH = %x, %v, want %x", t.Name(), got, err, want)
+		}
+	}
+
+	wantKey, _ := dhBob.PublicKey()
+	if got, err := client.DHPublicKey().PublicKey(); err != nil || got.Cmp(wantKey) != 0 {
+		t.Errorf("%s: DH public key = %d, %v, want %d", t.Name(), got, err, wantKey)
+	}
+
+	wantX, wantY, _ := ecBob.PublicKey()
+	if gotX, gotY, err := client.ECDHPublicKey().PublicKey(); err != nil || gotX.Cmp(wantX) != 0 || gotY.Cmp(wantY) != 0 {
+		t.Errorf("%s: ECDH public key = (%d, %d), %v, want (%d, %d)", t.Name(), gotX, gotY, err, wantX, wantY)
+	}
+
+	// x128 isn't served, and the DH key isn't taken for Bob's key on x128
+	if _, err := client.X128DH(big.NewInt(4)); !errors.Is(err, ErrRemote) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, ErrRemote)
+	}
+	if k, err := client.X128PublicKey().PublicKey(); !errors.Is(err, ErrRemote) {
+		t.Errorf("%s: got x128 public key %d, %v, want %v", t.Name(), k, err, ErrRemote)
+	}
+
+	// public keys may be sent as decimal numbers and strings too
+	want, _ = dhBob.DH(big.NewInt(123))
+	for _, body := range []string{`{"public_key": 123}`, `{"public_key": "123"}`, `{"public_key": "0x7b"}`} {
+		res, err := http.Post(srv.URL+"/dh", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err)
+		}
+		data, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if !bytes.Contains(data, []byte(hex.EncodeToString(want))) {
+			t.Errorf("%s: %s: got %s, want tag %x", t.Name(), body, data, want)
+		}
+	}
+}
+
+func TestHTTPClientPublicKeyErrors(t *testing.T) {
+	dhBob := NewDHAttackOracle(dh.MODP512V57(), dh.RangeCheck, nil)
+
+	// the public key isn't served
+	srv := httptest.NewServer(NewHTTPHandler(ServerOracles{DH: dhBob}))
+	client := NewHTTPClient(srv.URL)
+
+	if k, err := client.DHPublicKey().PublicKey(); err == nil {
+		t.Errorf("%s: got public key %d, want an error", t.Name(), k)
+	}
+
+	// Bob is down
+	srv.Close()
+
+	if k, err := client.DHPublicKey().PublicKey(); err == nil {
+		t.Errorf("%s: got public key %d from a closed server, want an error", t.Name(), k)
+	}
+}
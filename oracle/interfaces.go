@@ -0,0 +1,94 @@
+package oracle
+
+import (
+	"math/big"
+)
+
+// DHOracle is Bob in a finite field DH key agreement. DH returns the MAC of
+// the message under the key Bob derives from publicKey, or nil if Bob
+// rejects the key. An error means that Bob couldn't be asked at all.
+type DHOracle interface {
+	DH(publicKey *big.Int) ([]byte, error)
+}
+
+// ECDHOracle is Bob in an ECDH key agreement on a short Weierstrass curve.
+// ECDH returns the MAC of the message under the key Bob derives from the
+// point (x, y), or nil if Bob rejects the point. An error means that Bob
+// couldn't be asked at all.
+type ECDHOracle interface {
+	ECDH(x, y *big.Int) ([]byte, error)
+}
+
+// X128Oracle is Bob in an ECDH key agreement on the x128 Montgomery curve.
+// X128DH returns the MAC of the message under the key Bob derives from the
+// u-coordinate, or nil if Bob rejects it. An error means that Bob couldn't
+// be asked at all.
+type X128Oracle interface {
+	X128DH(u *big.Int) ([]byte, error)
+}
+
+// KeyChecker reports whether key is Bob's private key in big-endian form.
+type KeyChecker interface {
+	IsKeyCorrect(key []byte) bool
+}
+
+// PublicKeySource returns Bob's public key, which is a single number for
+// finite field DH and for x128, or an error if it couldn't be fetched.
+type PublicKeySource interface {
+	PublicKey() (*big.Int, error)
+}
+
+// ECPublicKeySource returns Bob's public point, or an error if it couldn't
+// be fetched.
+type ECPublicKeySource interface {
+	PublicKey() (x, y *big.Int, err error)
+}
+
+// LeakOracle leaks Bob's private key modulo m.
+type LeakOracle interface {
+	PrivateKeyMod(m *big.Int) *big.Int
+}
+
+// DHOracleFunc is an adapter to use an ordinary function as a DHOracle.
+type DHOracleFunc func(publicKey *big.Int) ([]byte, error)
+
+func (f DHOracleFunc) DH(publicKey *big.Int) ([]byte, error) {
+	return f(publicKey)
+}
+
+// ECDHOracleFunc is an adapter to use an ordinary function as an ECDHOracle.
+type ECDHOracleFunc func(x, y *big.Int) ([]byte, error)
+
+func (f ECDHOracleFunc) ECDH(x, y *big.Int) ([]byte, error) {
+	return f(x, y)
+}
+
+// X128OracleFunc is an adapter to use an ordinary function as an X128Oracle.
+type X128OracleFunc func(u *big.Int) ([]byte, error)
+
+func (f X128OracleFunc) X128DH(u *big.Int) ([]byte, error) {
+	return f(u)
+}
+
+// PublicKeyFunc is an adapter to use an ordinary function as
+// a PublicKeySource.
+type PublicKeyFunc func() (*big.Int, error)
+
+func (f PublicKeyFunc) PublicKey() (*big.Int, error) {
+	return f()
+}
+
+// ECPublicKeyFunc is an adapter to use an ordinary function as
+// an ECPublicKeySource.
+type ECPublicKeyFunc func() (x, y *big.Int, err error)
+
+func (f ECPublicKeyFunc) PublicKey() (x, y *big.Int, err error) {
+	return f()
+}
+
+// LeakOracleFunc is an adapter to use an ordinary function as a LeakOracle.
+type LeakOracleFunc func(m *big.Int) *big.Int
+
+func (f LeakOracleFunc) PrivateKeyMod(m *big.Int) *big.Int {
+	return f(m)
+}
@@ -0,0 +1,169 @@
+package oracle
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned by a metered oracle instead of an answer once
+// its query budget is spent.
+var ErrBudgetExceeded = errors.New("oracle: query budget exceeded")
+
+// Summary describes the queries made to a metered oracle.
+type Summary struct {
+	Queries  int           // the number of answered queries
+	Rejected int           // the number of queries Bob answered with nil
+	Errors   int           // the number of failed queries, including those over the budget
+	Budget   int           // the maximum number of queries, 0 if unlimited
+	Elapsed  time.Duration // the time spent waiting for the answers
+	WallTime time.Duration // the time since the first query
+}
+
+func (s Summary) String() string {
+	budget := "unlimited"
+	if s.Budget > 0 {
+		budget = fmt.Sprintf("%d", s.Budget)
+	}
+
+	return fmt.Sprintf("%d queries (%d rejected, %d failed, budget %s), %s in oracle, %s wall time",
+		s.Queries, s.Rejected, s.Errors, budget, s.Elapsed, s.WallTime)
+}
+
+// Meter counts the queries made through metered oracles and enforces
+// a query budget. A Meter may be shared between several oracles, e.g. to
+// limit the total number of queries an attack makes.
+type Meter struct {
+	mu       sync.Mutex
+	budget   int
+	queries  int
+	rejected int
+	errors   int
+	elapsed  time.Duration
+	first    time.Time
+}
+
+// NewMeter returns a Meter which allows at most budget queries. If budget is
+// 0, the number of queries is unlimited.
+func NewMeter(budget int) *Meter {
+	return &Meter{budget: budget}
+}
+
+// Summary returns the statistics of the queries made so far.
+func (m *Meter) Summary() Summary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := Summary{
+		Queries:  m.queries,
+		Rejected: m.rejected,
+		Errors:   m.errors,
+		Budget:   m.budget,
+		Elapsed:  m.elapsed,
+	}
+	if !m.first.IsZero() {
+		s.WallTime = time.Since(m.first)
+	}
+
+	return s
+}
+
+// query asks the oracle through f unless the budget is spent.
+func (m *Meter) query(f func() ([]byte, error)) ([]byte, error) {
+	m.mu.Lock()
+	if m.first.IsZero() {
+		m.first = time.Now()
+	}
+	if m.budget > 0 && m.queries >= m.budget {
+		m.errors++
+		m.mu.Unlock()
+		return nil, ErrBudgetExceeded
+	}
+	// the query is reserved before it's made, so that concurrent callers
+	// can't exceed the budget
+	m.queries++
+	m.mu.Unlock()
+
+	start := time.Now()
+	res, err := f()
+	elapsed := time.Since(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.elapsed += elapsed
+	switch {
+	case err != nil:
+		m.queries--
+		m.errors++
+	case res == nil:
+		m.rejected++
+	}
+
+	return res, err
+}
+
+// MeteredDHOracle counts the queries to a DHOracle.
+type MeteredDHOracle struct {
+	*Meter
+	oracle DHOracle
+}
+
+// NewMeteredDHOracle wraps the oracle with the meter.
+func NewMeteredDHOracle(oracle DHOracle, meter *Meter) *MeteredDHOracle {
+	return &MeteredDHOracle{Meter: meter, oracle: oracle}
+}
+
+func (o *MeteredDHOracle) DH(publicKey *big.Int) ([]byte, error) {
+	return o.query(func() ([]byte, error) {
+		return o.oracle.DH(publicKey)
+	})
+}
+
+func (o *MeteredDHOracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// MeteredECDHOracle counts the queries to an ECDHOracle.
+type MeteredECDHOracle struct {
+	*Meter
+	oracle ECDHOracle
+}
+
+// NewMeteredECDHOracle wraps the oracle with the meter.
+func NewMeteredECDHOracle(oracle ECDHOracle, meter *Meter) *MeteredECDHOracle {
+	return &MeteredECDHOracle{Meter: meter, oracle: oracle}
+}
+
+func (o *MeteredECDHOracle) ECDH(x, y *big.Int) ([]byte, error) {
+	return o.query(func() ([]byte, error) {
+		return o.oracle.ECDH(x, y)
+	})
+}
+
+func (o *MeteredECDHOracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// MeteredX128Oracle counts the queries to an X128Oracle.
+type MeteredX128Oracle struct {
+	*Meter
+	oracle X128Oracle
+}
+
+// NewMeteredX128Oracle wraps the oracle with the meter.
+func NewMeteredX128Oracle(oracle X128Oracle, meter *Meter) *MeteredX128Oracle {
+	return &MeteredX128Oracle{Meter: meter, oracle: oracle}
+}
+
+func (o *MeteredX128Oracle) X128DH(u *big.Int) ([]byte, error) {
+	return o.query(func() ([]byte, error) {
+		return o.oracle.X128DH(u)
+	})
+}
+
+func (o *MeteredX128Oracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
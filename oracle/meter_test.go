@@ -0,0 +1,37 @@
+package oracle
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+)
+
+func TestMeter(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+	bob := NewDHAttackOracle(dhGroup, dh.RangeCheck, nil)
+
+	meter := NewMeter(3)
+	o := NewMeteredDHOracle(bob, meter)
+
+	queries := []*big.Int{
+		dhGroup.DHParams().G,
+		big.NewInt(1), // rejected by the range check
+		dhGroup.DHParams().G,
+	}
+	for _, q := range queries {
+		if _, err := o.DH(q); err != nil {
+			t.Fatalf("%s: %s", t.Name(), err)
+		}
+	}
+
+	if _, err := o.DH(dhGroup.DHParams().G); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, ErrBudgetExceeded)
+	}
+
+	s := meter.Summary()
+	if s.Queries != 3 || s.Rejected != 1 || s.Errors != 1 || s.Budget != 3 {
+		t.Errorf("%s: wrong summary: %s", t.Name(), s)
+	}
+}
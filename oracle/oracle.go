@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
+	"io"
 	"math/big"
 
 	"github.com/svkirillov/cryptopals-go/dh"
@@ -23,105 +24,186 @@ func MAC(k []byte) []byte {
 	return mac.Sum(nil)
 }
 
-func NewDHAttackOracle(dhGroup dh.DHScheme) (
-	dh func(publicKey *big.Int) []byte,
-	isKeyCorrect func([]byte) bool,
-	getPublicKey func() *big.Int,
-) {
-	dhKey, err := dhGroup.GenerateKey(nil)
+// DHAttackOracle is Bob in a finite field DH key agreement, who checks the
+// public keys he receives according to a validation policy.
+type DHAttackOracle struct {
+	group    dh.DHScheme
+	key      *dh.DHKey
+	policy   dh.ValidationPolicy
+	protocol Protocol
+}
+
+// NewDHAttackOracle returns an oracle for Bob, who checks the public keys he
+// receives according to the policy. If a key is rejected, the oracle returns
+// nil instead of a MAC. Bob's key is generated from rng, or from
+// crypto/rand.Reader if rng is nil.
+func NewDHAttackOracle(dhGroup dh.DHScheme, policy dh.ValidationPolicy, rng io.Reader) *DHAttackOracle {
+	dhKey, err := dhGroup.GenerateKey(rng)
 	if err != nil {
 		panic(err)
 	}
 
-	dh = func(publicKey *big.Int) []byte {
-		sharedKey := dhGroup.DH(dhKey.Private, publicKey)
-		return MAC(sharedKey.Bytes())
-	}
+	return NewDHAttackOracleWithKey(dhGroup, policy, dhKey)
+}
 
-	isKeyCorrect = func(key []byte) bool {
-		return bytes.Equal(dhKey.Private.Bytes(), key)
+// NewDHAttackOracleWithKey returns an oracle for Bob with the given key,
+// e.g. a short exponent one.
+func NewDHAttackOracleWithKey(dhGroup dh.DHScheme, policy dh.ValidationPolicy, dhKey *dh.DHKey) *DHAttackOracle {
+	return &DHAttackOracle{
+		group:    dhGroup,
+		key:      dhKey,
+		policy:   policy,
+		protocol: MACProtocol{},
 	}
+}
 
-	getPublicKey = func() *big.Int {
-		return dhKey.Public
+// WithProtocol returns Bob with the same key, who answers with the protocol.
+func (o *DHAttackOracle) WithProtocol(protocol Protocol) *DHAttackOracle {
+	bob := *o
+	bob.protocol = protocol
+	return &bob
+}
+
+func (o *DHAttackOracle) DH(publicKey *big.Int) ([]byte, error) {
+	sharedKey, err := o.group.ValidatedDH(o.key.Private, publicKey, o.policy)
+	if err != nil {
+		return nil, nil
 	}
+	return o.protocol.Respond(sharedKey.Bytes())
+}
+
+func (o *DHAttackOracle) CheckSecret(secret, answer []byte) bool {
+	return o.protocol.CheckSecret(secret, answer)
+}
+
+func (o *DHAttackOracle) IsKeyCorrect(key []byte) bool {
+	return bytes.Equal(o.key.Private.Bytes(), key)
+}
+
+func (o *DHAttackOracle) PublicKey() (*big.Int, error) {
+	return o.key.Public, nil
+}
 
-	return
+// ECDHAttackOracle is Bob in an ECDH key agreement, who checks the points he
+// receives according to a validation policy.
+type ECDHAttackOracle struct {
+	curve      elliptic.Curve
+	privateKey []byte
+	x, y       *big.Int
+	policy     elliptic.ValidationPolicy
+	protocol   Protocol
 }
 
-func NewECDHAttackOracle(curve elliptic.Curve) (
-	ecdh func(x, y *big.Int) []byte,
-	isKeyCorrect func([]byte) bool,
-	getPublicKey func() (x, y *big.Int),
-) {
-	privateKey, x, y, err := elliptic.GenerateKey(curve, nil)
+// NewECDHAttackOracle returns an oracle for Bob, who checks the points he
+// receives according to the policy. If a point is rejected, the oracle
+// returns nil instead of a MAC. Bob's key is generated from rng, or from
+// crypto/rand.Reader if rng is nil.
+func NewECDHAttackOracle(curve elliptic.Curve, policy elliptic.ValidationPolicy, rng io.Reader) *ECDHAttackOracle {
+	privateKey, x, y, err := elliptic.GenerateKey(curve, rng)
 	if err != nil {
 		panic(err)
 	}
 
-	ecdh = func(x, y *big.Int) []byte {
-		sx, sy := curve.ScalarMult(x, y, privateKey)
-		return MAC(elliptic.Marshal(curve, sx, sy))
+	return &ECDHAttackOracle{
+		curve:      curve,
+		privateKey: privateKey,
+		x:          x,
+		y:          y,
+		policy:     policy,
+		protocol:   MACProtocol{},
 	}
+}
 
-	isKeyCorrect = func(key []byte) bool {
-		// skipping trailing zeros in fixed size big-endian byte representation of big.Int
-		// e.g. if the original private key is 886092136281582889795402858978242928
-		// then it's 16-byte representation will be [0 170 167 183 29 163 210 19 176 223 2 100 1 190 113 112]
-		// but the given key in big-endian byte representation derived from big.Int doesn't have first zero:
-		// [170 167 183 29 163 210 19 176 223 2 100 1 190 113 112]
-		i := 0
-		for i < len(privateKey) && privateKey[i] == 0 {
-			i++
-		}
-
-		return bytes.Equal(privateKey[i:], key)
-	}
+// WithProtocol returns Bob with the same key, who answers with the protocol.
+func (o *ECDHAttackOracle) WithProtocol(protocol Protocol) *ECDHAttackOracle {
+	bob := *o
+	bob.protocol = protocol
+	return &bob
+}
 
-	getPublicKey = func() (*big.Int, *big.Int) {
-		return x, y
+func (o *ECDHAttackOracle) ECDH(x, y *big.Int) ([]byte, error) {
+	sx, sy, err := elliptic.ValidatedECDH(o.curve, o.privateKey, x, y, o.policy)
+	if err != nil {
+		return nil, nil
 	}
+	return o.protocol.Respond(elliptic.Marshal(o.curve, sx, sy))
+}
 
-	return
+func (o *ECDHAttackOracle) CheckSecret(secret, answer []byte) bool {
+	return o.protocol.CheckSecret(secret, answer)
 }
 
-func NewX128TwistAttackOracle() (
-	ecdh func(x *big.Int) []byte,
-	isKeyCorrect func([]byte) bool,
-	getPublicKey func() *big.Int,
-	privateKeyOracle func(q *big.Int) *big.Int,
-) {
-	privateKey, publicKey, err := x128.GenerateKey(nil)
+func (o *ECDHAttackOracle) IsKeyCorrect(key []byte) bool {
+	return isFixedSizeKeyCorrect(o.privateKey, key)
+}
+
+func (o *ECDHAttackOracle) PublicKey() (x, y *big.Int, err error) {
+	return o.x, o.y, nil
+}
+
+// X128TwistAttackOracle is Bob in an ECDH key agreement on x128, who
+// doesn't check that the u-coordinates he receives are on the curve.
+type X128TwistAttackOracle struct {
+	privateKey []byte
+	publicKey  *big.Int
+	protocol   Protocol
+}
+
+// NewX128TwistAttackOracle returns an oracle for Bob, whose key is generated
+// from rng, or from crypto/rand.Reader if rng is nil.
+func NewX128TwistAttackOracle(rng io.Reader) *X128TwistAttackOracle {
+	privateKey, publicKey, err := x128.GenerateKey(rng)
 	if err != nil {
 		panic(err)
 	}
 
-	ecdh = func(x *big.Int) []byte {
-		sx := x128.ScalarMult(x, privateKey)
-		return MAC(sx.Bytes())
+	return &X128TwistAttackOracle{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		protocol:   MACProtocol{},
 	}
+}
 
-	isKeyCorrect = func(key []byte) bool {
-		// skipping trailing zeros in fixed size big-endian byte representation of big.Int
-		// e.g. if the original private key is 886092136281582889795402858978242928
-		// then it's 16-byte representation will be [0 170 167 183 29 163 210 19 176 223 2 100 1 190 113 112]
-		// but the given key in big-endian byte representation derived from big.Int doesn't have first zero:
-		// [170 167 183 29 163 210 19 176 223 2 100 1 190 113 112]
-		i := 0
-		for i < len(privateKey) && privateKey[i] == 0 {
-			i++
-		}
-
-		return bytes.Equal(privateKey[i:], key)
-	}
+// WithProtocol returns Bob with the same key, who answers with the protocol.
+func (o *X128TwistAttackOracle) WithProtocol(protocol Protocol) *X128TwistAttackOracle {
+	bob := *o
+	bob.protocol = protocol
+	return &bob
+}
 
-	getPublicKey = func() *big.Int {
-		return publicKey
-	}
+func (o *X128TwistAttackOracle) X128DH(u *big.Int) ([]byte, error) {
+	sx := x128.ScalarMult(u, o.privateKey)
+	return o.protocol.Respond(sx.Bytes())
+}
+
+func (o *X128TwistAttackOracle) CheckSecret(secret, answer []byte) bool {
+	return o.protocol.CheckSecret(secret, answer)
+}
+
+func (o *X128TwistAttackOracle) IsKeyCorrect(key []byte) bool {
+	return isFixedSizeKeyCorrect(o.privateKey, key)
+}
+
+func (o *X128TwistAttackOracle) PublicKey() (*big.Int, error) {
+	return o.publicKey, nil
+}
+
+func (o *X128TwistAttackOracle) PrivateKeyMod(m *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).SetBytes(o.privateKey), m)
+}
 
-	privateKeyOracle = func(q *big.Int) *big.Int {
-		return new(big.Int).Mod(new(big.Int).SetBytes(privateKey), q)
+// isFixedSizeKeyCorrect compares a fixed size private key with a key in
+// big-endian form.
+func isFixedSizeKeyCorrect(privateKey, key []byte) bool {
+	// skipping trailing zeros in fixed size big-endian byte representation of big.Int
+	// e.g. if the original private key is 886092136281582889795402858978242928
+	// then it's 16-byte representation will be [0 170 167 183 29 163 210 19 176 223 2 100 1 190 113 112]
+	// but the given key in big-endian byte representation derived from big.Int doesn't have first zero:
+	// [170 167 183 29 163 210 19 176 223 2 100 1 190 113 112]
+	i := 0
+	for i < len(privateKey) && privateKey[i] == 0 {
+		i++
 	}
 
-	return
+	return bytes.Equal(privateKey[i:], key)
 }
@@ -0,0 +1,197 @@
+package oracle
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// SecretChecker reports whether Bob's answer was made with a candidate
+// shared secret. It's how the attacks recognize the right residue.
+type SecretChecker interface {
+	CheckSecret(secret, answer []byte) bool
+}
+
+// Protocol is the way Bob turns the shared secret into his answer. The
+// protocol is public, so the attacker checks candidate shared secrets with
+// it.
+type Protocol interface {
+	SecretChecker
+	Respond(secret []byte) ([]byte, error)
+}
+
+// CheckerOf returns the SecretChecker of the oracle, if it implements one,
+// e.g. Bob himself or a Checked* oracle. The wrappers of this package check
+// the answers with the checker of the wrapped oracle, and Client and
+// HTTPClient with their Checker. Otherwise it returns MACProtocol, the
+// protocol of the challenges.
+func CheckerOf(oracle interface{}) SecretChecker {
+	if c, ok := oracle.(SecretChecker); ok {
+		return c
+	}
+	return MACProtocol{}
+}
+
+// MACProtocol answers with the MAC of a fixed message under the raw shared
+// secret, see MAC.
+type MACProtocol struct{}
+
+func (MACProtocol) Respond(secret []byte) ([]byte, error) {
+	return MAC(secret), nil
+}
+
+func (MACProtocol) CheckSecret(secret, answer []byte) bool {
+	return hmac.Equal(MAC(secret), answer)
+}
+
+// Cipher is an AEAD used by AEADProtocol.
+type Cipher int
+
+const (
+	AESGCM Cipher = iota
+	ChaCha20Poly1305
+)
+
+func (c Cipher) String() string {
+	switch c {
+	case AESGCM:
+		return "aes-gcm"
+	case ChaCha20Poly1305:
+		return "chacha20-poly1305"
+	default:
+		return fmt.Sprintf("Cipher(%d)", int(c))
+	}
+}
+
+// ParseCipher returns the cipher with the given name, see Cipher.String.
+func ParseCipher(name string) (Cipher, error) {
+	for _, c := range []Cipher{AESGCM, ChaCha20Poly1305} {
+		if c.String() == name {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown cipher %q", name)
+}
+
+// newAEAD returns the AEAD with a 256-bit key.
+func (c Cipher) newAEAD(key []byte) (cipher.AEAD, error) {
+	switch c {
+	case AESGCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case ChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, fmt.Errorf("unknown cipher %s", c)
+	}
+}
+
+// hkdfInfo binds the derived keys to the key agreement.
+const hkdfInfo = "cryptopals key agreement"
+
+// DeriveKey derives a 256-bit key from the shared secret with HKDF-SHA256.
+func DeriveKey(secret []byte) []byte {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(hkdfInfo)), key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// AEADProtocol derives a key from the shared secret with DeriveKey and
+// answers with the fixed message encrypted under the key. The answer is the
+// random nonce followed by the ciphertext.
+type AEADProtocol struct {
+	cipher Cipher
+
+	mu  sync.Mutex
+	rng io.Reader
+}
+
+// NewAEADProtocol returns the protocol with the cipher. The nonces are drawn
+// from rng, or from crypto/rand.Reader if rng is nil.
+func NewAEADProtocol(c Cipher, rng io.Reader) *AEADProtocol {
+	if rng == nil {
+		rng = rand.Reader
+	}
+
+	return &AEADProtocol{cipher: c, rng: rng}
+}
+
+func (p *AEADProtocol) Respond(secret []byte) ([]byte, error) {
+	aead, err := p.cipher.newAEAD(DeriveKey(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	p.mu.Lock()
+	_, err = io.ReadFull(p.rng, nonce)
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, []byte(dhKeyAgreementConst), nil), nil
+}
+
+func (p *AEADProtocol) CheckSecret(secret, answer []byte) bool {
+	aead, err := p.cipher.newAEAD(DeriveKey(secret))
+	if err != nil || len(answer) < aead.NonceSize() {
+		return false
+	}
+
+	nonce, ciphertext := answer[:aead.NonceSize()], answer[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	return err == nil && string(plaintext) == dhKeyAgreementConst
+}
+
+// CheckedDHOracle is a DHOracle whose answers are checked with the given
+// SecretChecker, e.g. Bob with a Protocol behind other wrappers or over the
+// network.
+type CheckedDHOracle struct {
+	DHOracle
+	SecretChecker
+}
+
+// NewCheckedDHOracle attaches the checker to the oracle.
+func NewCheckedDHOracle(oracle DHOracle, checker SecretChecker) *CheckedDHOracle {
+	return &CheckedDHOracle{DHOracle: oracle, SecretChecker: checker}
+}
+
+// CheckedECDHOracle is an ECDHOracle whose answers are checked with the given
+// SecretChecker.
+type CheckedECDHOracle struct {
+	ECDHOracle
+	SecretChecker
+}
+
+// NewCheckedECDHOracle attaches the checker to the oracle.
+func NewCheckedECDHOracle(oracle ECDHOracle, checker SecretChecker) *CheckedECDHOracle {
+	return &CheckedECDHOracle{ECDHOracle: oracle, SecretChecker: checker}
+}
+
+// CheckedX128Oracle is an X128Oracle whose answers are checked with the given
+// SecretChecker.
+type CheckedX128Oracle struct {
+	X128Oracle
+	SecretChecker
+}
+
+// NewCheckedX128Oracle attaches the checker to the oracle.
+func NewCheckedX128Oracle(oracle X128Oracle, checker SecretChecker) *CheckedX128Oracle {
+	return &CheckedX128Oracle{X128Oracle: oracle, SecretChecker: checker}
+}
@@ -0,0 +1,106 @@
+package oracle
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+func TestProtocol(t *testing.T) {
+	rng := helpers.NewSeededReader(40)
+	secret := []byte("shared secret")
+	wrongSecret := []byte("wrong secret")
+
+	protocols := []Protocol{
+		MACProtocol{},
+		NewAEADProtocol(AESGCM, rng),
+		NewAEADProtocol(ChaCha20Poly1305, rng),
+	}
+
+	for _, p := range protocols {
+		answer, err := p.Respond(secret)
+		if err != nil {
+			t.Fatalf("%s: %T: %s", t.Name(), p, err)
+		}
+
+		if !p.CheckSecret(secret, answer) {
+			t.Errorf("%s: %T: the right secret isn't accepted", t.Name(), p)
+		}
+		if p.CheckSecret(wrongSecret, answer) {
+			t.Errorf("%s: %T: a wrong secret is accepted", t.Name(), p)
+		}
+
+		corrupted := append([]byte(nil), answer...)
+		corrupted[len(corrupted)-1] ^= 1
+		if p.CheckSecret(secret, corrupted) {
+			t.Errorf("%s: %T: a corrupted answer is accepted", t.Name(), p)
+		}
+	}
+
+	for _, c := range []Cipher{AESGCM, ChaCha20Poly1305} {
+		if parsed, err := ParseCipher(c.String()); err != nil || parsed != c {
+			t.Errorf("%s: ParseCipher(%q) = %s, %v", t.Name(), c, parsed, err)
+		}
+	}
+}
+
+func TestBobWithProtocol(t *testing.T) {
+	rng := helpers.NewSeededReader(40)
+	dhGroup := dh.MODP512V57()
+	g := dhGroup.DHParams().G
+
+	macBob := NewDHAttackOracle(dhGroup, dh.NoValidation, rng)
+	aeadBob := macBob.WithProtocol(NewAEADProtocol(ChaCha20Poly1305, rng))
+
+	secret := dhGroup.DH(macBob.key.Private, g).Bytes()
+
+	macAnswer, err := macBob.DH(g)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	aeadAnswer, err := aeadBob.DH(g)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	if bytes.Equal(macAnswer, aeadAnswer) {
+		t.Fatalf("%s: Bob with a protocol answers with the MAC", t.Name())
+	}
+
+	// Bob is a checker himself, and the wrappers check with Bob's checker
+	if !CheckerOf(aeadBob).CheckSecret(secret, aeadAnswer) {
+		t.Errorf("%s: Bob's checker doesn't accept his answer", t.Name())
+	}
+	var recorded bytes.Buffer
+	wrapped := NewMeteredDHOracle(
+		NewFaultyDHOracle(
+			NewVotingDHOracle(
+				NewRecordingDHOracle(aeadBob, NewRecorder(&recorded)),
+				NewVoter(3, 3)),
+			NewInjector(Faults{}, rng)),
+		NewMeter(0))
+	if !CheckerOf(wrapped).CheckSecret(secret, aeadAnswer) {
+		t.Errorf("%s: the checker of the wrappers doesn't accept Bob's answer", t.Name())
+	}
+	if CheckerOf(wrapped).CheckSecret(secret, macAnswer) {
+		t.Errorf("%s: the checker of the wrappers accepts the MAC", t.Name())
+	}
+
+	// a remote Bob is checked with the MAC unless a checker is attached
+	client := &HTTPClient{}
+	if CheckerOf(client).CheckSecret(secret, aeadAnswer) {
+		t.Errorf("%s: the MAC checker accepts an AEAD answer", t.Name())
+	}
+	client.Checker = aeadBob
+	if !CheckerOf(client).CheckSecret(secret, aeadAnswer) {
+		t.Errorf("%s: the checker of the client doesn't accept Bob's answer", t.Name())
+	}
+	checked := NewCheckedDHOracle(NewMeteredDHOracle(aeadBob, NewMeter(0)), aeadBob)
+	if !CheckerOf(checked).CheckSecret(secret, aeadAnswer) {
+		t.Errorf("%s: the attached checker doesn't accept Bob's answer", t.Name())
+	}
+	if !CheckerOf(macBob).CheckSecret(secret, macAnswer) {
+		t.Errorf("%s: the MAC checker doesn't accept Bob's answer", t.Name())
+	}
+}
@@ -0,0 +1,428 @@
+package oracle
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The wire protocol is line based. Every request is a single line with
+// a command and its hex encoded arguments:
+//
+//	DH <public key>
+//	ECDH <x> <y>
+//	X128 <u>
+//	DHPUB
+//	ECDHPUB
+//	X128PUB
+//
+// where the PUB commands ask for Bob's public key of each key agreement.
+// Every response is a single line too:
+//
+//	OK <hex encoded MAC, public key or SEC1 uncompressed public point>
+//	REJECTED
+//	ERR <message>
+//
+// REJECTED means that Bob rejected the key, which is answered with nil by
+// in-process oracles.
+const (
+	cmdDH         = "DH"
+	cmdECDH       = "ECDH"
+	cmdX128       = "X128"
+	cmdDHPublic   = "DHPUB"
+	cmdECDHPublic = "ECDHPUB"
+	cmdX128Public = "X128PUB"
+
+	respOK       = "OK"
+	respRejected = "REJECTED"
+	respError    = "ERR"
+)
+
+// maxLineSize is the maximum length of a request or a response.
+const maxLineSize = 1 << 16
+
+// ServerOracles are the oracles served by a Server, and Bob's public keys
+// of each key agreement. Commands for nil oracles and keys are answered with
+// an error.
+type ServerOracles struct {
+	DH   DHOracle
+	ECDH ECDHOracle
+	X128 X128Oracle
+
+	DHPublicKey   PublicKeySource
+	ECDHPublicKey ECPublicKeySource
+	X128PublicKey PublicKeySource
+}
+
+// Server serves oracles over TCP.
+type Server struct {
+	oracles ServerOracles
+	ln      net.Listener
+
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// StartServer listens on addr, e.g. "127.0.0.1:0", and serves the oracles
+// in the background until Close is called.
+func StartServer(addr string, oracles ServerOracles) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		oracles: oracles,
+		ln:      ln,
+		conns:   make(map[net.Conn]bool),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops the server and closes all connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	err := s.ln.Close()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	w := bufio.NewWriter(conn)
+
+	for scanner.Scan() {
+		fmt.Fprintln(w, s.answer(scanner.Text()))
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// answer returns the response line for the request line.
+func (s *Server) answer(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return respError + " empty request"
+	}
+
+	args, err := parseHexArgs(fields[1:])
+	if err != nil {
+		return respError + " " + err.Error()
+	}
+
+	var res []byte
+
+	switch cmd := fields[0]; {
+	case cmd == cmdDH && len(args) == 1 && s.oracles.DH != nil:
+		res, err = s.oracles.DH.DH(args[0])
+	case cmd == cmdECDH && len(args) == 2 && s.oracles.ECDH != nil:
+		res, err = s.oracles.ECDH.ECDH(args[0], args[1])
+	case cmd == cmdX128 && len(args) == 1 && s.oracles.X128 != nil:
+		res, err = s.oracles.X128.X128DH(args[0])
+	case cmd == cmdDHPublic && len(args) == 0 && s.oracles.DHPublicKey != nil:
+		res, err = publicKeyBytes(s.oracles.DHPublicKey)
+	case cmd == cmdX128Public && len(args) == 0 && s.oracles.X128PublicKey != nil:
+		res, err = publicKeyBytes(s.oracles.X128PublicKey)
+	case cmd == cmdECDHPublic && len(args) == 0 && s.oracles.ECDHPublicKey != nil:
+		var x, y *big.Int
+		if x, y, err = s.oracles.ECDHPublicKey.PublicKey(); err == nil {
+			res = marshalPoint(x, y)
+		}
+	default:
+		return respError + " unsupported request " + cmd
+	}
+
+	switch {
+	case err != nil:
+		return respError + " " + err.Error()
+	case res == nil:
+		return respRejected
+	default:
+		return fmt.Sprintf("%s %x", respOK, res)
+	}
+}
+
+// publicKeyBytes returns the public key of the source, which is never
+// empty, not to be taken for a rejection.
+func publicKeyBytes(source PublicKeySource) ([]byte, error) {
+	publicKey, err := source.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	res := publicKey.Bytes()
+	if len(res) == 0 {
+		res = []byte{0}
+	}
+
+	return res, nil
+}
+
+func parseHexArgs(fields []string) ([]*big.Int, error) {
+	args := make([]*big.Int, len(fields))
+
+	for i, f := range fields {
+		n, ok := new(big.Int).SetString(f, 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid argument %q", f)
+		}
+		args[i] = n
+	}
+
+	return args, nil
+}
+
+// ErrRemote is wrapped by the errors reported by a remote oracle.
+var ErrRemote = errors.New("oracle: remote error")
+
+// Client is an oracle served by a Server. It implements DHOracle,
+// ECDHOracle and X128Oracle, and Bob's public keys are fetched through
+// DHPublicKey, ECDHPublicKey and X128PublicKey. The requests are made one at
+// a time over a single connection. The connection is closed if a request
+// fails on the way, as a late response would be read as the response to the
+// next request, and the next request is made over a new connection.
+type Client struct {
+	// Timeout limits the time of each request, no limit if it's 0.
+	Timeout time.Duration
+
+	// Checker checks the answers with the protocol of the remote Bob,
+	// MACProtocol if it's nil.
+	Checker SecretChecker
+
+	addr string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	closed bool
+}
+
+// Dial connects to a Server.
+func Dial(addr string) (*Client, error) {
+	c := &Client{addr: addr}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// connect makes a new connection to the server.
+func (c *Client) connect() error {
+	conn, err := net.Dial("tcp", c.addr)
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	c.reader = bufio.NewReaderSize(conn, 4096)
+
+	return nil
+}
+
+// disconnect closes the connection after a failed request.
+func (c *Client) disconnect() {
+	c.conn.Close()
+	c.conn = nil
+	c.reader = nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	c.reader = nil
+
+	return err
+}
+
+func (c *Client) DH(publicKey *big.Int) ([]byte, error) {
+	return c.request(cmdDH, publicKey)
+}
+
+func (c *Client) ECDH(x, y *big.Int) ([]byte, error) {
+	return c.request(cmdECDH, x, y)
+}
+
+func (c *Client) X128DH(u *big.Int) ([]byte, error) {
+	return c.request(cmdX128, u)
+}
+
+func (c *Client) CheckSecret(secret, answer []byte) bool {
+	if c.Checker == nil {
+		return MACProtocol{}.CheckSecret(secret, answer)
+	}
+	return c.Checker.CheckSecret(secret, answer)
+}
+
+// DHPublicKey returns the source of Bob's public key of the DH key
+// agreement.
+func (c *Client) DHPublicKey() PublicKeySource {
+	return PublicKeyFunc(func() (*big.Int, error) {
+		return c.publicKey(cmdDHPublic)
+	})
+}
+
+// X128PublicKey returns the source of Bob's public key on x128.
+func (c *Client) X128PublicKey() PublicKeySource {
+	return PublicKeyFunc(func() (*big.Int, error) {
+		return c.publicKey(cmdX128Public)
+	})
+}
+
+// ECDHPublicKey returns the source of Bob's public point of the ECDH key
+// agreement.
+func (c *Client) ECDHPublicKey() ECPublicKeySource {
+	return ECPublicKeyFunc(func() (x, y *big.Int, err error) {
+		res, err := c.request(cmdECDHPublic)
+		if err != nil {
+			return nil, nil, err
+		}
+		if res == nil {
+			return nil, nil, errors.New("oracle: no public key")
+		}
+
+		return unmarshalPoint(res)
+	})
+}
+
+func (c *Client) publicKey(cmd string) (*big.Int, error) {
+	res, err := c.request(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("oracle: no public key")
+	}
+
+	return new(big.Int).SetBytes(res), nil
+}
+
+// request sends a request and returns the decoded response, nil if Bob
+// rejected the request.
+func (c *Client) request(cmd string, args ...*big.Int) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString(cmd)
+	for _, arg := range args {
+		if arg.Sign() < 0 {
+			return nil, fmt.Errorf("oracle: negative argument %d", arg)
+		}
+		fmt.Fprintf(&b, " %x", arg)
+	}
+	b.WriteByte('\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, errors.New("oracle: client is closed")
+	}
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	var deadline time.Time
+	if c.Timeout > 0 {
+		deadline = time.Now().Add(c.Timeout)
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		c.disconnect()
+		return nil, err
+	}
+
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		c.disconnect()
+		return nil, err
+	}
+
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.disconnect()
+		return nil, err
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+
+	switch {
+	case fields[0] == respRejected:
+		return nil, nil
+	case fields[0] == respOK && len(fields) == 2:
+		// MACs may have leading zeros, so they aren't parsed as numbers
+		res, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("oracle: invalid response %q", line)
+		}
+		return res, nil
+	case fields[0] == respError && len(fields) == 2:
+		return nil, fmt.Errorf("%w: %s", ErrRemote, fields[1])
+	default:
+		return nil, fmt.Errorf("oracle: invalid response %q", line)
+	}
+}
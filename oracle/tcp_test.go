@@ -0,0 +1,124 @@
+package oracle
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/elliptic"
+)
+
+func TestTCPOracle(t *testing.T) {
+	dhGroup := dh.MODP512V57()
+	dhBob := NewDHAttackOracle(dhGroup, dh.RangeCheck, nil)
+	ecBob := NewECDHAttackOracle(elliptic.P128(), elliptic.NoValidation, nil)
+
+	srv, err := StartServer("127.0.0.1:0", ServerOracles{
+		DH:            dhBob,
+		ECDH:          ecBob,
+		DHPublicKey:   dhBob,
+		ECDHPublicKey: ecBob,
+	})
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	defer srv.Close()
+
+	client, err := Dial(srv.Addr())
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	defer client.Close()
+
+	// a MAC has leading zeros with probability 1/256, so a few queries are
+	// made to be sure they are kept
+	for i := int64(2); i < 1024; i++ {
+		want, _ := dhBob.DH(big.NewInt(i))
+		got, err := client.DH(big.NewInt(i))
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s: DH(%d) = %x, want %x", t.Name(), i, got, want)
+		}
+	}
+
+	if res, err := client.DH(big.NewInt(1)); res != nil || err != nil {
+		t.Errorf("%s: DH(1) = %x, %v, want rejection", t.Name(), res, err)
+	}
+
+	x, y := elliptic.P128().Params().Gx, elliptic.P128().Params().Gy
+	want, _ := ecBob.ECDH(x, y)
+	if got, err := client.ECDH(x, y); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("%s: ECDH = %x, %v, want %x", t.Name(), got, err, want)
+	}
+
+	wantKey, _ := dhBob.PublicKey()
+	if got, err := client.DHPublicKey().PublicKey(); err != nil || got.Cmp(wantKey) != 0 {
+		t.Errorf("%s: DH public key = %d, %v, want %d", t.Name(), got, err, wantKey)
+	}
+
+	wantX, wantY, _ := ecBob.PublicKey()
+	if gotX, gotY, err := client.ECDHPublicKey().PublicKey(); err != nil || gotX.Cmp(wantX) != 0 || gotY.Cmp(wantY) != 0 {
+		t.Errorf("%s: ECDH public key = (%d, %d), %v, want (%d, %d)", t.Name(), gotX, gotY, err, wantX, wantY)
+	}
+
+	// x128 isn't served, and the DH key isn't taken for Bob's key on x128
+	if _, err := client.X128DH(big.NewInt(4)); !errors.Is(err, ErrRemote) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, ErrRemote)
+	}
+	if k, err := client.X128PublicKey().PublicKey(); !errors.Is(err, ErrRemote) {
+		t.Errorf("%s: got x128 public key %d, %v, want %v", t.Name(), k, err, ErrRemote)
+	}
+}
+
+func TestTCPOracleTimeout(t *testing.T) {
+	dhBob := NewDHAttackOracle(dh.MODP512V57(), dh.RangeCheck, nil)
+
+	// Bob is slow to answer the first query only
+	slow := make(chan bool, 1)
+	slow <- true
+	srv, err := StartServer("127.0.0.1:0", ServerOracles{
+		DH: DHOracleFunc(func(publicKey *big.Int) ([]byte, error) {
+			select {
+			case <-slow:
+				time.Sleep(200 * time.Millisecond)
+			default:
+				// pass
+			}
+			return dhBob.DH(publicKey)
+		}),
+	})
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	defer srv.Close()
+
+	client, err := Dial(srv.Addr())
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	defer client.Close()
+	client.Timeout = 50 * time.Millisecond
+
+	if _, err := client.DH(big.NewInt(2)); !IsTransient(err) {
+		t.Fatalf("%s: got error %v, want a timeout", t.Name(), err)
+	}
+
+	// the late answer to the first query isn't taken for the answers to the
+	// next ones
+	time.Sleep(300 * time.Millisecond)
+	for i := int64(3); i < 6; i++ {
+		want, _ := dhBob.DH(big.NewInt(i))
+		got, err := client.DH(big.NewInt(i))
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s: DH(%d) = %x, want %x", t.Name(), i, got, want)
+		}
+	}
+}
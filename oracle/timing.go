@@ -0,0 +1,133 @@
+package oracle
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/svkirillov/cryptopals-go/elliptic"
+)
+
+// TimingOracle is Bob in an ECDH key agreement whose response time is
+// observable. TimedECDH returns the same answer as ECDHOracle.ECDH and the
+// time Bob took to compute it.
+type TimingOracle interface {
+	TimedECDH(x, y *big.Int) ([]byte, time.Duration, error)
+}
+
+// TimingCosts is the simulated time of the operations of a double-and-add
+// loop with a Montgomery-style extra reduction, see
+// ECDHReductionTimingOracle.
+type TimingCosts struct {
+	Double    time.Duration // a point doubling
+	Add       time.Duration // a point addition
+	Reduction time.Duration // an extra reduction, see NeedsExtraReduction
+	Jitter    time.Duration // the maximum uniform noise added to each answer
+}
+
+// DefaultTimingCosts are roughly the costs of the operations on P-128.
+var DefaultTimingCosts = TimingCosts{
+	Double:    8 * time.Microsecond,
+	Add:       7 * time.Microsecond,
+	Reduction: 1 * time.Microsecond,
+	Jitter:    2 * time.Microsecond,
+}
+
+// NeedsExtraReduction reports whether a doubling or an addition which
+// results in (x, y) takes an extra reduction in the simulated
+// implementation: it happens if x >= p/2. The rule is synthetic, it stands
+// for the data dependent final subtraction of Montgomery multiplication,
+// which elliptic.CurveParams doesn't have.
+func NeedsExtraReduction(curve elliptic.Curve, x, y *big.Int) bool {
+	return new(big.Int).Lsh(x, 1).Cmp(curve.Params().P) >= 0
+}
+
+// ECDHReductionTimingOracle is Bob in an ECDH key agreement whose field
+// arithmetic takes a synthetic extra reduction, see NeedsExtraReduction.
+// Bob computes the shared secret with elliptic.DoubleAndAdd, the loop of
+// elliptic.CurveParams.ScalarMult, but the time of each answer is simulated
+// from the operations of the loop, so that the leak doesn't depend
+// on the load of the machine. The extra reductions are what leaks the key:
+// with Reduction = 0, only the add/no-add branch is left, and the time only
+// depends on the weight and the length of the key.
+type ECDHReductionTimingOracle struct {
+	*ECDHAttackOracle
+	costs TimingCosts
+
+	mu  sync.Mutex
+	rng io.Reader
+}
+
+// NewECDHReductionTimingOracle returns a timing oracle for Bob, who doesn't
+// validate the points he receives. Bob's key and the jitter are generated
+// from rng, or from crypto/rand.Reader if rng is nil.
+func NewECDHReductionTimingOracle(curve elliptic.Curve, costs TimingCosts, rng io.Reader) *ECDHReductionTimingOracle {
+	if rng == nil {
+		rng = rand.Reader
+	}
+
+	return &ECDHReductionTimingOracle{
+		ECDHAttackOracle: NewECDHAttackOracle(curve, elliptic.NoValidation, rng),
+		costs:            costs,
+		rng:              rng,
+	}
+}
+
+func (o *ECDHReductionTimingOracle) TimedECDH(x, y *big.Int) ([]byte, time.Duration, error) {
+	sx, sy, elapsed := o.timedScalarMult(x, y)
+
+	jitter, err := o.jitter()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res, err := o.protocol.Respond(elliptic.Marshal(o.curve, sx, sy))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res, elapsed + jitter, nil
+}
+
+// timedScalarMult computes the scalar multiplication with the loop of
+// elliptic.CurveParams.ScalarMult and returns the simulated time it takes.
+// Operations with the point at infinity return early in
+// elliptic.CurveParams.Add, so they cost nothing.
+func (o *ECDHReductionTimingOracle) timedScalarMult(xIn, yIn *big.Int) (x, y *big.Int, elapsed time.Duration) {
+	x, y = elliptic.DoubleAndAdd(o.curve, xIn, yIn, o.privateKey, func(op elliptic.Operation) {
+		switch {
+		case op.Trivial:
+			return
+		case op.Double:
+			elapsed += o.costs.Double
+		default:
+			elapsed += o.costs.Add
+		}
+
+		if NeedsExtraReduction(o.curve, op.X, op.Y) {
+			elapsed += o.costs.Reduction
+		}
+	})
+
+	return x, y, elapsed
+}
+
+// jitter returns a uniform random duration in [0, Jitter).
+func (o *ECDHReductionTimingOracle) jitter() (time.Duration, error) {
+	if o.costs.Jitter <= 0 {
+		return 0, nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var buf [8]byte
+	if _, err := io.ReadFull(o.rng, buf[:]); err != nil {
+		return 0, err
+	}
+
+	return time.Duration(binary.BigEndian.Uint64(buf[:]) % uint64(o.costs.Jitter)), nil
+}
@@ -0,0 +1,423 @@
+package oracle
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A transcript is a JSON Lines file with an Entry per query, e.g.
+//
+//	{"oracle":"x128","input":["0x4"],"tag":"5f2a...","elapsed_ns":81234}
+//	{"oracle":"leak","input":["0x1b"],"output":"0x11","elapsed_ns":530}
+//
+// Names of the oracles which answer the queries.
+const (
+	OracleDH        = "dh"
+	OracleECDH      = "ecdh"
+	OracleX128      = "x128"
+	OracleTimedECDH = "timed-ecdh"
+	OraclePublicKey = "public-key"
+	OracleLeak      = "leak"
+)
+
+// Entry is a recorded query and its answer.
+type Entry struct {
+	Oracle   string        `json:"oracle"`
+	Input    []BigInt      `json:"input,omitempty"`
+	Tag      string        `json:"tag,omitempty"`      // the hex encoded MAC
+	Output   *BigInt       `json:"output,omitempty"`   // the public key or the leaked remainder
+	Rejected bool          `json:"rejected,omitempty"` // Bob answered with nil
+	Error    string        `json:"error,omitempty"`
+	Timeout  bool          `json:"timeout,omitempty"`     // the error was a timeout
+	Duration time.Duration `json:"duration_ns,omitempty"` // the time reported by a timing oracle
+	Elapsed  time.Duration `json:"elapsed_ns"`            // the time spent waiting for the answer
+}
+
+// Recorder writes the transcript of the queries made through recording
+// oracles. A Recorder may be shared between several oracles.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder which writes the transcript to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error which occurred while writing the transcript.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+func (r *Recorder) record(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = r.enc.Encode(e)
+	}
+}
+
+// query asks the oracle through f and records the query.
+func (r *Recorder) query(oracle string, input []*big.Int, f func() ([]byte, error)) ([]byte, error) {
+	res, _, err := r.timedQuery(oracle, input, func() ([]byte, time.Duration, error) {
+		res, err := f()
+		return res, 0, err
+	})
+
+	return res, err
+}
+
+// timedQuery asks a timing oracle through f and records the query.
+func (r *Recorder) timedQuery(
+	oracle string,
+	input []*big.Int,
+	f func() ([]byte, time.Duration, error),
+) ([]byte, time.Duration, error) {
+	e := Entry{Oracle: oracle, Input: toBigInts(input)}
+
+	start := time.Now()
+	res, duration, err := f()
+	e.Elapsed = time.Since(start)
+
+	switch {
+	case err != nil:
+		e.setError(err)
+	case res == nil:
+		e.Rejected = true
+	default:
+		e.Tag = hex.EncodeToString(res)
+	}
+	if err == nil {
+		e.Duration = duration
+	}
+	r.record(e)
+
+	return res, duration, err
+}
+
+func toBigInts(input []*big.Int) []BigInt {
+	res := make([]BigInt, len(input))
+	for i, n := range input {
+		res[i] = BigInt{n}
+	}
+	return res
+}
+
+// RecordingDHOracle records the queries to a DHOracle.
+type RecordingDHOracle struct {
+	*Recorder
+	oracle DHOracle
+}
+
+// NewRecordingDHOracle wraps the oracle with the recorder.
+func NewRecordingDHOracle(oracle DHOracle, recorder *Recorder) *RecordingDHOracle {
+	return &RecordingDHOracle{Recorder: recorder, oracle: oracle}
+}
+
+func (o *RecordingDHOracle) DH(publicKey *big.Int) ([]byte, error) {
+	return o.query(OracleDH, []*big.Int{publicKey}, func() ([]byte, error) {
+		return o.oracle.DH(publicKey)
+	})
+}
+
+func (o *RecordingDHOracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// RecordingECDHOracle records the queries to an ECDHOracle.
+type RecordingECDHOracle struct {
+	*Recorder
+	oracle ECDHOracle
+}
+
+// NewRecordingECDHOracle wraps the oracle with the recorder.
+func NewRecordingECDHOracle(oracle ECDHOracle, recorder *Recorder) *RecordingECDHOracle {
+	return &RecordingECDHOracle{Recorder: recorder, oracle: oracle}
+}
+
+func (o *RecordingECDHOracle) ECDH(x, y *big.Int) ([]byte, error) {
+	return o.query(OracleECDH, []*big.Int{x, y}, func() ([]byte, error) {
+		return o.oracle.ECDH(x, y)
+	})
+}
+
+func (o *RecordingECDHOracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// RecordingX128Oracle records the queries to an X128Oracle.
+type RecordingX128Oracle struct {
+	*Recorder
+	oracle X128Oracle
+}
+
+// NewRecordingX128Oracle wraps the oracle with the recorder.
+func NewRecordingX128Oracle(oracle X128Oracle, recorder *Recorder) *RecordingX128Oracle {
+	return &RecordingX128Oracle{Recorder: recorder, oracle: oracle}
+}
+
+func (o *RecordingX128Oracle) X128DH(u *big.Int) ([]byte, error) {
+	return o.query(OracleX128, []*big.Int{u}, func() ([]byte, error) {
+		return o.oracle.X128DH(u)
+	})
+}
+
+func (o *RecordingX128Oracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// RecordingTimingOracle records the queries to a TimingOracle.
+type RecordingTimingOracle struct {
+	*Recorder
+	oracle TimingOracle
+}
+
+// NewRecordingTimingOracle wraps the oracle with the recorder.
+func NewRecordingTimingOracle(oracle TimingOracle, recorder *Recorder) *RecordingTimingOracle {
+	return &RecordingTimingOracle{Recorder: recorder, oracle: oracle}
+}
+
+func (o *RecordingTimingOracle) TimedECDH(x, y *big.Int) ([]byte, time.Duration, error) {
+	return o.timedQuery(OracleTimedECDH, []*big.Int{x, y}, func() ([]byte, time.Duration, error) {
+		return o.oracle.TimedECDH(x, y)
+	})
+}
+
+func (o *RecordingTimingOracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// RecordingPublicKeySource records the public keys returned by
+// a PublicKeySource.
+type RecordingPublicKeySource struct {
+	*Recorder
+	source PublicKeySource
+}
+
+// NewRecordingPublicKeySource wraps the source with the recorder.
+func NewRecordingPublicKeySource(source PublicKeySource, recorder *Recorder) *RecordingPublicKeySource {
+	return &RecordingPublicKeySource{Recorder: recorder, source: source}
+}
+
+func (o *RecordingPublicKeySource) PublicKey() (*big.Int, error) {
+	start := time.Now()
+	res, err := o.source.PublicKey()
+
+	e := Entry{Oracle: OraclePublicKey, Elapsed: time.Since(start)}
+	if err != nil {
+		e.setError(err)
+	} else {
+		e.Output = &BigInt{res}
+	}
+	o.record(e)
+
+	return res, err
+}
+
+// RecordingLeakOracle records the queries to a LeakOracle.
+type RecordingLeakOracle struct {
+	*Recorder
+	oracle LeakOracle
+}
+
+// NewRecordingLeakOracle wraps the oracle with the recorder.
+func NewRecordingLeakOracle(oracle LeakOracle, recorder *Recorder) *RecordingLeakOracle {
+	return &RecordingLeakOracle{Recorder: recorder, oracle: oracle}
+}
+
+func (o *RecordingLeakOracle) PrivateKeyMod(m *big.Int) *big.Int {
+	start := time.Now()
+	res := o.oracle.PrivateKeyMod(m)
+	o.record(Entry{Oracle: OracleLeak, Input: toBigInts([]*big.Int{m}), Output: &BigInt{res}, Elapsed: time.Since(start)})
+
+	return res
+}
+
+// ErrNotRecorded is returned by a ReplayOracle for a query which isn't in
+// the transcript.
+var ErrNotRecorded = errors.New("oracle: query not recorded")
+
+// ReplayOracle answers the queries from a transcript. It implements
+// DHOracle, ECDHOracle, X128Oracle, TimingOracle, PublicKeySource and
+// LeakOracle. The queries are matched by their input, so an attack has to be
+// replayed with the same seed as the recorded run. The answers to a query
+// asked several times are replayed in the recorded order, and the last one
+// is repeated once they are exhausted.
+type ReplayOracle struct {
+	mu      sync.Mutex
+	answers map[string][]Entry
+	asked   map[string]int
+}
+
+// NewReplayOracle reads a transcript written by a Recorder.
+func NewReplayOracle(r io.Reader) (*ReplayOracle, error) {
+	o := &ReplayOracle{
+		answers: make(map[string][]Entry),
+		asked:   make(map[string]int),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("transcript: line %d: %w", line, err)
+		}
+
+		input := make([]*big.Int, len(e.Input))
+		for i, n := range e.Input {
+			if n.Int == nil {
+				return nil, fmt.Errorf("transcript: line %d: null input", line)
+			}
+			input[i] = n.Int
+		}
+
+		key := replayKey(e.Oracle, input)
+		o.answers[key] = append(o.answers[key], e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("transcript: %w", err)
+	}
+
+	return o, nil
+}
+
+func replayKey(oracle string, input []*big.Int) string {
+	var b strings.Builder
+	b.WriteString(oracle)
+	for _, n := range input {
+		fmt.Fprintf(&b, " %x", n)
+	}
+	return b.String()
+}
+
+// answer returns the next recorded answer to the query.
+func (o *ReplayOracle) answer(oracle string, input ...*big.Int) (Entry, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	key := replayKey(oracle, input)
+
+	answers := o.answers[key]
+	if len(answers) == 0 {
+		return Entry{}, false
+	}
+
+	i := o.asked[key]
+	if i < len(answers)-1 {
+		o.asked[key]++
+	}
+
+	return answers[i], true
+}
+
+// tag returns the recorded answer to a query to Bob.
+func (o *ReplayOracle) tag(oracle string, input ...*big.Int) ([]byte, time.Duration, error) {
+	e, ok := o.answer(oracle, input...)
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s", ErrNotRecorded, replayKey(oracle, input))
+	}
+
+	switch {
+	case e.Error != "":
+		return nil, 0, e.replayError()
+	case e.Rejected:
+		return nil, e.Duration, nil
+	}
+
+	tag, err := hex.DecodeString(e.Tag)
+	if err != nil || len(tag) == 0 {
+		return nil, 0, fmt.Errorf("transcript: invalid tag %q", e.Tag)
+	}
+
+	return tag, e.Duration, nil
+}
+
+// setError records err and whether it was a timeout, which its message
+// doesn't tell.
+func (e *Entry) setError(err error) {
+	var netErr net.Error
+	e.Error = err.Error()
+	e.Timeout = errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// replayError returns the recorded error, which wraps the errors of this
+// package, or ErrTimeout for a timeout, so that e.g. IsTransient works on
+// replay too.
+func (e *Entry) replayError() error {
+	if e.Timeout {
+		return fmt.Errorf("%w: %s", ErrTimeout, e.Error)
+	}
+
+	for _, err := range []error{ErrDropped, ErrNoMajority, ErrBudgetExceeded, ErrRemote} {
+		if strings.HasPrefix(e.Error, err.Error()) {
+			return fmt.Errorf("%w%s", err, strings.TrimPrefix(e.Error, err.Error()))
+		}
+	}
+
+	return errors.New(e.Error)
+}
+
+func (o *ReplayOracle) DH(publicKey *big.Int) ([]byte, error) {
+	res, _, err := o.tag(OracleDH, publicKey)
+	return res, err
+}
+
+func (o *ReplayOracle) ECDH(x, y *big.Int) ([]byte, error) {
+	res, _, err := o.tag(OracleECDH, x, y)
+	return res, err
+}
+
+func (o *ReplayOracle) X128DH(u *big.Int) ([]byte, error) {
+	res, _, err := o.tag(OracleX128, u)
+	return res, err
+}
+
+func (o *ReplayOracle) TimedECDH(x, y *big.Int) ([]byte, time.Duration, error) {
+	return o.tag(OracleTimedECDH, x, y)
+}
+
+// PublicKey returns the recorded public key.
+func (o *ReplayOracle) PublicKey() (*big.Int, error) {
+	e, ok := o.answer(OraclePublicKey)
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, OraclePublicKey)
+	case e.Error != "":
+		return nil, e.replayError()
+	case e.Output == nil || e.Output.Int == nil:
+		return nil, errors.New("transcript: null public key")
+	}
+
+	return e.Output.Int, nil
+}
+
+// PrivateKeyMod returns the recorded remainder, or nil if there is none.
+func (o *ReplayOracle) PrivateKeyMod(m *big.Int) *big.Int {
+	e, ok := o.answer(OracleLeak, m)
+	if !ok || e.Output == nil {
+		return nil
+	}
+
+	return e.Output.Int
+}
@@ -0,0 +1,117 @@
+package oracle
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+func TestTranscript(t *testing.T) {
+	rng := helpers.NewSeededReader(39)
+	dhGroup := dh.MODP512V57()
+	p48 := elliptic.P48()
+
+	dhBob := NewDHAttackOracle(dhGroup, dh.RangeCheck, rng)
+	ecBob := NewECDHReductionTimingOracle(p48, DefaultTimingCosts, rng)
+	xBob := NewX128TwistAttackOracle(rng)
+
+	var buf bytes.Buffer
+	recorder := NewRecorder(&buf)
+
+	dhOracle := NewRecordingDHOracle(dhBob, recorder)
+	dropping := NewRecordingDHOracle(NewFaultyDHOracle(dhBob, NewInjector(Faults{Drop: 1}, rng)), recorder)
+	timingOut := NewRecordingDHOracle(DHOracleFunc(func(*big.Int) ([]byte, error) {
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	}), recorder)
+	ecOracle := NewRecordingECDHOracle(ecBob, recorder)
+	timingOracle := NewRecordingTimingOracle(ecBob, recorder)
+	xOracle := NewRecordingX128Oracle(xBob, recorder)
+	publicKey := NewRecordingPublicKeySource(xBob, recorder)
+	leak := NewRecordingLeakOracle(xBob, recorder)
+
+	g := dhGroup.DHParams().G
+	h := new(big.Int).Add(g, helpers.BigOne)
+	x, y := elliptic.GeneratePoint(p48, rng)
+	u := big.NewInt(4)
+	m := big.NewInt(1 << 20)
+
+	type answer struct {
+		res []byte
+		err error
+	}
+	ask := func(dhOracle DHOracle, ecOracle ECDHOracle, xOracle X128Oracle) []answer {
+		var answers []answer
+		for _, q := range []func() ([]byte, error){
+			func() ([]byte, error) { return dhOracle.DH(g) },
+			func() ([]byte, error) { return dhOracle.DH(helpers.BigOne) }, // rejected
+			func() ([]byte, error) { return ecOracle.ECDH(x, y) },
+			func() ([]byte, error) { return xOracle.X128DH(u) },
+		} {
+			res, err := q()
+			answers = append(answers, answer{res, err})
+		}
+		return answers
+	}
+
+	recorded := ask(dhOracle, ecOracle, xOracle)
+
+	if _, err := dropping.DH(h); !errors.Is(err, ErrDropped) {
+		t.Fatalf("%s: got error %v, want %v", t.Name(), err, ErrDropped)
+	}
+	if _, err := timingOut.DH(u); !IsTransient(err) {
+		t.Fatalf("%s: got error %v, want a timeout", t.Name(), err)
+	}
+	tag, duration, err := timingOracle.TimedECDH(x, y)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	wantPublicKey, err := publicKey.PublicKey()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	wantRemainder := leak.PrivateKeyMod(m)
+
+	if err := recorder.Err(); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+	t.Logf("%s: transcript:\n%s", t.Name(), buf.String())
+
+	replay, err := NewReplayOracle(&buf)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+
+	for i, a := range ask(replay, replay, replay) {
+		want := recorded[i]
+		if a.err != nil || want.err != nil || !bytes.Equal(a.res, want.res) || (a.res == nil) != (want.res == nil) {
+			t.Errorf("%s: query %d: got %x, %v, want %x, %v", t.Name(), i, a.res, a.err, want.res, want.err)
+		}
+	}
+
+	if _, err := replay.DH(h); !errors.Is(err, ErrDropped) || !IsTransient(err) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, ErrDropped)
+	}
+	if _, err := replay.DH(u); !errors.Is(err, ErrTimeout) || !IsTransient(err) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, ErrTimeout)
+	}
+	if res, d, err := replay.TimedECDH(x, y); err != nil || !bytes.Equal(res, tag) || d != duration {
+		t.Errorf("%s: got %x, %s, %v, want %x, %s", t.Name(), res, d, err, tag, duration)
+	}
+	if k, err := replay.PublicKey(); err != nil || k.Cmp(wantPublicKey) != 0 {
+		t.Errorf("%s: got public key %d, %v, want %d", t.Name(), k, err, wantPublicKey)
+	}
+	if r := replay.PrivateKeyMod(m); r == nil || r.Cmp(wantRemainder) != 0 {
+		t.Errorf("%s: got remainder %d, want %d", t.Name(), r, wantRemainder)
+	}
+
+	if _, err := replay.DH(big.NewInt(5)); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, ErrNotRecorded)
+	}
+}
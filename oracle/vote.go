@@ -0,0 +1,119 @@
+package oracle
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrNoMajority is returned by a voting oracle if no answer was given by the
+// majority of the votes.
+var ErrNoMajority = errors.New("oracle: no majority answer")
+
+// Voter asks each query several times and returns the answer given by the
+// majority, so that a few corrupted answers are outvoted. Transient failures
+// of the queries, see IsTransient, are repeated. The answers are compared
+// byte by byte, so voting doesn't work with randomized protocols such as
+// AEADProtocol.
+type Voter struct {
+	votes   int
+	retries int
+}
+
+// NewVoter returns a Voter which asks each query votes times and repeats at
+// most retries transient failures per query.
+func NewVoter(votes, retries int) *Voter {
+	if votes < 1 {
+		votes = 1
+	}
+
+	return &Voter{votes: votes, retries: retries}
+}
+
+// vote asks the oracle through f until an answer has the majority.
+func (v *Voter) vote(f func() ([]byte, error)) ([]byte, error) {
+	counts := make(map[string]int)
+	failures := 0
+
+	for asked := 0; asked < v.votes; {
+		res, err := f()
+		if err != nil {
+			if IsTransient(err) && failures < v.retries {
+				failures++
+				continue
+			}
+			return nil, err
+		}
+		asked++
+
+		// MACs are never empty, so a rejection can't be mistaken for one
+		counts[string(res)]++
+		if 2*counts[string(res)] > v.votes {
+			return res, nil
+		}
+	}
+
+	return nil, ErrNoMajority
+}
+
+// VotingDHOracle votes on the answers of a DHOracle.
+type VotingDHOracle struct {
+	*Voter
+	oracle DHOracle
+}
+
+// NewVotingDHOracle wraps the oracle with the voter.
+func NewVotingDHOracle(oracle DHOracle, voter *Voter) *VotingDHOracle {
+	return &VotingDHOracle{Voter: voter, oracle: oracle}
+}
+
+func (o *VotingDHOracle) DH(publicKey *big.Int) ([]byte, error) {
+	return o.vote(func() ([]byte, error) {
+		return o.oracle.DH(publicKey)
+	})
+}
+
+func (o *VotingDHOracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// VotingECDHOracle votes on the answers of an ECDHOracle.
+type VotingECDHOracle struct {
+	*Voter
+	oracle ECDHOracle
+}
+
+// NewVotingECDHOracle wraps the oracle with the voter.
+func NewVotingECDHOracle(oracle ECDHOracle, voter *Voter) *VotingECDHOracle {
+	return &VotingECDHOracle{Voter: voter, oracle: oracle}
+}
+
+func (o *VotingECDHOracle) ECDH(x, y *big.Int) ([]byte, error) {
+	return o.vote(func() ([]byte, error) {
+		return o.oracle.ECDH(x, y)
+	})
+}
+
+func (o *VotingECDHOracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
+
+// VotingX128Oracle votes on the answers of an X128Oracle.
+type VotingX128Oracle struct {
+	*Voter
+	oracle X128Oracle
+}
+
+// NewVotingX128Oracle wraps the oracle with the voter.
+func NewVotingX128Oracle(oracle X128Oracle, voter *Voter) *VotingX128Oracle {
+	return &VotingX128Oracle{Voter: voter, oracle: oracle}
+}
+
+func (o *VotingX128Oracle) X128DH(u *big.Int) ([]byte, error) {
+	return o.vote(func() ([]byte, error) {
+		return o.oracle.X128DH(u)
+	})
+}
+
+func (o *VotingX128Oracle) CheckSecret(secret, answer []byte) bool {
+	return CheckerOf(o.oracle).CheckSecret(secret, answer)
+}
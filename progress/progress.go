@@ -0,0 +1,207 @@
+// Package progress reports the progress of the attacks as a stream of
+// events. The attacks take a Reporter, which may be nil if the progress is
+// of no interest.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Kind is the kind of an event.
+type Kind string
+
+const (
+	// SubgroupFound is reported before Bob is queried with an element of
+	// order Modulus.
+	SubgroupFound Kind = "subgroup-found"
+
+	// ResidueRecovered is reported once Bob's private key is known to be
+	// Residue modulo Modulus.
+	ResidueRecovered Kind = "residue-recovered"
+
+	// ModulusGrown is reported when the residues are combined with the CRT:
+	// Bob's private key is known modulo Modulus, which is Bits long.
+	ModulusGrown Kind = "modulus-grown"
+
+	// KangarooJumps is reported periodically while Kangaroo ("tame" or
+	// "wild") jumps. The kangaroo has made Jumps jumps and traveled Distance.
+	KangarooJumps Kind = "kangaroo-jumps"
+
+	// CandidateFound is reported for each candidate for the private key,
+	// or a part of it, which is yet to be checked.
+	CandidateFound Kind = "candidate-found"
+
+	// CandidateEliminated is reported for each candidate which turned out
+	// to be wrong.
+	CandidateEliminated Kind = "candidate-eliminated"
+
+	// Note is a free-form Message.
+	Note Kind = "note"
+)
+
+// Event is a step of an attack. Only the fields of its Kind are set.
+type Event struct {
+	Kind      Kind
+	Modulus   *big.Int
+	Residue   *big.Int
+	Bits      int
+	Kangaroo  string
+	Jumps     uint64
+	Distance  *big.Int
+	Candidate *big.Int
+	Message   string
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case SubgroupFound:
+		return fmt.Sprintf("subgroup of order %d", e.Modulus)
+	case ResidueRecovered:
+		return fmt.Sprintf("x = %d mod %d", e.Residue, e.Modulus)
+	case ModulusGrown:
+		return fmt.Sprintf("x is known modulo a %d-bit number", e.Bits)
+	case KangarooJumps:
+		s := fmt.Sprintf("%s kangaroo: %d jumps, distance %d", e.Kangaroo, e.Jumps, e.Distance)
+		if e.Candidate != nil {
+			s += fmt.Sprintf(", candidate %d", e.Candidate)
+		}
+		return s
+	case CandidateFound:
+		return fmt.Sprintf("candidate %d", e.Candidate)
+	case CandidateEliminated:
+		return fmt.Sprintf("candidate %d eliminated", e.Candidate)
+	case Note:
+		return e.Message
+	default:
+		return string(e.Kind)
+	}
+}
+
+// Reporter receives the events of an attack. Report may be called
+// concurrently.
+type Reporter interface {
+	Report(e Event)
+}
+
+// ReporterFunc is an adapter to use an ordinary function as a Reporter.
+type ReporterFunc func(e Event)
+
+func (f ReporterFunc) Report(e Event) {
+	f(e)
+}
+
+type discard struct{}
+
+func (discard) Report(Event) {}
+
+// Discard is a Reporter which ignores all events.
+var Discard Reporter = discard{}
+
+// OrDiscard returns r, or Discard if r is nil.
+func OrDiscard(r Reporter) Reporter {
+	if r == nil {
+		return Discard
+	}
+	return r
+}
+
+// TextLogger writes the events as lines of text prefixed with the time.
+type TextLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// NewTextLogger returns a TextLogger which writes to w.
+func NewTextLogger(w io.Writer) *TextLogger {
+	return &TextLogger{w: w}
+}
+
+func (l *TextLogger) Report(e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err == nil {
+		_, l.err = fmt.Fprintf(l.w, "%s %s\n", time.Now().Format("15:04:05.000"), e)
+	}
+}
+
+// Err returns the first error which occurred while writing the events.
+func (l *TextLogger) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// jsonEvent is an Event as written by a JSONLogger. Big integers are written
+// as hex strings with the 0x prefix, like in the oracle transcripts.
+type jsonEvent struct {
+	Time      time.Time `json:"time"`
+	Kind      Kind      `json:"kind"`
+	Modulus   string    `json:"modulus,omitempty"`
+	Residue   string    `json:"residue,omitempty"`
+	Bits      int       `json:"bits,omitempty"`
+	Kangaroo  string    `json:"kangaroo,omitempty"`
+	Jumps     uint64    `json:"jumps,omitempty"`
+	Distance  string    `json:"distance,omitempty"`
+	Candidate string    `json:"candidate,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+func hex(n *big.Int) string {
+	if n == nil {
+		return ""
+	}
+	if n.Sign() < 0 {
+		return "-0x" + new(big.Int).Neg(n).Text(16)
+	}
+	return "0x" + n.Text(16)
+}
+
+// JSONLogger writes the events as JSON Lines.
+type JSONLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONLogger returns a JSONLogger which writes to w.
+func NewJSONLogger(w io.Writer) *JSONLogger {
+	return &JSONLogger{enc: json.NewEncoder(w)}
+}
+
+func (l *JSONLogger) Report(e Event) {
+	je := jsonEvent{
+		Time:      time.Now(),
+		Kind:      e.Kind,
+		Modulus:   hex(e.Modulus),
+		Residue:   hex(e.Residue),
+		Bits:      e.Bits,
+		Kangaroo:  e.Kangaroo,
+		Jumps:     e.Jumps,
+		Distance:  hex(e.Distance),
+		Candidate: hex(e.Candidate),
+		Message:   e.Message,
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err == nil {
+		l.err = l.enc.Encode(je)
+	}
+}
+
+// Err returns the first error which occurred while writing the events.
+func (l *JSONLogger) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
@@ -0,0 +1,61 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestLoggers(t *testing.T) {
+	events := []Event{
+		{Kind: SubgroupFound, Modulus: big.NewInt(11)},
+		{Kind: ResidueRecovered, Residue: big.NewInt(5), Modulus: big.NewInt(11)},
+		{Kind: ModulusGrown, Modulus: big.NewInt(11), Bits: 4},
+		{Kind: KangarooJumps, Kangaroo: "wild", Jumps: 1 << 16, Distance: big.NewInt(1 << 20)},
+		{Kind: CandidateEliminated, Candidate: big.NewInt(42)},
+		{Kind: Note, Message: "done"},
+	}
+	lines := []string{
+		"subgroup of order 11",
+		"x = 5 mod 11",
+		"x is known modulo a 4-bit number",
+		"wild kangaroo: 65536 jumps, distance 1048576",
+		"candidate 42 eliminated",
+		"done",
+	}
+
+	var text, js bytes.Buffer
+	textLogger := NewTextLogger(&text)
+	jsonLogger := NewJSONLogger(&js)
+
+	for _, e := range events {
+		textLogger.Report(e)
+		jsonLogger.Report(e)
+		OrDiscard(nil).Report(e)
+	}
+	if textLogger.Err() != nil || jsonLogger.Err() != nil {
+		t.Fatalf("%s: %v, %v", t.Name(), textLogger.Err(), jsonLogger.Err())
+	}
+
+	textLines := strings.Split(strings.TrimSuffix(text.String(), "\n"), "\n")
+	jsonLines := strings.Split(strings.TrimSuffix(js.String(), "\n"), "\n")
+	if len(textLines) != len(events) || len(jsonLines) != len(events) {
+		t.Fatalf("%s: %d text and %d JSON lines for %d events", t.Name(), len(textLines), len(jsonLines), len(events))
+	}
+
+	for i, e := range events {
+		if !strings.HasSuffix(textLines[i], " "+lines[i]) {
+			t.Errorf("%s: text line %q, want %q", t.Name(), textLines[i], lines[i])
+		}
+
+		var je jsonEvent
+		if err := json.Unmarshal([]byte(jsonLines[i]), &je); err != nil {
+			t.Fatalf("%s: %s", t.Name(), err)
+		}
+		if je.Kind != e.Kind || je.Modulus != hex(e.Modulus) || je.Candidate != hex(e.Candidate) || je.Time.IsZero() {
+			t.Errorf("%s: JSON line %q doesn't match %v", t.Name(), jsonLines[i], e)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package search looks for a residue of the private key by brute force,
+// splitting the range between several goroutines.
+package search
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/svkirillov/cryptopals-go/helpers"
+)
+
+// chunkSize is the number of values a worker takes from the range at once.
+const chunkSize = 1 << 8
+
+// Smallest returns the smallest k in [from, to) for which match is true, or
+// nil if there is none. The values are checked by workers goroutines, or by
+// runtime.NumCPU() goroutines if workers is not positive, so match must be
+// safe for concurrent use and must not keep k. Smallest stops with
+// ctx.Err() once ctx is done.
+func Smallest(ctx context.Context, from, to *big.Int, workers int, match func(k *big.Int) bool) (*big.Int, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		mu        sync.Mutex
+		next      = new(big.Int).Set(from)
+		nextChunk uint64
+		found     *big.Int
+
+		// foundChunk is the chunk of found, the chunks after it are skipped
+		foundChunk uint64 = math.MaxUint64
+	)
+
+	step := big.NewInt(chunkSize)
+
+	// take returns the next chunk [start, end) of the range, or false if
+	// there is nothing left to check
+	take := func() (chunk uint64, start, end *big.Int, ok bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if next.Cmp(to) >= 0 || nextChunk > atomic.LoadUint64(&foundChunk) {
+			return 0, nil, nil, false
+		}
+
+		start = new(big.Int).Set(next)
+		if next.Add(next, step); next.Cmp(to) > 0 {
+			next.Set(to)
+		}
+		end = new(big.Int).Set(next)
+
+		chunk = nextChunk
+		nextChunk++
+
+		return chunk, start, end, true
+	}
+
+	// the chunks are taken in order, so once a chunk has a match only the
+	// chunks before it may have a smaller one
+	report := func(chunk uint64, k *big.Int) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if chunk < atomic.LoadUint64(&foundChunk) {
+			found = new(big.Int).Set(k)
+			atomic.StoreUint64(&foundChunk, chunk)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				chunk, k, end, ok := take()
+				if !ok {
+					return
+				}
+
+				for ; k.Cmp(end) < 0 && chunk < atomic.LoadUint64(&foundChunk); k.Add(k, helpers.BigOne) {
+					select {
+					case <-ctx.Done():
+						return
+					default:
+						// pass
+					}
+
+					if match(k) {
+						report(chunk, k)
+						break
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return found, nil
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/dh"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	oracle2 "github.com/svkirillov/cryptopals-go/oracle"
+)
+
+func TestSmallest(t *testing.T) {
+	// every multiple of 1000 matches, so the first chunks have no match and
+	// the later ones have several
+	multiple := func(k *big.Int) bool {
+		return k.Int64()%1000 == 0
+	}
+
+	for _, workers := range []int{1, 2, 3, 8} {
+		k, err := Smallest(context.Background(), big.NewInt(1), big.NewInt(100000), workers, multiple)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+		if k == nil || k.Int64() != 1000 {
+			t.Errorf("%s: got %d with %d workers, want 1000", t.Name(), k, workers)
+		}
+
+		k, err = Smallest(context.Background(), big.NewInt(1001), big.NewInt(2000), workers, multiple)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+		if k != nil {
+			t.Errorf("%s: got %d with %d workers, want nil", t.Name(), k, workers)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Smallest(ctx, big.NewInt(1), big.NewInt(100000), 4, multiple); !errors.Is(err, context.Canceled) {
+		t.Errorf("%s: got error %v, want %v", t.Name(), err, context.Canceled)
+	}
+}
+
+// BenchmarkSmallest recovers a residue of a DH private key like the small
+// subgroup attack does, with different numbers of workers.
+func BenchmarkSmallest(b *testing.B) {
+	group := dh.MODP512V57()
+	p := group.DHParams().P
+	q := group.DHParams().Q
+
+	j := new(big.Int).Div(new(big.Int).Sub(p, helpers.BigOne), q)
+	factors := helpers.Factorize(j, big.NewInt(1<<16))
+	r := factors[len(factors)-1]
+
+	// h is an element of order r, and Bob's private key is r-1 modulo r,
+	// so the whole range is searched
+	rng := helpers.NewSeededReader(1)
+	h := new(big.Int).Set(helpers.BigOne)
+	for h.Cmp(helpers.BigOne) == 0 {
+		x, err := helpers.GenerateBigInt(rng, p)
+		if err != nil {
+			b.Fatal(err)
+		}
+		h.Exp(x, new(big.Int).Div(new(big.Int).Sub(p, helpers.BigOne), r), p)
+	}
+	ss := oracle2.MAC(group.DH(new(big.Int).Sub(r, helpers.BigOne), h).Bytes())
+
+	match := func(k *big.Int) bool {
+		return oracle2.MACProtocol{}.CheckSecret(group.DH(k, h).Bytes(), ss)
+	}
+
+	counts := []int{1, 2, 4}
+	if n := runtime.NumCPU(); n > 4 {
+		counts = append(counts, n)
+	}
+
+	for _, workers := range counts {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				k, err := Smallest(context.Background(), big.NewInt(1), r, workers, match)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if k == nil {
+					b.Fatalf("no residue modulo %d", r)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+// Package timing implements a statistical timing attack on a double-and-add
+// loop whose additions take a data dependent extra reduction, as simulated by
+// oracle.ECDHReductionTimingOracle.
+//
+// The loop processes the scalar from the least significant bit: the
+// accumulator Q is added to the running double D = [2^i]P only if bit i is
+// set. Knowing the bits below i, the attacker can compute Q and D for every
+// point P he sends, and so predict whether the addition at step i takes an
+// extra reduction. If bit i is set, the answers for the points with
+// a predicted extra reduction are slower on average, otherwise there is no
+// difference. This is Kocher's attack on square-and-multiply.
+package timing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/svkirillov/cryptopals-go/congruence"
+	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/oracle"
+	"github.com/svkirillov/cryptopals-go/progress"
+)
+
+// lookahead is the number of bits searched for the second set bit of the
+// scalar. The addition of the lowest set bit is made to the point at
+// infinity and takes no time, so the lowest set bit is only seen through
+// the next one.
+const lookahead = 16
+
+// maxFlips is the number of the least confident bits which are flipped one
+// by one if the recovered scalar is wrong.
+const maxFlips = 16
+
+type sample struct {
+	x, y     *big.Int // the point sent to Bob
+	mac      []byte
+	residual time.Duration // the time not yet explained by the known bits
+
+	qx, qy *big.Int // the accumulator
+	dx, dy *big.Int // the running double
+}
+
+// RecoverScalar recovers Bob's scalar from the response times to samples
+// random points on the curve. costs is the attacker's model of Bob's
+// implementation, e.g. profiled on the same hardware. Random points are drawn
+// from rng, or from crypto/rand.Reader if rng is nil. Each recovered bit is
+// reported to events, which may be nil, as the scalar modulo 2^(i+1).
+func RecoverScalar(
+	curve elliptic.Curve,
+	timingOracle oracle.TimingOracle,
+	costs oracle.TimingCosts,
+	samples int,
+	rng io.Reader,
+	events progress.Reporter,
+) (*big.Int, error) {
+	return RecoverScalarContext(context.Background(), curve, timingOracle, costs, samples, rng, events)
+}
+
+// RecoverScalarContext is RecoverScalar which stops once ctx is done. The
+// bits recovered so far are returned in a *congruence.PartialError as the
+// scalar modulo 2^i.
+func RecoverScalarContext(
+	ctx context.Context,
+	curve elliptic.Curve,
+	timingOracle oracle.TimingOracle,
+	costs oracle.TimingCosts,
+	samples int,
+	rng io.Reader,
+	events progress.Reporter,
+) (*big.Int, error) {
+	events = progress.OrDiscard(events)
+
+	if costs.Reduction <= 0 {
+		return nil, errors.New("timing: the cost model has no extra reductions to exploit")
+	}
+
+	ss := make([]*sample, samples)
+	for i := range ss {
+		if ctx.Err() != nil {
+			return nil, &congruence.PartialError{Err: ctx.Err()}
+		}
+
+		x, y := elliptic.GeneratePoint(curve, rng)
+
+		mac, elapsed, err := timingOracle.TimedECDH(x, y)
+		if err != nil {
+			return nil, fmt.Errorf("oracle: %w", err)
+		}
+
+		ss[i] = &sample{
+			x: x, y: y,
+			mac:      mac,
+			residual: elapsed,
+			qx:       new(big.Int), qy: new(big.Int),
+			dx: x, dy: y,
+		}
+	}
+
+	bits := curve.Params().N.BitLen()
+	threshold := costs.Reduction / 2
+
+	k := new(big.Int)
+	confidence := make([]time.Duration, bits)
+
+	for i := 0; i < bits; i++ {
+		if ctx.Err() != nil {
+			partial := &congruence.PartialError{Err: ctx.Err()}
+			partial.Congruences.Add(k, new(big.Int).Lsh(big.NewInt(1), uint(i)))
+			return nil, partial
+		}
+
+		var bit uint
+		var diff time.Duration
+
+		if k.Sign() == 0 {
+			bit, diff = firstBit(curve, ss, i, bits, threshold)
+		} else {
+			diff = partition(curve, ss, func(s *sample) (*big.Int, *big.Int) {
+				return curve.Add(s.qx, s.qy, s.dx, s.dy)
+			})
+			if diff > threshold {
+				bit = 1
+			}
+		}
+
+		confidence[i] = diff - threshold
+		if confidence[i] < 0 {
+			confidence[i] = -confidence[i]
+		}
+
+		if bit == 1 {
+			k.SetBit(k, i, 1)
+		}
+		events.Report(progress.Event{
+			Kind:    progress.ResidueRecovered,
+			Residue: new(big.Int).Set(k),
+			Modulus: new(big.Int).Lsh(big.NewInt(1), uint(i+1)),
+		})
+
+		for _, s := range ss {
+			if bit == 1 {
+				wasInfinity := s.qx.Sign() == 0 && s.qy.Sign() == 0
+				s.qx, s.qy = curve.Add(s.qx, s.qy, s.dx, s.dy)
+				if !wasInfinity {
+					s.residual -= opCost(curve, costs, costs.Add, s.qx, s.qy)
+				}
+			}
+			s.dx, s.dy = curve.Double(s.dx, s.dy)
+			s.residual -= opCost(curve, costs, costs.Double, s.dx, s.dy)
+		}
+	}
+
+	checker := oracle.CheckerOf(timingOracle)
+
+	if check(curve, checker, ss[0], k) {
+		return k, nil
+	}
+	events.Report(progress.Event{Kind: progress.CandidateEliminated, Candidate: k})
+
+	// flip the least confident bits one by one
+	order := make([]int, bits)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return confidence[order[a]] < confidence[order[b]] })
+
+	for _, i := range order[:min(maxFlips, bits)] {
+		candidate := new(big.Int).SetBit(k, i, k.Bit(i)^1)
+		if check(curve, checker, ss[0], candidate) {
+			return candidate, nil
+		}
+		events.Report(progress.Event{Kind: progress.CandidateEliminated, Candidate: candidate})
+	}
+
+	return nil, errors.New("timing: couldn't recover the scalar, more samples are needed")
+}
+
+// firstBit decides bit i while no set bit is known yet: bit i is set if, for
+// the accumulator [2^i]P, one of the next additions shows the timing
+// difference.
+func firstBit(curve elliptic.Curve, ss []*sample, i, bits int, threshold time.Duration) (uint, time.Duration) {
+	type point struct{ x, y *big.Int }
+
+	// the hypothetical accumulator and running doubles
+	q := make([]point, len(ss))
+	d := make([]point, len(ss))
+	for j, s := range ss {
+		q[j] = point{s.dx, s.dy}
+		d[j] = point{s.dx, s.dy}
+	}
+
+	var best time.Duration
+
+	for l := i + 1; l < bits && l <= i+lookahead; l++ {
+		for j := range ss {
+			d[j].x, d[j].y = curve.Double(d[j].x, d[j].y)
+		}
+
+		j := 0
+		diff := partition(curve, ss, func(*sample) (*big.Int, *big.Int) {
+			x, y := curve.Add(q[j].x, q[j].y, d[j].x, d[j].y)
+			j++
+			return x, y
+		})
+		if diff > best {
+			best = diff
+		}
+	}
+
+	if best > threshold {
+		return 1, best
+	}
+
+	return 0, best
+}
+
+// partition returns the difference of the mean residual times of the samples
+// whose predicted operation takes an extra reduction and of the others.
+func partition(curve elliptic.Curve, ss []*sample, predict func(*sample) (*big.Int, *big.Int)) time.Duration {
+	var sum [2]time.Duration
+	var n [2]int
+
+	for _, s := range ss {
+		x, y := predict(s)
+
+		g := 0
+		if oracle.NeedsExtraReduction(curve, x, y) {
+			g = 1
+		}
+
+		sum[g] += s.residual
+		n[g]++
+	}
+
+	if n[0] == 0 || n[1] == 0 {
+		return 0
+	}
+
+	return sum[1]/time.Duration(n[1]) - sum[0]/time.Duration(n[0])
+}
+
+// opCost returns the simulated time of an operation which results in (x, y).
+func opCost(curve elliptic.Curve, costs oracle.TimingCosts, cost time.Duration, x, y *big.Int) time.Duration {
+	if oracle.NeedsExtraReduction(curve, x, y) {
+		cost += costs.Reduction
+	}
+	return cost
+}
+
+// check reports whether k is Bob's scalar using the answer to a sample.
+func check(curve elliptic.Curve, checker oracle.SecretChecker, s *sample, k *big.Int) bool {
+	sx, sy := curve.ScalarMult(s.x, s.y, k.Bytes())
+	return checker.CheckSecret(elliptic.Marshal(curve, sx, sy), s.mac)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
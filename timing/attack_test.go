@@ -0,0 +1,41 @@
+package timing
+
+import (
+	"testing"
+
+	"github.com/svkirillov/cryptopals-go/elliptic"
+	"github.com/svkirillov/cryptopals-go/helpers"
+	oracle2 "github.com/svkirillov/cryptopals-go/oracle"
+)
+
+func TestRecoverScalar(t *testing.T) {
+	p48 := elliptic.P48()
+	rng := helpers.NewSeededReader(37)
+
+	bob := oracle2.NewECDHReductionTimingOracle(p48, oracle2.DefaultTimingCosts, rng)
+
+	privateKey, err := RecoverScalar(p48, bob, oracle2.DefaultTimingCosts, 4000, rng, nil)
+	if err != nil {
+		t.Fatalf("%s: %s\n", t.Name(), err.Error())
+	}
+	t.Logf("%s: Private key: %d\n", t.Name(), privateKey)
+
+	if !bob.IsKeyCorrect(privateKey.Bytes()) {
+		t.Fatalf("%s: wrong private key was found in the timing attack\n", t.Name())
+	}
+}
+
+func TestRecoverScalarWithoutExtraReductions(t *testing.T) {
+	p48 := elliptic.P48()
+	rng := helpers.NewSeededReader(37)
+
+	// no extra reductions, the time depends only on the weight of the key
+	costs := oracle2.DefaultTimingCosts
+	costs.Reduction = 0
+
+	bob := oracle2.NewECDHReductionTimingOracle(p48, costs, rng)
+
+	if _, err := RecoverScalar(p48, bob, oracle2.DefaultTimingCosts, 1000, rng, nil); err == nil {
+		t.Fatalf("%s: the timing attack succeeded without a leak\n", t.Name())
+	}
+}